@@ -16,6 +16,11 @@ var Version = "dev"
 const (
 	defConfig          = "dashboard.json"
 	defRefreshInterval = "10s"
+	defStaleSyncs      = "3"
+	defRetries         = "2"
+	defRetryBackoff    = "200ms"
+	defCBFailures      = "5"
+	defCBOpenTimeout   = "30s"
 	defLogPath         = "grafterm.log"
 	defGraftermDir     = "grafterm"
 )
@@ -28,10 +33,26 @@ const (
 	envUserDatasources = envPrefix + "_USER_DATASOURCES"
 )
 
+// Commands.
+const (
+	cmdRun           = "run"
+	cmdImportGrafana = "import-grafana"
+)
+
 // flag descriptions.
 const (
-	descCfg             = "the path to the configuration file"
+	descRunCmd          = "run the dashboards on the terminal (default)"
+	descImportCmd       = "convert a Grafana dashboard JSON to a grafterm configuration, the Grafana features that can't be converted will be reported"
+	descImportDashboard = "the path to the Grafana dashboard JSON file"
+	descImportOutput    = "the path where the grafterm configuration will be written, by default the standard output. If the file has a YAML extension it will be written in YAML"
+	descCfg             = "repeatable flag with the path to a configuration file or a directory of configuration files, each dashboard will be loaded on its own page"
+	descPlaylistInt     = "the interval to rotate the dashboard pages automatically, by default the pages are not rotated"
 	descRefreshInterval = "the interval to refresh the dashboard"
+	descStaleSyncs      = "the number of failed refreshes in a row after the data of a widget is marked as stale, 0 disables it"
+	descRetries         = "the number of times a datasource query failed with a transient error (network or server error) is retried, 0 disables the retries"
+	descRetryBackoff    = "the wait before the first retry of a failed datasource query, the wait is doubled on every retry"
+	descCBFailures      = "the number of consecutive failed queries of a datasource that will stop querying it for the circuit breaker timeout, 0 disables it"
+	descCBOpenTimeout   = "the time a datasource is not queried after multiple consecutive failed queries"
 	descLogPath         = "the path where the log output will be written"
 	descRelativeDur     = "the relative duration from now to load the graph."
 	descStart           = "the time the dashboard will start in time. Accepts 2 formats, relative time from now based on duration(e.g.: 24h, 15m), or fixed duration in ISO 8601 (e.g.: 2019-05-12T09:35:11+00:00). If set it disables relative duration flag."
@@ -44,19 +65,32 @@ const (
 var descUserDS = fmt.Sprintf("path to a configuration file with user defined datasources, these datasources can override the dashboard datasources with the same ID and also can be used to alias them using datasource alias flags. It fallbacks to %s env var", envUserDatasources)
 
 type flags struct {
+	command         string
+	importGrafana   importGrafanaFlags
 	variables       map[string]string
 	aliases         map[string]string
-	cfg             string
+	cfgs            []string
+	playlistInt     time.Duration
 	userDSPath      string
 	debug           bool
 	version         bool
 	refreshInterval time.Duration
+	staleSyncs      int
+	retries         int
+	retryBackoff    time.Duration
+	cbFailures      int
+	cbOpenTimeout   time.Duration
 	logPath         string
 	start           string
 	relativeDur     time.Duration
 	end             string
 }
 
+type importGrafanaFlags struct {
+	dashboardPath string
+	outputPath    string
+}
+
 func newFlags() (*flags, error) {
 	flags := &flags{
 		variables: map[string]string{},
@@ -77,8 +111,14 @@ func newFlags() (*flags, error) {
 	app.Version(Version)
 
 	// Register flags.
-	app.Flag("cfg", descCfg).Default(defConfig).Short('c').StringVar(&flags.cfg)
+	app.Flag("cfg", descCfg).Default(defConfig).Short('c').StringsVar(&flags.cfgs)
+	app.Flag("playlist-interval", descPlaylistInt).DurationVar(&flags.playlistInt)
 	app.Flag("refresh-interval", descRefreshInterval).Default(defRefreshInterval).Short('r').DurationVar(&flags.refreshInterval)
+	app.Flag("stale-syncs", descStaleSyncs).Default(defStaleSyncs).IntVar(&flags.staleSyncs)
+	app.Flag("retries", descRetries).Default(defRetries).IntVar(&flags.retries)
+	app.Flag("retry-backoff", descRetryBackoff).Default(defRetryBackoff).DurationVar(&flags.retryBackoff)
+	app.Flag("circuit-breaker-failures", descCBFailures).Default(defCBFailures).IntVar(&flags.cbFailures)
+	app.Flag("circuit-breaker-timeout", descCBOpenTimeout).Default(defCBOpenTimeout).DurationVar(&flags.cbOpenTimeout)
 	app.Flag("log-path", descLogPath).Default(defLogPath).StringVar(&flags.logPath)
 	app.Flag("relative-duration", descRelativeDur).Short('d').DurationVar(&flags.relativeDur)
 	app.Flag("start", descStart).Short('s').StringVar(&flags.start)
@@ -87,7 +127,18 @@ func newFlags() (*flags, error) {
 	app.Flag("ds-alias", descDSAlias).Short('a').StringMapVar(&flags.aliases)
 	app.Flag("user-datasources", descUserDS).Default(userDsPath).Short('u').Envar(envUserDatasources).StringVar(&flags.userDSPath)
 	app.Flag("debug", descDebug).BoolVar(&flags.debug)
-	app.Parse(os.Args[1:])
+
+	// Register commands.
+	app.Command(cmdRun, descRunCmd).Default()
+	importCmd := app.Command(cmdImportGrafana, descImportCmd)
+	importCmd.Arg("dashboard", descImportDashboard).Required().StringVar(&flags.importGrafana.dashboardPath)
+	importCmd.Flag("output", descImportOutput).Short('o').StringVar(&flags.importGrafana.outputPath)
+
+	cmd, err := app.Parse(os.Args[1:])
+	if err != nil {
+		return nil, err
+	}
+	flags.command = cmd
 
 	if err := flags.validate(); err != nil {
 		return nil, err
@@ -97,5 +148,21 @@ func newFlags() (*flags, error) {
 }
 
 func (f *flags) validate() error {
+	if f.playlistInt < 0 {
+		return fmt.Errorf("playlist interval can't be negative")
+	}
+
+	if f.staleSyncs < 0 {
+		return fmt.Errorf("stale syncs can't be negative")
+	}
+
+	if f.retries < 0 || f.retryBackoff < 0 {
+		return fmt.Errorf("retries and retry backoff can't be negative")
+	}
+
+	if f.cbFailures < 0 || f.cbOpenTimeout < 0 {
+		return fmt.Errorf("circuit breaker failures and timeout can't be negative")
+	}
+
 	return nil
 }
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/slok/grafterm/internal/service/configuration/grafana"
+)
+
+// importGrafana converts a Grafana dashboard to a grafterm configuration and
+// reports to the user the features that could not be converted.
+func (m *Main) importGrafana() error {
+	f, err := os.Open(m.flags.importGrafana.dashboardPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	res, err := grafana.Convert(f)
+	if err != nil {
+		return err
+	}
+
+	// Check the converted configuration is a valid configuration.
+	if _, err := res.Configuration.Dashboard(); err != nil {
+		return fmt.Errorf("converted dashboard is not valid: %s", err)
+	}
+
+	bs, err := json.MarshalIndent(res.Configuration, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	outPath := m.flags.importGrafana.outputPath
+	switch filepath.Ext(outPath) {
+	case ".yaml", ".yml":
+		bs, err = jsonToYAML(bs)
+		if err != nil {
+			return err
+		}
+	default:
+		bs = append(bs, '\n')
+	}
+
+	var out io.Writer = os.Stdout
+	if outPath != "" {
+		of, err := os.Create(outPath)
+		if err != nil {
+			return err
+		}
+		defer of.Close()
+		out = of
+	}
+
+	_, err = out.Write(bs)
+	if err != nil {
+		return err
+	}
+
+	// Report to the user what needs to be done by hand.
+	for _, u := range res.Unsupported {
+		fmt.Fprintf(os.Stderr, "unsupported: %s\n", u)
+	}
+	for _, id := range res.DatasourceIDs {
+		fmt.Fprintf(os.Stderr, "datasource: %s datasource needs to be defined on the configuration or the user datasources\n", id)
+	}
+
+	return nil
+}
+
+// jsonToYAML converts JSON data to YAML data.
+func jsonToYAML(bs []byte) ([]byte, error) {
+	var data interface{}
+	err := json.Unmarshal(bs, &data)
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(data)
+}
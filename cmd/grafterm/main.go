@@ -3,8 +3,11 @@ package main
 import (
 	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -21,6 +24,7 @@ import (
 	"github.com/slok/grafterm/internal/view/page"
 	"github.com/slok/grafterm/internal/view/render"
 	"github.com/slok/grafterm/internal/view/render/termdash"
+	"github.com/slok/grafterm/internal/view/sync"
 )
 
 // Main is the main application.
@@ -36,6 +40,10 @@ func (m *Main) Run() error {
 		return nil
 	}
 
+	if m.flags.command == cmdImportGrafana {
+		return m.importGrafana()
+	}
+
 	// If debug mode then use a verbose logger.
 	m.logger = log.Dummy
 	if m.flags.debug {
@@ -50,13 +58,8 @@ func (m *Main) Run() error {
 		})
 	}
 
-	// Load Dashboard.
-	cfg, err := loadConfiguration(m.flags.cfg)
-	if err != nil {
-		return err
-	}
-
-	ddss, err := cfg.Datasources()
+	// Load Dashboards.
+	cfgs, err := m.loadConfigurations()
 	if err != nil {
 		return err
 	}
@@ -66,18 +69,29 @@ func (m *Main) Run() error {
 		return err
 	}
 
-	gatherer, err := m.createGatherer(ddss, udss)
-	if err != nil {
-		return err
-	}
+	// Create time range controller, only set fixed time if start set.
+	var start, end time.Time
+	if m.flags.start != "" {
+		start, err = timeFromFlag(m.flags.start)
+		if err != nil {
+			return fmt.Errorf("error parsing start flag: %s", err)
+		}
+		end, err = timeFromFlag(m.flags.end)
+		if err != nil {
+			return fmt.Errorf("error parsing end flag: %s", err)
+		}
 
-	// Create controller.
-	ctrl := controller.NewController(gatherer)
+		// Check times are correct.
+		if !end.IsZero() && end.Before(start) {
+			return fmt.Errorf("end timestamp can't be before start timestamp")
+		}
+	}
+	timeRange := view.NewTimeRangeController(start, end, m.flags.relativeDur)
 
 	// Create renderer.
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	renderer, err := termdash.NewTermDashboard(cancel, m.logger)
+	renderer, err := termdash.NewTermDashboard(cancel, timeRange, m.logger)
 	if err != nil {
 		return err
 	}
@@ -109,36 +123,14 @@ func (m *Main) Run() error {
 	// Run application.
 	{
 		appcfg := view.AppConfig{
-			RefreshInterval:   m.flags.refreshInterval,
-			RelativeTimeRange: m.flags.relativeDur,
-		}
-
-		// Only set fixed time if start set.
-		if m.flags.start != "" {
-			start, err := timeFromFlag(m.flags.start)
-			if err != nil {
-				return fmt.Errorf("error parsing start flag: %s", err)
-			}
-			end, err := timeFromFlag(m.flags.end)
-			if err != nil {
-				return fmt.Errorf("error parsing end flag: %s", err)
-			}
-
-			appcfg.TimeRangeStart = start
-			appcfg.TimeRangeEnd = end
-
-			// Check times are correct.
-			if !appcfg.TimeRangeEnd.IsZero() && appcfg.TimeRangeEnd.Before(appcfg.TimeRangeStart) {
-				return fmt.Errorf("end timestamp can't be before start timestamp")
-			}
-		}
-
-		ds, err := cfg.Dashboard()
-		if err != nil {
-			return err
+			RefreshInterval:     m.flags.refreshInterval,
+			RelativeTimeRange:   m.flags.relativeDur,
+			TimeRangeStart:      start,
+			TimeRangeEnd:        end,
+			TimeRangeController: timeRange,
 		}
 
-		app, err := m.createApp(ctx, appcfg, ds, ctrl, renderer)
+		app, err := m.createApp(ctx, appcfg, cfgs, udss, renderer)
 		if err != nil {
 			return err
 		}
@@ -160,6 +152,56 @@ func (m *Main) Run() error {
 	return g.Run()
 }
 
+// dashboardConfiguration is a loaded dashboard configuration file.
+type dashboardConfiguration struct {
+	path string
+	cfg  configuration.Configuration
+}
+
+// loadConfigurations will load all the dashboard configuration files, if the path
+// is a directory it will load all the configuration files of the directory.
+func (m *Main) loadConfigurations() ([]dashboardConfiguration, error) {
+	paths := []string{}
+	for _, p := range m.flags.cfgs {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, err
+		}
+
+		if !fi.IsDir() {
+			paths = append(paths, p)
+			continue
+		}
+
+		// Sorted by file name.
+		fis, err := ioutil.ReadDir(p)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range fis {
+			if fi.IsDir() || !configuration.IsConfigurationFile(fi.Name()) {
+				continue
+			}
+			paths = append(paths, filepath.Join(p, fi.Name()))
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no dashboard configuration files found")
+	}
+
+	cfgs := []dashboardConfiguration{}
+	for _, p := range paths {
+		cfg, err := loadConfiguration(p)
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s configuration: %s", p, err)
+		}
+		cfgs = append(cfgs, dashboardConfiguration{path: p, cfg: cfg})
+	}
+
+	return cfgs, nil
+}
+
 func loadConfiguration(cfgPath string) (configuration.Configuration, error) {
 	// Load dashboard file.
 	f, err := os.Open(cfgPath)
@@ -168,7 +210,7 @@ func loadConfiguration(cfgPath string) (configuration.Configuration, error) {
 	}
 	defer f.Close()
 
-	cfg, err := configuration.JSONLoader{}.Load(f)
+	cfg, err := configuration.FileLoader{Name: cfgPath}.Load(f)
 	if err != nil {
 		return nil, err
 	}
@@ -185,7 +227,7 @@ func (m *Main) loadUserDatasources() ([]model.Datasource, error) {
 	}
 	defer f.Close()
 
-	cfg, err := configuration.JSONLoader{}.Load(f)
+	cfg, err := configuration.FileLoader{Name: m.flags.userDSPath}.Load(f)
 	if err != nil {
 		return nil, err
 	}
@@ -193,37 +235,109 @@ func (m *Main) loadUserDatasources() ([]model.Datasource, error) {
 	return cfg.Datasources()
 }
 
-func (m *Main) createGatherer(dashboardDss, userDss []model.Datasource) (metric.Gatherer, error) {
-	gatherer, err := metricdatasource.NewGatherer(metricdatasource.ConfigGatherer{
+// createGatherer creates the gatherer of a dashboard and the reporter of the
+// health of the datasources that the gatherer uses.
+func (m *Main) createGatherer(ctx context.Context, dashboardDss, userDss []model.Datasource) (metric.Gatherer, *view.DatasourceHealthReporter, error) {
+	cfg := metricdatasource.ConfigGatherer{
+		Context:              ctx,
 		DashboardDatasources: dashboardDss,
 		UserDatasources:      userDss,
 		Aliases:              m.flags.aliases,
-	})
+	}
+
+	resolutions, err := metricdatasource.Resolve(cfg)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	cfg.Resolutions = resolutions
+
+	gatherer, err := metricdatasource.NewGatherer(cfg)
+	if err != nil {
+		return nil, nil, err
 	}
+
+	gatherer = metricmiddleware.Retry(metricmiddleware.RetryConfig{
+		Retries:    m.flags.retries,
+		Backoff:    m.flags.retryBackoff,
+		MaxBackoff: m.flags.refreshInterval,
+	}, gatherer)
+	gatherer = metricmiddleware.CircuitBreaker(metricmiddleware.CircuitBreakerConfig{
+		Failures:    m.flags.cbFailures,
+		OpenTimeout: m.flags.cbOpenTimeout,
+	}, gatherer)
+	// Track the health outside the retries and the circuit breaker, so a
+	// query is only recorded once with its final result (open circuits too).
+	tracker := metricmiddleware.NewHealthTracker()
+	health := view.NewDatasourceHealthReporter(tracker, resolutions)
+	gatherer = metricmiddleware.Health(tracker, gatherer)
+	// Cache the results only until the next refresh.
+	gatherer = metricmiddleware.Cache(m.flags.refreshInterval, m.logger, gatherer)
 	gatherer = metricmiddleware.Logger(m.logger, gatherer)
 
-	return gatherer, nil
+	return gatherer, health, nil
 }
 
-func (m *Main) createApp(ctx context.Context, appCfg view.AppConfig, dashboard model.Dashboard, ctrl controller.Controller, renderer render.Renderer) (*view.App, error) {
-	dashCfg := page.DashboardCfg{
-		AppRelativeTimeRange: m.flags.relativeDur,
-		AppOverrideVariables: m.flags.variables,
-		Controller:           ctrl,
-		Dashboard:            dashboard,
-		Renderer:             renderer,
+func (m *Main) createApp(ctx context.Context, appCfg view.AppConfig, cfgs []dashboardConfiguration, userDss []model.Datasource, renderer render.Renderer) (*view.App, error) {
+	// Create one dashboard page per configuration.
+	pages := []sync.Syncer{}
+	for _, c := range cfgs {
+		p, err := m.createDashboardPage(ctx, appCfg, c, userDss, renderer)
+		if err != nil {
+			return nil, fmt.Errorf("error creating %s dashboard: %s", c.path, err)
+		}
+		pages = append(pages, p)
 	}
 
-	syncer, err := page.NewDashboard(ctx, dashCfg, m.logger)
+	syncer, err := page.NewPlaylist(ctx, page.PlaylistCfg{
+		Pages:    pages,
+		Interval: m.flags.playlistInt,
+		Renderer: renderer,
+	}, m.logger)
 	if err != nil {
 		return nil, err
 	}
+
 	app := view.NewApp(appCfg, syncer, m.logger)
 	return app, nil
 }
 
+func (m *Main) createDashboardPage(ctx context.Context, appCfg view.AppConfig, c dashboardConfiguration, userDss []model.Datasource, renderer render.Renderer) (sync.Syncer, error) {
+	ddss, err := c.cfg.Datasources()
+	if err != nil {
+		return nil, err
+	}
+
+	gatherer, health, err := m.createGatherer(ctx, ddss, userDss)
+	if err != nil {
+		return nil, err
+	}
+
+	dashboard, err := c.cfg.Dashboard()
+	if err != nil {
+		return nil, err
+	}
+
+	// If the dashboard doesn't have a title use the file name.
+	if dashboard.Title == "" {
+		dashboard.Title = strings.TrimSuffix(filepath.Base(c.path), filepath.Ext(c.path))
+	}
+
+	dashCfg := page.DashboardCfg{
+		AppRelativeTimeRange: appCfg.RelativeTimeRange,
+		AppTimeRangeStart:    appCfg.TimeRangeStart,
+		AppTimeRangeEnd:      appCfg.TimeRangeEnd,
+		AppOverrideVariables: m.flags.variables,
+		Controller:           controller.NewController(gatherer),
+		Gatherer:             gatherer,
+		Dashboard:            dashboard,
+		Renderer:             renderer,
+		DatasourceHealth:     health,
+		WidgetStaleSyncs:     m.flags.staleSyncs,
+	}
+
+	return page.NewDashboard(ctx, dashCfg, m.logger)
+}
+
 // timeFromFlag gets the time from a flag based on a duration or on a
 // fixed time stamp.
 func timeFromFlag(v string) (time.Time, error) {
@@ -16,6 +16,8 @@ type Controller interface {
 	GetSingleMetric(ctx context.Context, query model.Query, t time.Time) (*model.Metric, error)
 	// GetSingleInstantMetric will get one single metric value in real time.
 	GetSingleInstantMetric(ctx context.Context, query model.Query) (*model.Metric, error)
+	// GetMetrics will get the metric value of all the series at a point in time.
+	GetMetrics(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error)
 	// GetRangeMetrics will get N metrics based in a time range.
 	GetRangeMetrics(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error)
 }
@@ -52,6 +54,25 @@ func (c controller) GetSingleInstantMetric(ctx context.Context, query model.Quer
 	return c.GetSingleMetric(ctx, query, time.Now().UTC())
 }
 
+func (c controller) GetMetrics(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	ms, err := c.gatherer.GatherSingle(ctx, query, t)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// Only return the series with a value, and only the latest one.
+	res := []model.MetricSeries{}
+	for _, s := range ms {
+		if len(s.Metrics) == 0 {
+			continue
+		}
+		s.Metrics = s.Metrics[len(s.Metrics)-1:]
+		res = append(res, s)
+	}
+
+	return res, nil
+}
+
 func (c controller) GetRangeMetrics(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
 	if step <= 0 {
 		return nil, fmt.Errorf("step must be positive")
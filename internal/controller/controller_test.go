@@ -109,6 +109,69 @@ func TestGetSingleMetric(t *testing.T) {
 	}
 }
 
+func TestGetMetrics(t *testing.T) {
+	ts := time.Now()
+
+	tests := []struct {
+		name           string
+		query          model.Query
+		serviceMetrics []model.MetricSeries
+		serviceErr     error
+		expErr         bool
+		expSeries      []model.MetricSeries
+	}{
+		{
+			name:  "Returning multiple series the controller should return all of them with the latest value.",
+			query: model.Query{Expr: "test"},
+			serviceMetrics: []model.MetricSeries{
+				{ID: "s1", Metrics: []model.Metric{{Value: 17.9}}},
+				{ID: "s2", Metrics: []model.Metric{{Value: 28.1}, {Value: 30.5}}},
+			},
+			expSeries: []model.MetricSeries{
+				{ID: "s1", Metrics: []model.Metric{{Value: 17.9}}},
+				{ID: "s2", Metrics: []model.Metric{{Value: 30.5}}},
+			},
+		},
+		{
+			name:  "Series without metrics should be ignored.",
+			query: model.Query{Expr: "test"},
+			serviceMetrics: []model.MetricSeries{
+				{ID: "s1", Metrics: []model.Metric{}},
+				{ID: "s2", Metrics: []model.Metric{{Value: 28.1}}},
+			},
+			expSeries: []model.MetricSeries{
+				{ID: "s2", Metrics: []model.Metric{{Value: 28.1}}},
+			},
+		},
+		{
+			name:       "Returning a error from the metrics service should error.",
+			query:      model.Query{Expr: "test"},
+			serviceErr: errors.New("wanted error"),
+			expErr:     true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mg := &mmetric.Gatherer{}
+			mg.On("GatherSingle", mock.Anything, test.query, ts).Once().Return(test.serviceMetrics, test.serviceErr)
+
+			c := controller.NewController(mg)
+			gotms, err := c.GetMetrics(context.TODO(), test.query, ts)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expSeries, gotms)
+				mg.AssertExpectations(t)
+			}
+		})
+	}
+}
+
 func TestGetRangeMetrics(t *testing.T) {
 	start := time.Now()
 	end := start.Add(5 * time.Hour)
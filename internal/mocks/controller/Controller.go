@@ -13,6 +13,29 @@ type Controller struct {
 	mock.Mock
 }
 
+// GetMetrics provides a mock function with given fields: ctx, query, t
+func (_m *Controller) GetMetrics(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	ret := _m.Called(ctx, query, t)
+
+	var r0 []model.MetricSeries
+	if rf, ok := ret.Get(0).(func(context.Context, model.Query, time.Time) []model.MetricSeries); ok {
+		r0 = rf(ctx, query, t)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]model.MetricSeries)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(context.Context, model.Query, time.Time) error); ok {
+		r1 = rf(ctx, query, t)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // GetRangeMetrics provides a mock function with given fields: ctx, query, start, end, step
 func (_m *Controller) GetRangeMetrics(ctx context.Context, query model.Query, start time.Time, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
 	ret := _m.Called(ctx, query, start, end, step)
@@ -11,6 +11,7 @@ package mocks // import "github.com/slok/grafterm/internal/mocks"
 //go:generate mockery -output ./view/render -outpkg render -dir ../view/render -name GaugeWidget
 //go:generate mockery -output ./view/render -outpkg render -dir ../view/render -name SinglestatWidget
 //go:generate mockery -output ./view/render -outpkg render -dir ../view/render -name GraphWidget
+//go:generate mockery -output ./view/render -outpkg render -dir ../view/render -name TableWidget
 
 // Services mocks.
 //go:generate mockery -output ./service/metric -outpkg metric -dir ../service/metric -name Gatherer
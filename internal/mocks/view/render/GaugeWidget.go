@@ -4,6 +4,7 @@ package render
 
 import mock "github.com/stretchr/testify/mock"
 import model "github.com/slok/grafterm/internal/model"
+import render "github.com/slok/grafterm/internal/view/render"
 
 // GaugeWidget is an autogenerated mock type for the GaugeWidget type
 type GaugeWidget struct {
@@ -38,6 +39,20 @@ func (_m *GaugeWidget) SetColor(hexColor string) error {
 	return r0
 }
 
+// SetState provides a mock function with given fields: state
+func (_m *GaugeWidget) SetState(state render.WidgetState) error {
+	ret := _m.Called(state)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(render.WidgetState) error); ok {
+		r0 = rf(state)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Sync provides a mock function with given fields: isPercent, value
 func (_m *GaugeWidget) Sync(isPercent bool, value float64) error {
 	ret := _m.Called(isPercent, value)
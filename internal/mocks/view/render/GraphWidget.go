@@ -39,6 +39,20 @@ func (_m *GraphWidget) GetWidgetCfg() model.Widget {
 	return r0
 }
 
+// SetState provides a mock function with given fields: state
+func (_m *GraphWidget) SetState(state render.WidgetState) error {
+	ret := _m.Called(state)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(render.WidgetState) error); ok {
+		r0 = rf(state)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
 // Sync provides a mock function with given fields: series
 func (_m *GraphWidget) Sync(series []render.Series) error {
 	ret := _m.Called(series)
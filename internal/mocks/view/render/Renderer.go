@@ -17,13 +17,13 @@ func (_m *Renderer) Close() {
 	_m.Called()
 }
 
-// LoadDashboard provides a mock function with given fields: ctx, _a1
-func (_m *Renderer) LoadDashboard(ctx context.Context, _a1 *grid.Grid) ([]render.Widget, error) {
-	ret := _m.Called(ctx, _a1)
+// LoadDashboard provides a mock function with given fields: ctx, title, _a2, selector, health
+func (_m *Renderer) LoadDashboard(ctx context.Context, title string, _a2 *grid.Grid, selector render.VariableSelector, health render.DatasourceHealthReporter) ([]render.Widget, error) {
+	ret := _m.Called(ctx, title, _a2, selector, health)
 
 	var r0 []render.Widget
-	if rf, ok := ret.Get(0).(func(context.Context, *grid.Grid) []render.Widget); ok {
-		r0 = rf(ctx, _a1)
+	if rf, ok := ret.Get(0).(func(context.Context, string, *grid.Grid, render.VariableSelector, render.DatasourceHealthReporter) []render.Widget); ok {
+		r0 = rf(ctx, title, _a2, selector, health)
 	} else {
 		if ret.Get(0) != nil {
 			r0 = ret.Get(0).([]render.Widget)
@@ -31,11 +31,30 @@ func (_m *Renderer) LoadDashboard(ctx context.Context, _a1 *grid.Grid) ([]render
 	}
 
 	var r1 error
-	if rf, ok := ret.Get(1).(func(context.Context, *grid.Grid) error); ok {
-		r1 = rf(ctx, _a1)
+	if rf, ok := ret.Get(1).(func(context.Context, string, *grid.Grid, render.VariableSelector, render.DatasourceHealthReporter) error); ok {
+		r1 = rf(ctx, title, _a2, selector, health)
 	} else {
 		r1 = ret.Error(1)
 	}
 
 	return r0, r1
 }
+
+// SetPageSwitcher provides a mock function with given fields: switcher
+func (_m *Renderer) SetPageSwitcher(switcher render.PageSwitcher) {
+	_m.Called(switcher)
+}
+
+// ShowPage provides a mock function with given fields: index
+func (_m *Renderer) ShowPage(index int) error {
+	ret := _m.Called(index)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(int) error); ok {
+		r0 = rf(index)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
@@ -15,6 +15,7 @@ const (
 
 // Dashboard represents a dashboard.
 type Dashboard struct {
+	Title     string     `json:"title,omitempty"`
 	Grid      Grid       `json:"grid,omitempty"`
 	Variables []Variable `json:"variables,omitempty"`
 	Widgets   []Widget   `json:"widgets,omitempty"`
@@ -31,6 +32,7 @@ type Variable struct {
 type VariableSource struct {
 	Constant *ConstantVariableSource `json:"constant,omitempty"`
 	Interval *IntervalVariableSource `json:"interval,omitempty"`
+	Query    *QueryVariableSource    `json:"query,omitempty"`
 }
 
 // ConstantVariableSource represents the constant variables.
@@ -43,6 +45,26 @@ type IntervalVariableSource struct {
 	Steps int `json:"steps,omitempty"`
 }
 
+// QueryVariableSource represents the variables that get their values
+// from a datasource query.
+type QueryVariableSource struct {
+	// Query is the query that will be made to the datasource to get the values,
+	// Prometheus style `label_values(metric, label)` expressions are supported.
+	Query Query `json:"query,omitempty"`
+	// Label is the label of the returned series that will be used as the value,
+	// if empty the series ID will be used.
+	Label string `json:"label,omitempty"`
+	// Regex will filter the values, if it has a capture group the captured
+	// value will be used as the value.
+	Regex         string         `json:"regex,omitempty"`
+	CompiledRegex *regexp.Regexp `json:"-"`
+	// Multi allows selecting multiple values at the same time.
+	Multi bool `json:"multi,omitempty"`
+	// Default is the value used when the values can't be obtained from
+	// the datasource.
+	Default string `json:"default,omitempty"`
+}
+
 // Widget represents a widget.
 type Widget struct {
 	Title        string  `json:"title,omitempty"`
@@ -74,8 +96,10 @@ type GridPos struct {
 	Y int `json:"y,omitempty"`
 	// W represents the width of the widget (same unit as X).
 	W int `json:"w,omitempty"`
-	// TODO(slok): H represents the height of the widget (same unit as Y).
-	// H int `json:"h,omitempty"`
+	// H represents the height of the widget (same unit as Y), the widgets
+	// on the same row will use the height of the tallest widget of the row.
+	// Not setting H or setting to 0 would fallback to a height of 1.
+	H int `json:"h,omitempty"`
 }
 
 // WidgetSource will tell what kind of widget is.
@@ -83,6 +107,7 @@ type WidgetSource struct {
 	Singlestat *SinglestatWidgetSource `json:"singlestat,omitempty"`
 	Gauge      *GaugeWidgetSource      `json:"gauge,omitempty"`
 	Graph      *GraphWidgetSource      `json:"graph,omitempty"`
+	Table      *TableWidgetSource      `json:"table,omitempty"`
 }
 
 // SinglestatWidgetSource represents a simple value widget.
@@ -106,6 +131,35 @@ type GaugeWidgetSource struct {
 type GraphWidgetSource struct {
 	Queries       []Query            `json:"queries,omitempty"`
 	Visualization GraphVisualization `json:"visualization,omitempty"`
+	// QueryConcurrency is the maximum number of queries that will be
+	// gathered at the same time.
+	QueryConcurrency int `json:"queryConcurrency,omitempty"`
+}
+
+// TableValueColumn is the name of the column that has the value of the series
+// on the table widgets.
+const TableValueColumn = "value"
+
+// TableWidgetSource represents a widget that shows one row per series
+// with the labels of the series and the value.
+type TableWidgetSource struct {
+	ValueRepresentation `json:",inline"`
+	Query               Query `json:"query,omitempty"`
+	// Columns are the columns and their order that will be shown, the label names
+	// and `value` for the value of the series can be used. If not set all the
+	// labels will be shown sorted by name followed by the value.
+	Columns []string `json:"columns,omitempty"`
+	// Sort is the sort options of the table rows.
+	Sort       TableSort   `json:"sort,omitempty"`
+	Thresholds []Threshold `json:"thresholds,omitempty"`
+}
+
+// TableSort controls how the rows of a table widget will be sorted.
+type TableSort struct {
+	// Column is the column used to sort the rows, by default the first column.
+	Column string `json:"column,omitempty"`
+	// Desc will sort the rows in descending order.
+	Desc bool `json:"desc,omitempty"`
 }
 
 // Query is the query that will be made to the datasource.
@@ -196,7 +250,14 @@ func (d *Dashboard) Validate() error {
 		}
 	}
 
-	// TODO(slok): Validate all widgets as a whole (for example total of grid)
+	// Validate all widgets as a whole.
+	if d.Grid.FixedWidgets {
+		err := validateFixedWidgetsOverlap(d.Widgets)
+		if err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -224,6 +285,11 @@ func (v Variable) validate() error {
 		if i.Steps <= 0 {
 			return fmt.Errorf("%s interval variable step should be > 0", v.Name)
 		}
+	case v.VariableSource.Query != nil:
+		err := v.VariableSource.Query.validate()
+		if err != nil {
+			return fmt.Errorf("%s query variable: %s", v.Name, err)
+		}
 	default:
 		return fmt.Errorf("%s variable is empty, it should be of a specific type", v.Name)
 	}
@@ -231,6 +297,23 @@ func (v Variable) validate() error {
 	return nil
 }
 
+func (q *QueryVariableSource) validate() error {
+	err := q.Query.validate()
+	if err != nil {
+		return err
+	}
+
+	if q.Regex != "" {
+		re, err := regexp.Compile(q.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex: %s", err)
+		}
+		q.CompiledRegex = re
+	}
+
+	return nil
+}
+
 func (w Widget) validate(d Dashboard) error {
 	err := w.GridPos.validate(d.Grid)
 	if err != nil {
@@ -253,6 +336,11 @@ func (w Widget) validate(d Dashboard) error {
 		if err != nil {
 			return fmt.Errorf("error on %s graph widget: %s", w.Title, err)
 		}
+	case w.Table != nil:
+		err := w.Table.validate()
+		if err != nil {
+			return fmt.Errorf("error on %s table widget: %s", w.Title, err)
+		}
 	}
 	return nil
 }
@@ -262,17 +350,51 @@ func (g GridPos) validate(gr Grid) error {
 		return fmt.Errorf("widget grid position should have a width")
 	}
 
-	if gr.FixedWidgets && g.X <= 0 {
-		return fmt.Errorf("widget grid position in a fixed grid should have am X position")
+	if g.H < 0 {
+		return fmt.Errorf("widget grid position height can't be negative")
 	}
 
-	if gr.FixedWidgets && g.Y <= 0 {
-		return fmt.Errorf("widget grid position in a fixed grid should have am Y position")
+	// Fixed grids place the widgets from the 0 position.
+	if gr.FixedWidgets && g.X < 0 {
+		return fmt.Errorf("widget grid position in a fixed grid can't have a negative X position")
+	}
+
+	if gr.FixedWidgets && g.Y < 0 {
+		return fmt.Errorf("widget grid position in a fixed grid can't have a negative Y position")
 	}
 
 	return nil
 }
 
+// validateFixedWidgetsOverlap checks that the widgets placed on a fixed
+// grid don't overlap between them.
+func validateFixedWidgetsOverlap(ws []Widget) error {
+	for i, w1 := range ws {
+		for _, w2 := range ws[i+1:] {
+			if w1.GridPos.overlaps(w2.GridPos) {
+				return fmt.Errorf("%s widget and %s widget grid positions overlap", w1.Title, w2.Title)
+			}
+		}
+	}
+
+	return nil
+}
+
+// overlaps returns true if both grid positions share part of the grid.
+func (g GridPos) overlaps(other GridPos) bool {
+	height := func(g GridPos) int {
+		if g.H <= 0 {
+			return 1
+		}
+		return g.H
+	}
+
+	overlapX := g.X < other.X+other.W && other.X < g.X+g.W
+	overlapY := g.Y < other.Y+height(other) && other.Y < g.Y+height(g)
+
+	return overlapX && overlapY
+}
+
 func (g GaugeWidgetSource) validate() error {
 	err := g.Query.validate()
 	if err != nil {
@@ -315,6 +437,10 @@ func (g GraphWidgetSource) validate() error {
 		return fmt.Errorf("graph must have at least one query")
 	}
 
+	if g.QueryConcurrency < 0 {
+		return fmt.Errorf("graph query concurrency can't be negative")
+	}
+
 	for _, q := range g.Queries {
 		err := q.validate()
 		if err != nil {
@@ -336,6 +462,43 @@ func (g GraphWidgetSource) validate() error {
 	return nil
 }
 
+func (t TableWidgetSource) validate() error {
+	err := t.Query.validate()
+	if err != nil {
+		return fmt.Errorf("query error on table widget: %s", err)
+	}
+
+	err = t.ValueRepresentation.validate()
+	if err != nil {
+		return err
+	}
+
+	columns := map[string]struct{}{}
+	for _, c := range t.Columns {
+		if c == "" {
+			return fmt.Errorf("table columns can't be empty")
+		}
+
+		_, ok := columns[c]
+		if ok {
+			return fmt.Errorf("table column %s can't be repeated", c)
+		}
+		columns[c] = struct{}{}
+	}
+
+	// If the columns are set, the sort column should be one of them.
+	if _, ok := columns[t.Sort.Column]; len(t.Columns) > 0 && t.Sort.Column != "" && !ok {
+		return fmt.Errorf("table sort column %s is not a table column", t.Sort.Column)
+	}
+
+	err = validateThresholds(t.Thresholds)
+	if err != nil {
+		return fmt.Errorf("thresholds error on table widget: %s", err)
+	}
+
+	return nil
+}
+
 func (q Query) validate() error {
 	if q.Expr == "" {
 		return fmt.Errorf("query must have an expression")
@@ -87,6 +87,22 @@ func getBaseDashboard() model.Dashboard {
 					},
 				}},
 			},
+			model.Widget{
+				Title:   "test-table",
+				GridPos: model.GridPos{W: 10, Y: 10, X: 10},
+				WidgetSource: model.WidgetSource{Table: &model.TableWidgetSource{
+					Query: model.Query{
+						Expr:         "query",
+						DatasourceID: "test",
+					},
+					Columns: []string{"pod", "namespace", "value"},
+					Sort:    model.TableSort{Column: "value", Desc: true},
+					Thresholds: []model.Threshold{
+						model.Threshold{Color: "#FFFFFF"},
+						model.Threshold{Color: "#FFF000", StartValue: 10},
+					},
+				}},
+			},
 		},
 	}
 }
@@ -162,6 +178,61 @@ func TestValidateDashboard(t *testing.T) {
 			},
 			expErr: true,
 		},
+		{
+			name: "Query variables should have a valid query.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Variables[0] = model.Variable{
+					Name: "test",
+					VariableSource: model.VariableSource{Query: &model.QueryVariableSource{
+						Query: model.Query{Expr: "up"},
+					}},
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "Query variables should have a valid regex.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Variables[0] = model.Variable{
+					Name: "test",
+					VariableSource: model.VariableSource{Query: &model.QueryVariableSource{
+						Query: model.Query{Expr: "up", DatasourceID: "test"},
+						Regex: "([a-z]+",
+					}},
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "Query variables should have the regex compiled.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Variables[0] = model.Variable{
+					Name: "test",
+					VariableSource: model.VariableSource{Query: &model.QueryVariableSource{
+						Query: model.Query{Expr: "up", DatasourceID: "test"},
+						Regex: "([a-z]+)",
+					}},
+				}
+				return d
+			},
+			expDashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Variables[0] = model.Variable{
+					Name: "test",
+					VariableSource: model.VariableSource{Query: &model.QueryVariableSource{
+						Query:         model.Query{Expr: "up", DatasourceID: "test"},
+						Regex:         "([a-z]+)",
+						CompiledRegex: regexp.MustCompile("([a-z]+)"),
+					}},
+				}
+				return d
+			},
+		},
 
 		// Widgets.
 		{
@@ -176,29 +247,102 @@ func TestValidateDashboard(t *testing.T) {
 			expErr: true,
 		},
 		{
-			name: "A widget grid position with fixed grid, Y is required.",
+			name: "A widget grid position with fixed grid, X can't be negative.",
 			dashboard: func() model.Dashboard {
 				d := getBaseDashboard()
 				d.Grid.FixedWidgets = true
 				w := d.Widgets[0]
-				w.GridPos.X = 0
+				w.GridPos.X = -1
 				d.Widgets[0] = w
 				return d
 			},
 			expErr: true,
 		},
 		{
-			name: "A widget grid position with fixed grid, Y is required.",
+			name: "A widget grid position with fixed grid, Y can't be negative.",
 			dashboard: func() model.Dashboard {
 				d := getBaseDashboard()
 				d.Grid.FixedWidgets = true
 				w := d.Widgets[0]
-				w.GridPos.Y = 0
+				w.GridPos.Y = -1
 				d.Widgets[0] = w
 				return d
 			},
 			expErr: true,
 		},
+		{
+			name: "A widget grid position height can't be negative.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Widgets[0].GridPos.H = -1
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "Widgets on a fixed grid can't overlap.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Grid.FixedWidgets = true
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "Widgets on a fixed grid can't overlap with the height of other widgets.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Grid.FixedWidgets = true
+				d.Widgets[0].GridPos = model.GridPos{X: 1, Y: 1, W: 20, H: 5}
+				d.Widgets[1].GridPos = model.GridPos{X: 21, Y: 1, W: 20}
+				d.Widgets[2].GridPos = model.GridPos{X: 10, Y: 5, W: 20}
+				d.Widgets[3].GridPos = model.GridPos{X: 1, Y: 7, W: 20}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "Widgets on a fixed grid that don't overlap should be valid.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Grid.FixedWidgets = true
+				d.Widgets[0].GridPos = model.GridPos{X: 1, Y: 1, W: 20, H: 5}
+				d.Widgets[1].GridPos = model.GridPos{X: 21, Y: 1, W: 20}
+				d.Widgets[2].GridPos = model.GridPos{X: 21, Y: 2, W: 20, H: 4}
+				d.Widgets[3].GridPos = model.GridPos{X: 1, Y: 6, W: 40}
+				return d
+			},
+			expDashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Grid.FixedWidgets = true
+				d.Widgets[0].GridPos = model.GridPos{X: 1, Y: 1, W: 20, H: 5}
+				d.Widgets[1].GridPos = model.GridPos{X: 21, Y: 1, W: 20}
+				d.Widgets[2].GridPos = model.GridPos{X: 21, Y: 2, W: 20, H: 4}
+				d.Widgets[3].GridPos = model.GridPos{X: 1, Y: 6, W: 40}
+				return d
+			},
+		},
+		{
+			name: "Widgets on a fixed grid can be placed on the start of the grid.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Grid.FixedWidgets = true
+				d.Widgets[0].GridPos = model.GridPos{X: 0, Y: 0, W: 20}
+				d.Widgets[1].GridPos = model.GridPos{X: 20, Y: 0, W: 20}
+				d.Widgets[2].GridPos = model.GridPos{X: 0, Y: 1, W: 20}
+				d.Widgets[3].GridPos = model.GridPos{X: 20, Y: 1, W: 20}
+				return d
+			},
+			expDashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Grid.FixedWidgets = true
+				d.Widgets[0].GridPos = model.GridPos{X: 0, Y: 0, W: 20}
+				d.Widgets[1].GridPos = model.GridPos{X: 20, Y: 0, W: 20}
+				d.Widgets[2].GridPos = model.GridPos{X: 0, Y: 1, W: 20}
+				d.Widgets[3].GridPos = model.GridPos{X: 20, Y: 1, W: 20}
+				return d
+			},
+		},
 
 		// Gauge widget.
 		{
@@ -345,6 +489,17 @@ func TestValidateDashboard(t *testing.T) {
 			},
 			expErr: true,
 		},
+		{
+			name: "A graph widget query concurrency can't be negative.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				w := d.Widgets[2]
+				w.Graph.QueryConcurrency = -1
+				d.Widgets[2] = w
+				return d
+			},
+			expErr: true,
+		},
 		{
 			name: "A graph widget series override should have a regex.",
 			dashboard: func() model.Dashboard {
@@ -406,6 +561,71 @@ func TestValidateDashboard(t *testing.T) {
 			},
 			expErr: true,
 		},
+
+		// Table widget.
+		{
+			name: "A table widget with a query should have an expression.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Widgets[3].Table.Query.Expr = ""
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A table widget should have a valid unit.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Widgets[3].Table.Unit = "unknown"
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A table widget can't have repeated columns.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Widgets[3].Table.Columns = []string{"pod", "pod"}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A table widget sort column should be one of the columns.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Widgets[3].Table.Sort.Column = "missing"
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A table widget without columns can sort by any column.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Widgets[3].Table.Columns = nil
+				d.Widgets[3].Table.Sort.Column = "pod"
+				return d
+			},
+			expDashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Widgets[3].Table.Columns = nil
+				d.Widgets[3].Table.Sort.Column = "pod"
+				return d
+			},
+		},
+		{
+			name: "A table widget thresholds can't have same start values.",
+			dashboard: func() model.Dashboard {
+				d := getBaseDashboard()
+				d.Widgets[3].Table.Thresholds = []model.Threshold{
+					model.Threshold{Color: "#FFFFFF", StartValue: 5},
+					model.Threshold{Color: "#FFF000", StartValue: 5},
+				}
+				return d
+			},
+			expErr: true,
+		},
 	}
 
 	for _, test := range tests {
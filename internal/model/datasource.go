@@ -1,6 +1,10 @@
 package model
 
-import "fmt"
+import (
+	"fmt"
+	"net/url"
+	"time"
+)
 
 // Datasource is where the data will be retrieved.
 type Datasource struct {
@@ -10,32 +14,147 @@ type Datasource struct {
 
 // DatasourceSource represents the datasource.
 type DatasourceSource struct {
-	Fake       *FakeDatasource       `json:"fake,omitempty"`
-	Prometheus *PrometheusDatasource `json:"prometheus,omitempty"`
-	Graphite   *GraphiteDatasource   `json:"graphite,omitempty"`
-	InfluxDB   *InfluxDBDatasource   `json:"influxdb,omitempty"`
+	Fake          *FakeDatasource          `json:"fake,omitempty"`
+	Prometheus    *PrometheusDatasource    `json:"prometheus,omitempty"`
+	Graphite      *GraphiteDatasource      `json:"graphite,omitempty"`
+	InfluxDB      *InfluxDBDatasource      `json:"influxdb,omitempty"`
+	InfluxDB2     *InfluxDB2Datasource     `json:"influxdb2,omitempty"`
+	Elasticsearch *ElasticsearchDatasource `json:"elasticsearch,omitempty"`
+	System        *SystemDatasource        `json:"system,omitempty"`
+	Exec          *ExecDatasource          `json:"exec,omitempty"`
+	File          *FileDatasource          `json:"file,omitempty"`
+	SQL           *SQLDatasource           `json:"sql,omitempty"`
+	Scrape        *ScrapeDatasource        `json:"scrape,omitempty"`
+	OpenTSDB      *OpenTSDBDatasource      `json:"opentsdb,omitempty"`
 }
 
 // FakeDatasource is the fake datasource.
 type FakeDatasource struct{}
 
+// HTTPOptions are the options of the HTTP client used by the HTTP
+// kind datasources.
+type HTTPOptions struct {
+	// Username and Password will set the basic auth of the requests.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// BearerToken will set the bearer token auth of the requests.
+	BearerToken string `json:"bearerToken,omitempty"`
+	// Headers are custom headers set on the requests (e.g `X-Scope-OrgID`).
+	Headers map[string]string `json:"headers,omitempty"`
+	// CAFile is the CA bundle used to verify the server certificates.
+	CAFile string `json:"caFile,omitempty"`
+	// CertFile and KeyFile are the client certificate used on TLS.
+	CertFile string `json:"certFile,omitempty"`
+	KeyFile  string `json:"keyFile,omitempty"`
+	// Insecure will skip the server certificates verification.
+	Insecure bool `json:"insecure,omitempty"`
+	// Timeout is the timeout of the requests (e.g `10s`).
+	Timeout string `json:"timeout,omitempty"`
+	// ProxyURL is the proxy used on the requests, by default
+	// the proxy of the environment is used.
+	ProxyURL string `json:"proxyURL,omitempty"`
+}
+
 // PrometheusDatasource is the Prometheus kind datasource.
 type PrometheusDatasource struct {
-	Address string `json:"address,omitempty"`
+	Address     string `json:"address,omitempty"`
+	HTTPOptions `json:",inline"`
 }
 
 // GraphiteDatasource is the Graphite kind datasource.
 type GraphiteDatasource struct {
-	Address string `json:"address,omitempty"`
+	Address     string `json:"address,omitempty"`
+	HTTPOptions `json:",inline"`
 }
 
 // InfluxDBDatasource is the Graphite kind datasource.
 type InfluxDBDatasource struct {
-	Address  string `json:"address,omitempty"`
-	Insecure bool   `json:"insecure,omitempty"`
-	Database string `json:"database,omitempty"`
-	Username string `json:"username,omitempty"`
-	Password string `json:"password,omitempty"`
+	Address     string `json:"address,omitempty"`
+	Database    string `json:"database,omitempty"`
+	HTTPOptions `json:",inline"`
+}
+
+// InfluxDB2Datasource is the InfluxDB 2.x kind datasource, it uses Flux queries.
+type InfluxDB2Datasource struct {
+	Address     string `json:"address,omitempty"`
+	Org         string `json:"org,omitempty"`
+	Bucket      string `json:"bucket,omitempty"`
+	Token       string `json:"token,omitempty"`
+	HTTPOptions `json:",inline"`
+}
+
+// ElasticsearchDatasource is the Elasticsearch kind datasource.
+type ElasticsearchDatasource struct {
+	Address string `json:"address,omitempty"`
+	// Index is the index or the index pattern where the documents are searched.
+	Index string `json:"index,omitempty"`
+	// TimeField is the field of the documents with the timestamp.
+	TimeField   string `json:"timeField,omitempty"`
+	HTTPOptions `json:",inline"`
+}
+
+// SystemDatasource is the local system kind datasource, it gets the
+// metrics from the proc filesystem.
+type SystemDatasource struct {
+	// ProcPath is the path of the proc filesystem, by default `/proc`.
+	ProcPath string `json:"procPath,omitempty"`
+	// Interval is the interval of the metrics collection (e.g `2s`).
+	Interval string `json:"interval,omitempty"`
+	// Retention is the time the collected metrics are kept (e.g `1h`).
+	Retention string `json:"retention,omitempty"`
+	// Processes enables the collection of the metrics of every process.
+	Processes bool `json:"processes,omitempty"`
+}
+
+// ExecDatasource is the command kind datasource, the query expressions
+// are commands that print the metrics.
+type ExecDatasource struct {
+	// Dir is the working directory of the commands.
+	Dir string `json:"dir,omitempty"`
+	// Timeout is the maximum time the commands can run (e.g `10s`).
+	Timeout string `json:"timeout,omitempty"`
+	// MaxOutputBytes is the maximum size of the commands output.
+	MaxOutputBytes int `json:"maxOutputBytes,omitempty"`
+}
+
+// FileDatasource is the file kind datasource, it gets the metrics from
+// the samples of a file.
+type FileDatasource struct {
+	// Path is the path of the CSV or JSON lines file.
+	Path string `json:"path,omitempty"`
+	// Format is the format of the file (`csv` or `jsonl`), by default
+	// it's detected by the file extension.
+	Format string `json:"format,omitempty"`
+}
+
+// SQLDatasource is the SQL database kind datasource.
+type SQLDatasource struct {
+	// Driver is the database driver (e.g `postgres` or `mysql`).
+	Driver string `json:"driver,omitempty"`
+	// DSN is the data source name of the database connection.
+	DSN string `json:"dsn,omitempty"`
+	// TimeColumn is the column with the time of the metrics, by default `time`.
+	TimeColumn string `json:"timeColumn,omitempty"`
+	// ValueColumn is the column with the value of the metrics, by default `value`.
+	ValueColumn string `json:"valueColumn,omitempty"`
+}
+
+// ScrapeDatasource is the Prometheus metrics endpoint kind datasource, it
+// scrapes the endpoint directly without a Prometheus server.
+type ScrapeDatasource struct {
+	// Address is the URL of the metrics endpoint (e.g `http://127.0.0.1:8080/metrics`).
+	Address string `json:"address,omitempty"`
+	// Interval is the interval of the scrapes (e.g `5s`).
+	Interval string `json:"interval,omitempty"`
+	// Retention is the time the scraped metrics are kept (e.g `1h`).
+	Retention   string `json:"retention,omitempty"`
+	HTTPOptions `json:",inline"`
+}
+
+// OpenTSDBDatasource is the OpenTSDB kind datasource.
+type OpenTSDBDatasource struct {
+	Address     string `json:"address,omitempty"`
+	HTTPOptions `json:",inline"`
 }
 
 // Validate validates the object model is correct.
@@ -53,6 +172,22 @@ func (d Datasource) Validate() error {
 		err = d.Graphite.validate()
 	case d.InfluxDB != nil:
 		err = d.InfluxDB.validate()
+	case d.InfluxDB2 != nil:
+		err = d.InfluxDB2.validate()
+	case d.Elasticsearch != nil:
+		err = d.Elasticsearch.validate()
+	case d.System != nil:
+		err = d.System.validate()
+	case d.Exec != nil:
+		err = d.Exec.validate()
+	case d.File != nil:
+		err = d.File.validate()
+	case d.SQL != nil:
+		err = d.SQL.validate()
+	case d.Scrape != nil:
+		err = d.Scrape.validate()
+	case d.OpenTSDB != nil:
+		err = d.OpenTSDB.validate()
 	case d.Fake != nil:
 	default:
 		err = fmt.Errorf("declared datasource %s can't be empty", d.ID)
@@ -69,7 +204,7 @@ func (p PrometheusDatasource) validate() error {
 		return fmt.Errorf("prometheus address can't be empty")
 	}
 
-	return nil
+	return p.HTTPOptions.validate()
 }
 
 func (g GraphiteDatasource) validate() error {
@@ -77,7 +212,7 @@ func (g GraphiteDatasource) validate() error {
 		return fmt.Errorf("Graphite API address can't be empty")
 	}
 
-	return nil
+	return g.HTTPOptions.validate()
 }
 
 func (g InfluxDBDatasource) validate() error {
@@ -85,5 +220,161 @@ func (g InfluxDBDatasource) validate() error {
 		return fmt.Errorf("InfluxDB API address can't be empty")
 	}
 
+	// The InfluxDB client doesn't accept a custom transport.
+	if g.BearerToken != "" {
+		return fmt.Errorf("InfluxDB datasource doesn't support bearer token auth, use username and password")
+	}
+
+	if len(g.Headers) > 0 {
+		return fmt.Errorf("InfluxDB datasource doesn't support custom headers")
+	}
+
+	return g.HTTPOptions.validate()
+}
+
+func (i InfluxDB2Datasource) validate() error {
+	if i.Address == "" {
+		return fmt.Errorf("InfluxDB 2 API address can't be empty")
+	}
+
+	if i.Org == "" {
+		return fmt.Errorf("InfluxDB 2 org can't be empty")
+	}
+
+	if i.Token != "" && (i.BearerToken != "" || i.Username != "") {
+		return fmt.Errorf("InfluxDB 2 token can't be used with other auth methods")
+	}
+
+	return i.HTTPOptions.validate()
+}
+
+func (e ElasticsearchDatasource) validate() error {
+	if e.Address == "" {
+		return fmt.Errorf("Elasticsearch address can't be empty")
+	}
+
+	if e.Index == "" {
+		return fmt.Errorf("Elasticsearch index can't be empty")
+	}
+
+	return e.HTTPOptions.validate()
+}
+
+func (s SystemDatasource) validate() error {
+	for name, v := range map[string]string{"interval": s.Interval, "retention": s.Retention} {
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid system datasource %s: %s", name, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("system datasource %s should be > 0", name)
+		}
+	}
+
+	return nil
+}
+
+func (e ExecDatasource) validate() error {
+	if e.Timeout != "" {
+		t, err := time.ParseDuration(e.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid exec datasource timeout: %s", err)
+		}
+		if t <= 0 {
+			return fmt.Errorf("exec datasource timeout should be > 0")
+		}
+	}
+
+	if e.MaxOutputBytes < 0 {
+		return fmt.Errorf("exec datasource max output bytes can't be negative")
+	}
+
+	return nil
+}
+
+func (f FileDatasource) validate() error {
+	if f.Path == "" {
+		return fmt.Errorf("file datasource path can't be empty")
+	}
+
+	switch f.Format {
+	case "", "csv", "jsonl":
+	default:
+		return fmt.Errorf("unknown file datasource format %s", f.Format)
+	}
+
+	return nil
+}
+
+func (s SQLDatasource) validate() error {
+	if s.Driver == "" {
+		return fmt.Errorf("SQL driver can't be empty")
+	}
+
+	if s.DSN == "" {
+		return fmt.Errorf("SQL DSN can't be empty")
+	}
+
+	return nil
+}
+
+func (s ScrapeDatasource) validate() error {
+	if s.Address == "" {
+		return fmt.Errorf("scrape address can't be empty")
+	}
+
+	for name, v := range map[string]string{"interval": s.Interval, "retention": s.Retention} {
+		if v == "" {
+			continue
+		}
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid scrape datasource %s: %s", name, err)
+		}
+		if d <= 0 {
+			return fmt.Errorf("scrape datasource %s should be > 0", name)
+		}
+	}
+
+	return s.HTTPOptions.validate()
+}
+
+func (o OpenTSDBDatasource) validate() error {
+	if o.Address == "" {
+		return fmt.Errorf("OpenTSDB address can't be empty")
+	}
+
+	return o.HTTPOptions.validate()
+}
+
+func (h HTTPOptions) validate() error {
+	if h.Username != "" && h.BearerToken != "" {
+		return fmt.Errorf("basic auth and bearer token can't be used at the same time")
+	}
+
+	if (h.CertFile == "") != (h.KeyFile == "") {
+		return fmt.Errorf("client certificate requires cert and key files")
+	}
+
+	if h.Timeout != "" {
+		t, err := time.ParseDuration(h.Timeout)
+		if err != nil {
+			return fmt.Errorf("invalid timeout: %s", err)
+		}
+		if t <= 0 {
+			return fmt.Errorf("timeout should be > 0")
+		}
+	}
+
+	if h.ProxyURL != "" {
+		_, err := url.Parse(h.ProxyURL)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %s", err)
+		}
+	}
+
 	return nil
 }
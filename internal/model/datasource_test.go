@@ -81,6 +81,162 @@ func TestValidateDatasource(t *testing.T) {
 			},
 			expErr: true,
 		},
+		{
+			name: "A datasource with basic auth and bearer token should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.Prometheus = &model.PrometheusDatasource{
+					Address:     "http://127.0.0.1:9090",
+					HTTPOptions: model.HTTPOptions{Username: "user", BearerToken: "token"},
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A datasource with a client certificate without key should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.Graphite = &model.GraphiteDatasource{
+					Address:     "http://127.0.0.1:7123",
+					HTTPOptions: model.HTTPOptions{CertFile: "/tmp/cert.pem"},
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A datasource with an invalid timeout should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.Prometheus = &model.PrometheusDatasource{
+					Address:     "http://127.0.0.1:9090",
+					HTTPOptions: model.HTTPOptions{Timeout: "10"},
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A InfluxDB datasource with bearer token should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.InfluxDB = &model.InfluxDBDatasource{
+					Address:     "http://127.0.0.1:8086",
+					HTTPOptions: model.HTTPOptions{BearerToken: "my-token"},
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A InfluxDB datasource with custom headers should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.InfluxDB = &model.InfluxDBDatasource{
+					Address:     "http://127.0.0.1:8086",
+					HTTPOptions: model.HTTPOptions{Headers: map[string]string{"X-Scope-OrgID": "tenant1"}},
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A system datasource with an invalid interval should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.System = &model.SystemDatasource{
+					Interval: "-1s",
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "An exec datasource with a negative max output should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.Exec = &model.ExecDatasource{
+					MaxOutputBytes: -1,
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A file datasource without path should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.File = &model.FileDatasource{
+					Format: "csv",
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A file datasource with an unknown format should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.File = &model.FileDatasource{
+					Path:   "samples.txt",
+					Format: "txt",
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A SQL datasource without DSN should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.SQL = &model.SQLDatasource{
+					Driver: "postgres",
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A scrape datasource with an invalid interval should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.Scrape = &model.ScrapeDatasource{
+					Address:  "http://127.0.0.1:8080/metrics",
+					Interval: "5",
+				}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "An OpenTSDB datasource without address should error.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.OpenTSDB = &model.OpenTSDBDatasource{}
+				return d
+			},
+			expErr: true,
+		},
+		{
+			name: "A datasource with HTTP options should be valid.",
+			ds: func() model.Datasource {
+				d := getBaseDatasource()
+				d.Prometheus = &model.PrometheusDatasource{
+					Address: "http://127.0.0.1:9090",
+					HTTPOptions: model.HTTPOptions{
+						BearerToken: "token",
+						Headers:     map[string]string{"X-Scope-OrgID": "tenant1"},
+						CertFile:    "/tmp/cert.pem",
+						KeyFile:     "/tmp/key.pem",
+						Timeout:     "10s",
+						ProxyURL:    "http://127.0.0.1:3128",
+					},
+				}
+				return d
+			},
+			expErr: false,
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,181 @@
+package grafana
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// The types of this file are the subset of the Grafana dashboard JSON model
+// that the converter understands. Grafana has changed the model a lot between
+// versions so some of the fields can have different types, these fields are
+// decoded as raw JSON and interpreted by the converter.
+
+type dashboard struct {
+	Title      string          `json:"title"`
+	Panels     []panel         `json:"panels"`
+	Rows       json.RawMessage `json:"rows"`
+	Templating struct {
+		List []templateVariable `json:"list"`
+	} `json:"templating"`
+}
+
+type templateVariable struct {
+	Name       string          `json:"name"`
+	Type       string          `json:"type"`
+	Datasource json.RawMessage `json:"datasource"`
+	// Query can be a string or an object with the query.
+	Query      json.RawMessage  `json:"query"`
+	Regex      string           `json:"regex"`
+	Multi      bool             `json:"multi"`
+	IncludeAll bool             `json:"includeAll"`
+	Auto       bool             `json:"auto"`
+	AutoCount  int              `json:"auto_count"`
+	Current    variableOption   `json:"current"`
+	Options    []variableOption `json:"options"`
+}
+
+type variableOption struct {
+	// Value can be a string or a list of strings.
+	Value json.RawMessage `json:"value"`
+}
+
+type gridPos struct {
+	X int `json:"x"`
+	Y int `json:"y"`
+	W int `json:"w"`
+	H int `json:"h"`
+}
+
+type panel struct {
+	Type        string          `json:"type"`
+	Title       string          `json:"title"`
+	GridPos     gridPos         `json:"gridPos"`
+	Datasource  json.RawMessage `json:"datasource"`
+	Targets     []target        `json:"targets"`
+	Repeat      string          `json:"repeat"`
+	FieldConfig fieldConfig     `json:"fieldConfig"`
+	Options     panelOptions    `json:"options"`
+	// Panels are the panels of collapsed rows.
+	Panels []panel `json:"panels"`
+
+	// Legacy (graph and singlestat) panel settings.
+	Format          string           `json:"format"`
+	Decimals        *int             `json:"decimals"`
+	Thresholds      json.RawMessage  `json:"thresholds"`
+	Colors          []string         `json:"colors"`
+	ValueName       string           `json:"valueName"`
+	Prefix          string           `json:"prefix"`
+	Postfix         string           `json:"postfix"`
+	Gauge           *legacyGauge     `json:"gauge"`
+	Legend          *legacyLegend    `json:"legend"`
+	YAxes           []legacyYAxis    `json:"yaxes"`
+	SeriesOverrides []seriesOverride `json:"seriesOverrides"`
+	NullPointMode   string           `json:"nullPointMode"`
+}
+
+type target struct {
+	RefID        string          `json:"refId"`
+	Hide         bool            `json:"hide"`
+	Datasource   json.RawMessage `json:"datasource"`
+	Expr         string          `json:"expr"`
+	LegendFormat string          `json:"legendFormat"`
+	Target       string          `json:"target"`
+	Query        string          `json:"query"`
+	RawQuery     bool            `json:"rawQuery"`
+	Alias        string          `json:"alias"`
+}
+
+type fieldConfig struct {
+	Defaults struct {
+		Unit       string      `json:"unit"`
+		Decimals   *int        `json:"decimals"`
+		Min        *float64    `json:"min"`
+		Max        *float64    `json:"max"`
+		Thresholds *thresholds `json:"thresholds"`
+		Custom     struct {
+			// SpanNulls can be a boolean or a number.
+			SpanNulls json.RawMessage `json:"spanNulls"`
+		} `json:"custom"`
+	} `json:"defaults"`
+	Overrides []fieldOverride `json:"overrides"`
+}
+
+type thresholds struct {
+	Mode  string          `json:"mode"`
+	Steps []thresholdStep `json:"steps"`
+}
+
+type thresholdStep struct {
+	Color string `json:"color"`
+	// Value is nil on the base step.
+	Value *float64 `json:"value"`
+}
+
+type fieldOverride struct {
+	Matcher struct {
+		ID      string          `json:"id"`
+		Options json.RawMessage `json:"options"`
+	} `json:"matcher"`
+	Properties []struct {
+		ID    string          `json:"id"`
+		Value json.RawMessage `json:"value"`
+	} `json:"properties"`
+}
+
+type panelOptions struct {
+	Legend *struct {
+		ShowLegend  *bool  `json:"showLegend"`
+		DisplayMode string `json:"displayMode"`
+		Placement   string `json:"placement"`
+	} `json:"legend"`
+	ReduceOptions struct {
+		Calcs []string `json:"calcs"`
+	} `json:"reduceOptions"`
+}
+
+type legacyGauge struct {
+	Show     bool     `json:"show"`
+	MinValue *float64 `json:"minValue"`
+	MaxValue *float64 `json:"maxValue"`
+}
+
+type legacyLegend struct {
+	Show      *bool `json:"show"`
+	RightSide bool  `json:"rightSide"`
+}
+
+type legacyYAxis struct {
+	Format   string `json:"format"`
+	Decimals *int   `json:"decimals"`
+}
+
+type seriesOverride map[string]json.RawMessage
+
+// rawString returns the string of a raw JSON string, if the raw JSON is not
+// a string it will return false.
+func rawString(raw json.RawMessage) (string, bool) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", false
+	}
+	return s, true
+}
+
+// rawStrings returns the strings of a raw JSON string or list of strings.
+func rawStrings(raw json.RawMessage) []string {
+	if s, ok := rawString(raw); ok {
+		return []string{s}
+	}
+
+	var ss []string
+	if err := json.Unmarshal(raw, &ss); err != nil {
+		return nil
+	}
+	return ss
+}
+
+// isNull returns true if the raw JSON is missing or null.
+func isNull(raw json.RawMessage) bool {
+	s := strings.TrimSpace(string(raw))
+	return s == "" || s == "null"
+}
@@ -0,0 +1,991 @@
+package grafana
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/configuration/meta"
+	v1 "github.com/slok/grafterm/internal/service/configuration/v1"
+	"github.com/slok/grafterm/internal/view/grid"
+)
+
+const (
+	// gridMaxWidth is the number of columns of the Grafana grid.
+	gridMaxWidth = 24
+	// defDatasourceID is the datasource ID used by the panels that
+	// use the Grafana default datasource.
+	defDatasourceID = "default"
+	// intervalVariable is the variable used to replace the Grafana
+	// interval builtin variables.
+	intervalVariable = "__interval"
+	// defIntervalSteps are the steps of the interval variables when
+	// Grafana doesn't have them.
+	defIntervalSteps = 50
+)
+
+// Result is the result of a Grafana dashboard conversion.
+type Result struct {
+	// Configuration is the converted grafterm configuration.
+	Configuration *v1.Configuration
+	// DatasourceIDs are the IDs of the datasources used by the dashboard.
+	// Grafana dashboards don't have the settings of the datasources, so
+	// these need to be defined by the user (e.g user datasources).
+	DatasourceIDs []string
+	// Unsupported are the Grafana features of the dashboard that could
+	// not be converted.
+	Unsupported []string
+}
+
+// Convert converts a Grafana dashboard in JSON format to a grafterm v1 configuration.
+// The Grafana panels and variables are mapped to grafterm widgets and variables, the
+// things that don't have an equivalent on grafterm are reported on the result.
+func Convert(r io.Reader) (*Result, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var d dashboard
+	err = json.Unmarshal(bs, &d)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling grafana dashboard: %s", err)
+	}
+
+	c := &converter{
+		variables:   map[string]struct{}{},
+		datasources: map[string]struct{}{},
+	}
+
+	return c.convert(d)
+}
+
+type converter struct {
+	// variables are the names of the dashboard variables.
+	variables   map[string]struct{}
+	datasources map[string]struct{}
+	unsupported []string
+	// usesInterval is true if the dashboard uses the Grafana interval
+	// builtin variables.
+	usesInterval bool
+}
+
+func (c *converter) report(format string, args ...interface{}) {
+	c.unsupported = append(c.unsupported, fmt.Sprintf(format, args...))
+}
+
+func (c *converter) convert(d dashboard) (*Result, error) {
+	if !isNull(d.Rows) {
+		c.report("legacy dashboard rows are not supported, only the dashboard panels will be converted")
+	}
+
+	// The variables need to be known before converting the templated strings.
+	for _, tv := range d.Templating.List {
+		c.variables[tv.Name] = struct{}{}
+	}
+
+	vars := map[string]*model.Variable{}
+	for _, tv := range d.Templating.List {
+		v, ok := c.convertVariable(tv)
+		if ok {
+			vars[v.Name] = v
+		}
+	}
+
+	widgets := []model.Widget{}
+	for _, p := range d.Panels {
+		widgets = append(widgets, c.convertPanel(p)...)
+	}
+	widgets = c.reflowWidgets(widgets)
+
+	// Check the widgets can be placed on the grafterm grid.
+	_, err := grid.NewFixedGrid(gridMaxWidth, append([]model.Widget{}, widgets...))
+	if err != nil {
+		return nil, fmt.Errorf("converted widgets can't be placed on the grid: %s", err)
+	}
+
+	if c.usesInterval {
+		vars[intervalVariable] = &model.Variable{
+			Name: intervalVariable,
+			VariableSource: model.VariableSource{
+				Interval: &model.IntervalVariableSource{Steps: defIntervalSteps},
+			},
+		}
+	}
+
+	dsIDs := []string{}
+	for id := range c.datasources {
+		dsIDs = append(dsIDs, id)
+	}
+	sort.Strings(dsIDs)
+
+	return &Result{
+		Configuration: &v1.Configuration{
+			Meta: meta.Meta{Version: v1.Version},
+			V1Dashboard: v1.Dashboard{
+				Title: d.Title,
+				Grid: model.Grid{
+					FixedWidgets: true,
+					MaxWidth:     gridMaxWidth,
+				},
+				Variables: vars,
+				Widgets:   widgets,
+			},
+		},
+		DatasourceIDs: dsIDs,
+		Unsupported:   c.unsupported,
+	}, nil
+}
+
+// reflowWidgets moves down the widgets that start inside the row of the
+// widgets placed above them (e.g a panel stacked next to a taller panel),
+// Grafana places the panels freely but the rows of a grafterm fixed grid
+// can't be split vertically. The widgets below the moved widget are moved
+// down too, so they don't overlap.
+func (c *converter) reflowWidgets(widgets []model.Widget) []model.Widget {
+	sort.SliceStable(widgets, func(i, j int) bool {
+		gpi, gpj := widgets[i].GridPos, widgets[j].GridPos
+		if gpi.Y != gpj.Y {
+			return gpi.Y < gpj.Y
+		}
+		return gpi.X < gpj.X
+	})
+
+	rowY, rowEnd := 0, 0
+	for i := range widgets {
+		gp := widgets[i].GridPos
+		if i == 0 || gp.Y != rowY {
+			if gp.Y < rowEnd {
+				shift := rowEnd - gp.Y
+				for j := i; j < len(widgets); j++ {
+					widgets[j].GridPos.Y += shift
+				}
+				c.report("%s panel starts inside the row of the panels placed on y %d, it has been moved down to y %d", widgets[i].Title, rowY, rowEnd)
+				gp = widgets[i].GridPos
+			}
+			rowY = gp.Y
+		}
+		if end := gp.Y + widgetHeight(gp); rowEnd < end {
+			rowEnd = end
+		}
+	}
+
+	return widgets
+}
+
+// widgetHeight returns the height of the widget on the grafterm grid.
+func widgetHeight(gp model.GridPos) int {
+	if gp.H <= 0 {
+		return 1
+	}
+	return gp.H
+}
+
+func (c *converter) convertVariable(tv templateVariable) (*model.Variable, bool) {
+	v := &model.Variable{Name: tv.Name}
+
+	switch tv.Type {
+	case "query":
+		query := ""
+		if q, ok := rawString(tv.Query); ok {
+			query = q
+		} else {
+			var qo struct {
+				Query string `json:"query"`
+			}
+			_ = json.Unmarshal(tv.Query, &qo)
+			query = qo.Query
+		}
+		if query == "" {
+			c.report("%s query variable doesn't have a query", tv.Name)
+			return nil, false
+		}
+
+		v.Query = &model.QueryVariableSource{
+			Query: model.Query{
+				Expr:         c.convertTemplate(query, fmt.Sprintf("%s variable query", tv.Name)),
+				DatasourceID: c.datasourceID(tv.Datasource),
+			},
+			Regex: trimRegex(tv.Regex),
+			Multi: tv.Multi || tv.IncludeAll,
+		}
+	case "constant":
+		value, _ := rawString(tv.Query)
+		if value == "" {
+			c.report("%s constant variable doesn't have a value", tv.Name)
+			return nil, false
+		}
+		v.Constant = &model.ConstantVariableSource{Value: value}
+	case "interval":
+		if tv.Auto {
+			steps := tv.AutoCount
+			if steps <= 0 {
+				steps = defIntervalSteps
+			}
+			v.Interval = &model.IntervalVariableSource{Steps: steps}
+			break
+		}
+
+		value := currentValue(tv)
+		if value == "" {
+			c.report("%s interval variable doesn't have a value", tv.Name)
+			return nil, false
+		}
+		c.report("%s interval variable without auto option is converted to a constant", tv.Name)
+		v.Constant = &model.ConstantVariableSource{Value: value}
+	case "custom", "textbox":
+		value := currentValue(tv)
+		if value == "" {
+			c.report("%s %s variable doesn't have a value", tv.Name, tv.Type)
+			return nil, false
+		}
+		c.report("%s %s variable is converted to a constant", tv.Name, tv.Type)
+		v.Constant = &model.ConstantVariableSource{Value: value}
+	case "datasource":
+		c.report("%s datasource variable is not supported, the widgets will use the %s datasource ID", tv.Name, tv.Name)
+		return nil, false
+	default:
+		c.report("%s %s variable is not supported", tv.Name, tv.Type)
+		return nil, false
+	}
+
+	return v, true
+}
+
+// currentValue returns the current value of the variable, if there
+// isn't a current value it will return the first option.
+func currentValue(tv templateVariable) string {
+	if vs := rawStrings(tv.Current.Value); len(vs) > 0 {
+		return vs[0]
+	}
+	for _, o := range tv.Options {
+		if vs := rawStrings(o.Value); len(vs) > 0 {
+			return vs[0]
+		}
+	}
+	if q, ok := rawString(tv.Query); ok {
+		return strings.TrimSpace(strings.Split(q, ",")[0])
+	}
+	return ""
+}
+
+func (c *converter) convertPanel(p panel) []model.Widget {
+	if p.Repeat != "" {
+		c.report("%s panel repeat is not supported, the panel will be shown once", p.Title)
+	}
+	if c.hasVariables(p.Title) {
+		c.report("%s panel title templating is not supported", p.Title)
+	}
+
+	w := model.Widget{
+		Title: p.Title,
+		GridPos: model.GridPos{
+			X: p.GridPos.X,
+			Y: p.GridPos.Y,
+			W: p.GridPos.W,
+			H: p.GridPos.H,
+		},
+	}
+
+	switch p.Type {
+	case "row":
+		// Rows don't have widgets, but collapsed rows have their panels inside.
+		widgets := []model.Widget{}
+		for _, rp := range p.Panels {
+			widgets = append(widgets, c.convertPanel(rp)...)
+		}
+		return widgets
+	case "graph", "timeseries":
+		g, ok := c.convertGraph(p)
+		if !ok {
+			return nil
+		}
+		w.Graph = g
+	case "stat":
+		s, ok := c.convertStat(p)
+		if !ok {
+			return nil
+		}
+		w.Singlestat = s
+	case "singlestat":
+		if p.Gauge != nil && p.Gauge.Show {
+			g, ok := c.convertLegacyGauge(p)
+			if !ok {
+				return nil
+			}
+			w.Gauge = g
+			break
+		}
+
+		s, ok := c.convertLegacySinglestat(p)
+		if !ok {
+			return nil
+		}
+		w.Singlestat = s
+	case "gauge":
+		g, ok := c.convertGauge(p)
+		if !ok {
+			return nil
+		}
+		w.Gauge = g
+	default:
+		c.report("%s panel of type %s is not supported", p.Title, p.Type)
+		return nil
+	}
+
+	return []model.Widget{w}
+}
+
+func (c *converter) convertGraph(p panel) (*model.GraphWidgetSource, bool) {
+	queries := c.queries(p)
+	if len(queries) == 0 {
+		c.report("%s panel doesn't have supported queries", p.Title)
+		return nil, false
+	}
+
+	g := &model.GraphWidgetSource{Queries: queries}
+	vis := &g.Visualization
+
+	// Legacy graph panel.
+	if p.Type == "graph" {
+		if p.Legend != nil {
+			vis.Legend.Disable = p.Legend.Show != nil && !*p.Legend.Show
+			vis.Legend.RightSide = p.Legend.RightSide
+		}
+		if len(p.YAxes) > 0 {
+			vis.YAxis.Unit = c.unit(p.YAxes[0].Format, p.Title)
+			if p.YAxes[0].Decimals != nil {
+				vis.YAxis.Decimals = *p.YAxes[0].Decimals
+			}
+		}
+		for _, so := range p.SeriesOverrides {
+			o, ok := c.convertLegacySeriesOverride(so, p.Title)
+			if ok {
+				vis.SeriesOverride = append(vis.SeriesOverride, o)
+			}
+		}
+		if npm, ok := c.nullPointMode(p.NullPointMode, p.Title); ok && npm != model.NullPointModeAsNull {
+			vis.SeriesOverride = append(vis.SeriesOverride, model.SeriesOverride{Regex: ".*", NullPointMode: npm})
+		}
+		if !isNull(p.Thresholds) && string(p.Thresholds) != "[]" {
+			c.report("%s panel thresholds are not supported on graphs", p.Title)
+		}
+
+		return g, true
+	}
+
+	// Timeseries panel.
+	if l := p.Options.Legend; l != nil {
+		vis.Legend.Disable = (l.ShowLegend != nil && !*l.ShowLegend) || l.DisplayMode == "hidden"
+		vis.Legend.RightSide = l.Placement == "right"
+	}
+	defs := p.FieldConfig.Defaults
+	vis.YAxis.Unit = c.unit(defs.Unit, p.Title)
+	if defs.Decimals != nil {
+		vis.YAxis.Decimals = *defs.Decimals
+	}
+	for _, fo := range p.FieldConfig.Overrides {
+		o, ok := c.convertFieldOverride(fo, p.Title)
+		if ok {
+			vis.SeriesOverride = append(vis.SeriesOverride, o)
+		}
+	}
+	if spanNulls(defs.Custom.SpanNulls) {
+		vis.SeriesOverride = append(vis.SeriesOverride, model.SeriesOverride{Regex: ".*", NullPointMode: model.NullPointModeConnected})
+	}
+
+	return g, true
+}
+
+func (c *converter) convertLegacySeriesOverride(so seriesOverride, title string) (model.SeriesOverride, bool) {
+	o := model.SeriesOverride{}
+	alias, _ := rawString(so["alias"])
+	if alias == "" {
+		c.report("%s panel series override without alias is not supported", title)
+		return o, false
+	}
+	o.Regex = aliasRegex(alias)
+
+	// Sort the properties so the reports are deterministic.
+	props := []string{}
+	for k := range so {
+		props = append(props, k)
+	}
+	sort.Strings(props)
+
+	for _, k := range props {
+		switch k {
+		case "alias":
+		case "color":
+			cs, _ := rawString(so[k])
+			color, ok := c.color(cs, title)
+			if ok {
+				o.Color = color
+			}
+		case "nullPointMode":
+			m, _ := rawString(so[k])
+			npm, ok := c.nullPointMode(m, title)
+			if ok {
+				o.NullPointMode = npm
+			}
+		default:
+			c.report("%s panel series override %s property is not supported", title, k)
+		}
+	}
+
+	if o.Color == "" && o.NullPointMode == "" {
+		return o, false
+	}
+
+	return o, true
+}
+
+func (c *converter) convertFieldOverride(fo fieldOverride, title string) (model.SeriesOverride, bool) {
+	o := model.SeriesOverride{}
+	opt, _ := rawString(fo.Matcher.Options)
+	switch fo.Matcher.ID {
+	case "byName":
+		o.Regex = "^" + regexp.QuoteMeta(opt) + "$"
+	case "byRegexp":
+		o.Regex = trimRegex(opt)
+	default:
+		c.report("%s panel override matcher %s is not supported", title, fo.Matcher.ID)
+		return o, false
+	}
+
+	for _, prop := range fo.Properties {
+		switch prop.ID {
+		case "color":
+			var cv struct {
+				Mode       string `json:"mode"`
+				FixedColor string `json:"fixedColor"`
+			}
+			_ = json.Unmarshal(prop.Value, &cv)
+			if cv.Mode != "fixed" {
+				c.report("%s panel override color mode %s is not supported", title, cv.Mode)
+				continue
+			}
+			color, ok := c.color(cv.FixedColor, title)
+			if ok {
+				o.Color = color
+			}
+		case "custom.spanNulls":
+			if spanNulls(prop.Value) {
+				o.NullPointMode = model.NullPointModeConnected
+			}
+		default:
+			c.report("%s panel override %s property is not supported", title, prop.ID)
+		}
+	}
+
+	if o.Color == "" && o.NullPointMode == "" {
+		return o, false
+	}
+
+	return o, true
+}
+
+func (c *converter) convertStat(p panel) (*model.SinglestatWidgetSource, bool) {
+	q, ok := c.query(p)
+	if !ok {
+		return nil, false
+	}
+
+	for _, calc := range p.Options.ReduceOptions.Calcs {
+		if calc != "lastNotNull" && calc != "last" {
+			c.report("%s panel %s calculation is not supported, the last value will be used", p.Title, calc)
+		}
+	}
+
+	defs := p.FieldConfig.Defaults
+	s := &model.SinglestatWidgetSource{
+		Query:      q,
+		Thresholds: c.thresholds(defs.Thresholds, nil, nil, p.Title),
+	}
+	s.Unit = c.unit(defs.Unit, p.Title)
+	if defs.Decimals != nil {
+		s.Decimals = *defs.Decimals
+	}
+
+	return s, true
+}
+
+func (c *converter) convertGauge(p panel) (*model.GaugeWidgetSource, bool) {
+	q, ok := c.query(p)
+	if !ok {
+		return nil, false
+	}
+
+	// Grafana gauges are from 0 to 100 by default.
+	defs := p.FieldConfig.Defaults
+	min, max := 0.0, 100.0
+	if defs.Min != nil {
+		min = *defs.Min
+	}
+	if defs.Max != nil {
+		max = *defs.Max
+	}
+
+	g := &model.GaugeWidgetSource{
+		Query:      q,
+		Min:        c.gaugeLimit(min, p.Title),
+		Max:        c.gaugeLimit(max, p.Title),
+		Thresholds: c.thresholds(defs.Thresholds, &min, &max, p.Title),
+	}
+	g.PercentValue = c.gaugePercent(defs.Unit, p.Title)
+
+	return g, true
+}
+
+func (c *converter) convertLegacySinglestat(p panel) (*model.SinglestatWidgetSource, bool) {
+	q, ok := c.query(p)
+	if !ok {
+		return nil, false
+	}
+	c.checkLegacySinglestat(p)
+
+	s := &model.SinglestatWidgetSource{
+		Query:      q,
+		Thresholds: c.legacyThresholds(p),
+	}
+	s.Unit = c.unit(p.Format, p.Title)
+	if p.Decimals != nil {
+		s.Decimals = *p.Decimals
+	}
+
+	return s, true
+}
+
+func (c *converter) convertLegacyGauge(p panel) (*model.GaugeWidgetSource, bool) {
+	q, ok := c.query(p)
+	if !ok {
+		return nil, false
+	}
+	c.checkLegacySinglestat(p)
+
+	min, max := 0.0, 100.0
+	if p.Gauge.MinValue != nil {
+		min = *p.Gauge.MinValue
+	}
+	if p.Gauge.MaxValue != nil {
+		max = *p.Gauge.MaxValue
+	}
+
+	g := &model.GaugeWidgetSource{
+		Query:      q,
+		Min:        c.gaugeLimit(min, p.Title),
+		Max:        c.gaugeLimit(max, p.Title),
+		Thresholds: c.legacyThresholds(p),
+	}
+	g.PercentValue = c.gaugePercent(p.Format, p.Title)
+
+	return g, true
+}
+
+func (c *converter) checkLegacySinglestat(p panel) {
+	if p.ValueName != "" && p.ValueName != "current" {
+		c.report("%s panel %s value is not supported, the last value will be used", p.Title, p.ValueName)
+	}
+	if p.Prefix != "" || p.Postfix != "" {
+		c.report("%s panel prefix and postfix are not supported", p.Title)
+	}
+}
+
+// gaugePercent returns if the gauge is a percent gauge based on the unit,
+// grafterm gauges don't have units.
+func (c *converter) gaugePercent(unit, title string) bool {
+	switch unit {
+	case "percent", "percentunit":
+		return true
+	case "", "none", "short":
+		return false
+	}
+
+	c.report("%s panel unit %s is not supported on gauges", title, unit)
+	return false
+}
+
+// gaugeLimit converts the gauge min and max to the integer limits
+// of the grafterm gauges.
+func (c *converter) gaugeLimit(v float64, title string) int {
+	if v != math.Trunc(v) {
+		c.report("%s panel gauge limit %v is not an integer, it will be rounded", title, v)
+	}
+	return int(math.Round(v))
+}
+
+// query returns the query of the single value panels.
+func (c *converter) query(p panel) (model.Query, bool) {
+	queries := c.queries(p)
+	if len(queries) == 0 {
+		c.report("%s panel doesn't have supported queries", p.Title)
+		return model.Query{}, false
+	}
+	if len(queries) > 1 {
+		c.report("%s panel has multiple queries, only the first one will be used", p.Title)
+	}
+
+	return queries[0], true
+}
+
+func (c *converter) queries(p panel) []model.Query {
+	queries := []model.Query{}
+	for _, t := range p.Targets {
+		if t.Hide {
+			continue
+		}
+
+		expr := ""
+		switch {
+		case t.Expr != "":
+			expr = t.Expr
+		case t.Target != "":
+			expr = t.Target
+		case t.RawQuery && t.Query != "":
+			expr = t.Query
+		default:
+			c.report("%s panel %s query is not supported, only Prometheus, Graphite and raw InfluxDB queries are supported", p.Title, t.RefID)
+			continue
+		}
+
+		ds := p.Datasource
+		if !isNull(t.Datasource) {
+			ds = t.Datasource
+		}
+
+		where := fmt.Sprintf("%s panel %s query", p.Title, t.RefID)
+		queries = append(queries, model.Query{
+			Expr:         c.convertTemplate(expr, where),
+			Legend:       c.convertLegend(t, where),
+			DatasourceID: c.datasourceID(ds),
+		})
+	}
+
+	return queries
+}
+
+var (
+	// grafanaLegendRegexp matches the Grafana labels on the legends (e.g `{{ instance }}`).
+	grafanaLegendRegexp = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+	// influxDBAliasRegexp matches the InfluxDB tags on the aliases (e.g `$tag_host`).
+	influxDBAliasRegexp = regexp.MustCompile(`\$tag_([A-Za-z0-9_]+)`)
+	// grafanaVariableRegexp matches the Grafana variables (e.g `$var`, `${var}`, `${var:csv}` or `[[var]]`).
+	grafanaVariableRegexp = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)(?::([^}]*))?\}|\[\[([A-Za-z0-9_]+)(?::([^\]]*))?\]\]|\$([A-Za-z0-9_]+)`)
+)
+
+func (c *converter) convertLegend(t target, where string) string {
+	switch {
+	case t.LegendFormat == "__auto":
+		return ""
+	case t.LegendFormat != "":
+		l := grafanaLegendRegexp.ReplaceAllString(t.LegendFormat, "{{ .$1 }}")
+		return c.convertTemplate(l, where+" legend")
+	case t.Alias != "":
+		l := influxDBAliasRegexp.ReplaceAllString(t.Alias, "{{ .$1 }}")
+		return c.convertTemplate(l, where+" alias")
+	}
+
+	return ""
+}
+
+// convertTemplate converts the Grafana variables to grafterm template variables.
+func (c *converter) convertTemplate(s, where string) string {
+	return grafanaVariableRegexp.ReplaceAllStringFunc(s, func(m string) string {
+		sm := grafanaVariableRegexp.FindStringSubmatch(m)
+		name, format := sm[1]+sm[3]+sm[5], sm[2]+sm[4]
+
+		switch {
+		case name == "__interval" || name == "__rate_interval":
+			c.usesInterval = true
+			return "{{ ." + intervalVariable + " }}"
+		case strings.HasPrefix(name, "__"):
+			c.report("%s uses %s builtin variable that is not supported", where, name)
+			return m
+		}
+
+		if _, ok := c.variables[name]; !ok {
+			return m
+		}
+		if format != "" {
+			c.report("%s uses %s variable with %s format that is not supported", where, name, format)
+		}
+
+		return "{{ ." + name + " }}"
+	})
+}
+
+// hasVariables returns true if the string has dashboard variables.
+func (c *converter) hasVariables(s string) bool {
+	for _, sm := range grafanaVariableRegexp.FindAllStringSubmatch(s, -1) {
+		if _, ok := c.variables[sm[1]+sm[3]+sm[5]]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// datasourceID returns the grafterm datasource ID of a Grafana datasource reference,
+// the references can be the name of the datasource or an object with the uid.
+func (c *converter) datasourceID(raw json.RawMessage) string {
+	id := ""
+	if s, ok := rawString(raw); ok {
+		id = s
+	} else if !isNull(raw) {
+		var ref struct {
+			UID  string `json:"uid"`
+			Type string `json:"type"`
+		}
+		_ = json.Unmarshal(raw, &ref)
+		id = ref.UID
+		if id == "" {
+			id = ref.Type
+		}
+	}
+
+	// Datasource variables and inputs (e.g `${DS_PROMETHEUS}`).
+	id = strings.TrimPrefix(id, "$")
+	id = strings.TrimPrefix(id, "{")
+	id = strings.TrimSuffix(id, "}")
+	if id == "" || id == "default" || strings.HasPrefix(id, "-- ") {
+		id = defDatasourceID
+	}
+
+	c.datasources[id] = struct{}{}
+	return id
+}
+
+var units = map[string]string{
+	"":            "",
+	"short":       "short",
+	"none":        "none",
+	"percent":     "percent",
+	"percentunit": "ratio",
+	"s":           "seconds",
+	"ms":          "ms",
+	"reqps":       "reqps",
+	"bytes":       "bytes",
+	"decbytes":    "bytes",
+}
+
+func (c *converter) unit(u, title string) string {
+	gu, ok := units[u]
+	if !ok {
+		c.report("%s panel unit %s is not supported", title, u)
+		return ""
+	}
+	return gu
+}
+
+func (c *converter) nullPointMode(m, title string) (model.NullPointMode, bool) {
+	switch m {
+	case "", "null":
+		return model.NullPointModeAsNull, true
+	case "connected":
+		return model.NullPointModeConnected, true
+	case "null as zero":
+		return model.NullPointModeAsZero, true
+	}
+
+	c.report("%s panel null point mode %s is not supported", title, m)
+	return "", false
+}
+
+// spanNulls returns true if the Grafana span nulls setting is enabled, the
+// setting can be a boolean or the maximum time to span nulls.
+func spanNulls(raw json.RawMessage) bool {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b
+	}
+	return !isNull(raw)
+}
+
+// thresholds converts the Grafana thresholds, the percentage thresholds
+// can only be converted when min and max are known.
+func (c *converter) thresholds(ts *thresholds, min, max *float64, title string) []model.Threshold {
+	if ts == nil {
+		return nil
+	}
+
+	percentage := ts.Mode == "percentage"
+	if percentage && (min == nil || max == nil) {
+		c.report("%s panel percentage thresholds are not supported", title)
+		return nil
+	}
+
+	steps := []thresholdStep{}
+	for _, s := range ts.Steps {
+		if s.Value != nil && percentage {
+			v := *min + (*max-*min)*(*s.Value)/100
+			s.Value = &v
+		}
+		steps = append(steps, s)
+	}
+
+	return c.newThresholds(steps, title)
+}
+
+// legacyThresholds converts the legacy singlestat thresholds, these are a comma
+// separated list of values and a list of colors with one color more than values.
+func (c *converter) legacyThresholds(p panel) []model.Threshold {
+	if len(p.Colors) == 0 {
+		return nil
+	}
+
+	steps := []thresholdStep{{Color: p.Colors[0]}}
+	ts, _ := rawString(p.Thresholds)
+	for i, sv := range strings.Split(ts, ",") {
+		sv = strings.TrimSpace(sv)
+		if sv == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(sv, 64)
+		if err != nil || i+1 >= len(p.Colors) {
+			c.report("%s panel threshold %s is not valid", p.Title, sv)
+			continue
+		}
+		steps = append(steps, thresholdStep{Color: p.Colors[i+1], Value: &v})
+	}
+
+	return c.newThresholds(steps, p.Title)
+}
+
+// newThresholds creates the grafterm thresholds from the Grafana steps. The first
+// grafterm threshold is the base threshold, Grafana base threshold doesn't have a
+// value so we need to set a start value that is not used by other thresholds.
+func (c *converter) newThresholds(steps []thresholdStep, title string) []model.Threshold {
+	var base *model.Threshold
+	values := map[float64]int{}
+	ths := []model.Threshold{}
+	for _, s := range steps {
+		color, ok := c.color(s.Color, title)
+		if !ok {
+			continue
+		}
+		if s.Value == nil {
+			base = &model.Threshold{Color: color}
+			continue
+		}
+
+		// Like Grafana, the last step with the same value wins.
+		t := model.Threshold{Color: color, StartValue: *s.Value}
+		if i, ok := values[t.StartValue]; ok {
+			ths[i] = t
+			continue
+		}
+		values[t.StartValue] = len(ths)
+		ths = append(ths, t)
+	}
+
+	sort.Slice(ths, func(i, j int) bool {
+		return ths[i].StartValue < ths[j].StartValue
+	})
+
+	if base != nil {
+		if len(ths) > 0 && ths[0].StartValue <= base.StartValue {
+			base.StartValue = ths[0].StartValue - 1
+		}
+		ths = append([]model.Threshold{*base}, ths...)
+	}
+
+	return ths
+}
+
+// namedColors are the Grafana palette colors.
+var namedColors = map[string]string{
+	"green":              "#73BF69",
+	"dark-green":         "#37872D",
+	"semi-dark-green":    "#56A64B",
+	"light-green":        "#96D98D",
+	"super-light-green":  "#C8F2C2",
+	"red":                "#F2495C",
+	"dark-red":           "#C4162A",
+	"semi-dark-red":      "#E02F44",
+	"light-red":          "#FF7383",
+	"super-light-red":    "#FFA6B0",
+	"yellow":             "#FADE2A",
+	"dark-yellow":        "#E0B400",
+	"semi-dark-yellow":   "#F2CC0C",
+	"light-yellow":       "#FFEE52",
+	"super-light-yellow": "#FFF899",
+	"orange":             "#FF9830",
+	"dark-orange":        "#FA6400",
+	"semi-dark-orange":   "#FF780A",
+	"light-orange":       "#FFB357",
+	"super-light-orange": "#FFCB7D",
+	"blue":               "#5794F2",
+	"dark-blue":          "#1F60C4",
+	"semi-dark-blue":     "#3274D9",
+	"light-blue":         "#8AB8FF",
+	"super-light-blue":   "#C0D8FF",
+	"purple":             "#B877D9",
+	"dark-purple":        "#8F3BB8",
+	"semi-dark-purple":   "#A352CC",
+	"light-purple":       "#CA95E5",
+	"super-light-purple": "#DEB6F2",
+}
+
+var (
+	hexColorRegexp = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+	rgbColorRegexp = regexp.MustCompile(`^rgba?\(\s*(\d+)\s*,\s*(\d+)\s*,\s*(\d+)\s*(?:,\s*[0-9.]+\s*)?\)$`)
+)
+
+// color converts the Grafana colors to hex colors.
+func (c *converter) color(color, title string) (string, bool) {
+	color = strings.TrimSpace(color)
+
+	if hexColorRegexp.MatchString(color) {
+		return color, true
+	}
+
+	if hc, ok := namedColors[color]; ok {
+		return hc, true
+	}
+
+	if sm := rgbColorRegexp.FindStringSubmatch(color); sm != nil {
+		r, _ := strconv.Atoi(sm[1])
+		g, _ := strconv.Atoi(sm[2])
+		b, _ := strconv.Atoi(sm[3])
+		if r <= 255 && g <= 255 && b <= 255 {
+			return fmt.Sprintf("#%02x%02x%02x", r, g, b), true
+		}
+	}
+
+	c.report("%s panel color %s is not supported", title, color)
+	return "", false
+}
+
+// aliasRegex returns the regex of a legacy series override alias, the aliases
+// can be a regex (e.g `/p9.*/`) or the name of the series.
+func aliasRegex(alias string) string {
+	if len(alias) > 1 && strings.HasPrefix(alias, "/") && strings.HasSuffix(alias, "/") {
+		return trimRegex(alias)
+	}
+	return "^" + regexp.QuoteMeta(alias) + "$"
+}
+
+// trimRegex removes the Grafana regex slashes and flags (e.g `/.*-(.*)/i`).
+func trimRegex(re string) string {
+	if !strings.HasPrefix(re, "/") {
+		return re
+	}
+
+	end := strings.LastIndex(re, "/")
+	if end <= 0 {
+		return re
+	}
+
+	flags := re[end+1:]
+	re = re[1:end]
+	if strings.Contains(flags, "i") {
+		re = "(?i)" + re
+	}
+	return re
+}
@@ -0,0 +1,396 @@
+package grafana_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/configuration/grafana"
+	"github.com/slok/grafterm/internal/service/configuration/meta"
+	v1 "github.com/slok/grafterm/internal/service/configuration/v1"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		name      string
+		dashboard string
+		expResult *grafana.Result
+		expErr    bool
+	}{
+		{
+			name:      "Invalid JSON should fail.",
+			dashboard: `{"title": "test",}`,
+			expErr:    true,
+		},
+		{
+			name:      "Empty dashboard should return an empty configuration.",
+			dashboard: `{"title": "test"}`,
+			expResult: &grafana.Result{
+				Configuration: &v1.Configuration{
+					Meta: meta.Meta{Version: "v1"},
+					V1Dashboard: v1.Dashboard{
+						Title:     "test",
+						Grid:      model.Grid{FixedWidgets: true, MaxWidth: 24},
+						Variables: map[string]*model.Variable{},
+						Widgets:   []model.Widget{},
+					},
+				},
+				DatasourceIDs: []string{},
+			},
+		},
+		{
+			name: "Variables should be converted and the unsupported reported.",
+			dashboard: `{
+  "title": "test",
+  "templating": {
+    "list": [
+      {"name": "ds", "type": "datasource", "query": "prometheus"},
+      {"name": "env", "type": "constant", "query": "prod"},
+      {"name": "interval", "type": "interval", "auto": true, "auto_count": 30, "query": "1m,5m"},
+      {"name": "step", "type": "interval", "query": "1m,5m", "current": {"value": "5m"}},
+      {"name": "color", "type": "custom", "query": "red,blue", "options": [{"value": "red"}, {"value": "blue"}]},
+      {"name": "job", "type": "query", "datasource": "$ds", "query": {"query": "label_values(up{env=\"$env\"}, job)"}, "regex": "/api-(.*)/", "includeAll": true},
+      {"name": "filters", "type": "adhoc"}
+    ]
+  }
+}`,
+			expResult: &grafana.Result{
+				Configuration: &v1.Configuration{
+					Meta: meta.Meta{Version: "v1"},
+					V1Dashboard: v1.Dashboard{
+						Title: "test",
+						Grid:  model.Grid{FixedWidgets: true, MaxWidth: 24},
+						Variables: map[string]*model.Variable{
+							"env": &model.Variable{
+								Name:           "env",
+								VariableSource: model.VariableSource{Constant: &model.ConstantVariableSource{Value: "prod"}},
+							},
+							"interval": &model.Variable{
+								Name:           "interval",
+								VariableSource: model.VariableSource{Interval: &model.IntervalVariableSource{Steps: 30}},
+							},
+							"step": &model.Variable{
+								Name:           "step",
+								VariableSource: model.VariableSource{Constant: &model.ConstantVariableSource{Value: "5m"}},
+							},
+							"color": &model.Variable{
+								Name:           "color",
+								VariableSource: model.VariableSource{Constant: &model.ConstantVariableSource{Value: "red"}},
+							},
+							"job": &model.Variable{
+								Name: "job",
+								VariableSource: model.VariableSource{Query: &model.QueryVariableSource{
+									Query: model.Query{
+										Expr:         `label_values(up{env="{{ .env }}"}, job)`,
+										DatasourceID: "ds",
+									},
+									Regex: "api-(.*)",
+									Multi: true,
+								}},
+							},
+						},
+						Widgets: []model.Widget{},
+					},
+				},
+				DatasourceIDs: []string{"ds"},
+				Unsupported: []string{
+					"ds datasource variable is not supported, the widgets will use the ds datasource ID",
+					"step interval variable without auto option is converted to a constant",
+					"color custom variable is converted to a constant",
+					"filters adhoc variable is not supported",
+				},
+			},
+		},
+		{
+			name: "Panels should be converted to widgets and the unsupported reported.",
+			dashboard: `{
+  "title": "test",
+  "templating": {"list": [{"name": "env", "type": "constant", "query": "prod"}]},
+  "panels": [
+    {
+      "type": "timeseries",
+      "title": "Requests",
+      "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8},
+      "datasource": {"type": "prometheus", "uid": "${DS_PROMETHEUS}"},
+      "targets": [
+        {"refId": "A", "expr": "sum(rate(http_requests_total{env=\"$env\"}[$__rate_interval])) by (code)", "legendFormat": "{{code}}"},
+        {"refId": "B", "expr": "sum(up)", "hide": true}
+      ],
+      "fieldConfig": {
+        "defaults": {"unit": "reqps", "decimals": 1, "custom": {"spanNulls": true}},
+        "overrides": [
+          {"matcher": {"id": "byName", "options": "5xx"}, "properties": [{"id": "color", "value": {"mode": "fixed", "fixedColor": "red"}}]},
+          {"matcher": {"id": "byRegexp", "options": "/2.*/"}, "properties": [{"id": "custom.lineWidth", "value": 2}]}
+        ]
+      },
+      "options": {"legend": {"showLegend": true, "placement": "right"}}
+    },
+    {
+      "type": "graph",
+      "title": "Latency",
+      "gridPos": {"x": 12, "y": 0, "w": 12, "h": 8},
+      "datasource": "graphite",
+      "targets": [{"refId": "A", "target": "latency.p99"}],
+      "legend": {"show": false},
+      "yaxes": [{"format": "s", "decimals": 2}, {"format": "short"}],
+      "seriesOverrides": [{"alias": "/p99/", "color": "rgba(255, 0, 0, 0.5)", "nullPointMode": "null as zero", "lines": false}],
+      "nullPointMode": "connected"
+    },
+    {
+      "type": "stat",
+      "title": "Up",
+      "gridPos": {"x": 0, "y": 8, "w": 6, "h": 4},
+      "targets": [{"refId": "A", "expr": "sum(up)"}],
+      "fieldConfig": {
+        "defaults": {
+          "unit": "none",
+          "thresholds": {"mode": "absolute", "steps": [{"color": "red", "value": null}, {"color": "#37872D", "value": 0}, {"color": "green", "value": 3}]}
+        }
+      },
+      "options": {"reduceOptions": {"calcs": ["mean"]}}
+    },
+    {
+      "type": "gauge",
+      "title": "CPU",
+      "gridPos": {"x": 6, "y": 8, "w": 6, "h": 4},
+      "targets": [{"refId": "A", "expr": "avg(cpu)"}],
+      "fieldConfig": {
+        "defaults": {
+          "unit": "percent",
+          "min": 0,
+          "max": 200,
+          "thresholds": {"mode": "percentage", "steps": [{"color": "green", "value": null}, {"color": "orange", "value": 50}]}
+        }
+      }
+    },
+    {
+      "type": "singlestat",
+      "title": "Errors",
+      "gridPos": {"x": 12, "y": 8, "w": 6, "h": 4},
+      "datasource": "-- Mixed --",
+      "targets": [{"refId": "A", "expr": "sum(errors)", "datasource": "thanos"}],
+      "format": "percentunit",
+      "decimals": 3,
+      "thresholds": "0.1,0.5",
+      "colors": ["#299c46", "rgb(237, 129, 40)", "#d44a3a"],
+      "postfix": " errors"
+    },
+    {
+      "type": "row",
+      "title": "Details",
+      "collapsed": true,
+      "gridPos": {"x": 0, "y": 12, "w": 24, "h": 1},
+      "panels": [
+        {
+          "type": "singlestat",
+          "title": "Memory",
+          "gridPos": {"x": 0, "y": 13, "w": 6, "h": 4},
+          "targets": [{"refId": "A", "expr": "sum(memory)"}],
+          "format": "bytes",
+          "gauge": {"show": true, "minValue": 0, "maxValue": 1024.5}
+        },
+        {"type": "table", "title": "Pods", "gridPos": {"x": 6, "y": 13, "w": 6, "h": 4}}
+      ]
+    }
+  ]
+}`,
+			expResult: &grafana.Result{
+				Configuration: &v1.Configuration{
+					Meta: meta.Meta{Version: "v1"},
+					V1Dashboard: v1.Dashboard{
+						Title: "test",
+						Grid:  model.Grid{FixedWidgets: true, MaxWidth: 24},
+						Variables: map[string]*model.Variable{
+							"env": &model.Variable{
+								Name:           "env",
+								VariableSource: model.VariableSource{Constant: &model.ConstantVariableSource{Value: "prod"}},
+							},
+							"__interval": &model.Variable{
+								Name:           "__interval",
+								VariableSource: model.VariableSource{Interval: &model.IntervalVariableSource{Steps: 50}},
+							},
+						},
+						Widgets: []model.Widget{
+							{
+								Title:   "Requests",
+								GridPos: model.GridPos{X: 0, Y: 0, W: 12, H: 8},
+								WidgetSource: model.WidgetSource{Graph: &model.GraphWidgetSource{
+									Queries: []model.Query{
+										{
+											Expr:         `sum(rate(http_requests_total{env="{{ .env }}"}[{{ .__interval }}])) by (code)`,
+											Legend:       "{{ .code }}",
+											DatasourceID: "DS_PROMETHEUS",
+										},
+									},
+									Visualization: model.GraphVisualization{
+										Legend: model.Legend{RightSide: true},
+										YAxis:  model.YAxis{ValueRepresentation: model.ValueRepresentation{Unit: "reqps", Decimals: 1}},
+										SeriesOverride: []model.SeriesOverride{
+											{Regex: "^5xx$", Color: "#F2495C"},
+											{Regex: ".*", NullPointMode: model.NullPointModeConnected},
+										},
+									},
+								}},
+							},
+							{
+								Title:   "Latency",
+								GridPos: model.GridPos{X: 12, Y: 0, W: 12, H: 8},
+								WidgetSource: model.WidgetSource{Graph: &model.GraphWidgetSource{
+									Queries: []model.Query{
+										{Expr: "latency.p99", DatasourceID: "graphite"},
+									},
+									Visualization: model.GraphVisualization{
+										Legend: model.Legend{Disable: true},
+										YAxis:  model.YAxis{ValueRepresentation: model.ValueRepresentation{Unit: "seconds", Decimals: 2}},
+										SeriesOverride: []model.SeriesOverride{
+											{Regex: "p99", Color: "#ff0000", NullPointMode: model.NullPointModeAsZero},
+											{Regex: ".*", NullPointMode: model.NullPointModeConnected},
+										},
+									},
+								}},
+							},
+							{
+								Title:   "Up",
+								GridPos: model.GridPos{X: 0, Y: 8, W: 6, H: 4},
+								WidgetSource: model.WidgetSource{Singlestat: &model.SinglestatWidgetSource{
+									ValueRepresentation: model.ValueRepresentation{Unit: "none"},
+									Query:               model.Query{Expr: "sum(up)", DatasourceID: "default"},
+									Thresholds: []model.Threshold{
+										{Color: "#F2495C", StartValue: -1},
+										{Color: "#37872D", StartValue: 0},
+										{Color: "#73BF69", StartValue: 3},
+									},
+								}},
+							},
+							{
+								Title:   "CPU",
+								GridPos: model.GridPos{X: 6, Y: 8, W: 6, H: 4},
+								WidgetSource: model.WidgetSource{Gauge: &model.GaugeWidgetSource{
+									Query:        model.Query{Expr: "avg(cpu)", DatasourceID: "default"},
+									PercentValue: true,
+									Min:          0,
+									Max:          200,
+									Thresholds: []model.Threshold{
+										{Color: "#73BF69", StartValue: 0},
+										{Color: "#FF9830", StartValue: 100},
+									},
+								}},
+							},
+							{
+								Title:   "Errors",
+								GridPos: model.GridPos{X: 12, Y: 8, W: 6, H: 4},
+								WidgetSource: model.WidgetSource{Singlestat: &model.SinglestatWidgetSource{
+									ValueRepresentation: model.ValueRepresentation{Unit: "ratio", Decimals: 3},
+									Query:               model.Query{Expr: "sum(errors)", DatasourceID: "thanos"},
+									Thresholds: []model.Threshold{
+										{Color: "#299c46", StartValue: 0},
+										{Color: "#ed8128", StartValue: 0.1},
+										{Color: "#d44a3a", StartValue: 0.5},
+									},
+								}},
+							},
+							{
+								Title:   "Memory",
+								GridPos: model.GridPos{X: 0, Y: 13, W: 6, H: 4},
+								WidgetSource: model.WidgetSource{Gauge: &model.GaugeWidgetSource{
+									Query: model.Query{Expr: "sum(memory)", DatasourceID: "default"},
+									Min:   0,
+									Max:   1025,
+								}},
+							},
+						},
+					},
+				},
+				DatasourceIDs: []string{"DS_PROMETHEUS", "default", "graphite", "thanos"},
+				Unsupported: []string{
+					"Requests panel override custom.lineWidth property is not supported",
+					"Latency panel series override lines property is not supported",
+					"Up panel mean calculation is not supported, the last value will be used",
+					"Errors panel prefix and postfix are not supported",
+					"Memory panel gauge limit 1024.5 is not an integer, it will be rounded",
+					"Memory panel unit bytes is not supported on gauges",
+					"Pods panel of type table is not supported",
+				},
+			},
+		},
+		{
+			name: "Panels starting inside the row of a taller panel should be moved down.",
+			dashboard: `{
+  "title": "test",
+  "panels": [
+    {"type": "stat", "title": "A", "gridPos": {"x": 0, "y": 0, "w": 12, "h": 8}, "targets": [{"expr": "a"}]},
+    {"type": "stat", "title": "B", "gridPos": {"x": 12, "y": 0, "w": 12, "h": 4}, "targets": [{"expr": "b"}]},
+    {"type": "stat", "title": "C", "gridPos": {"x": 12, "y": 4, "w": 12, "h": 4}, "targets": [{"expr": "c"}]},
+    {"type": "stat", "title": "D", "gridPos": {"x": 0, "y": 8, "w": 24, "h": 4}, "targets": [{"expr": "d"}]}
+  ]
+}`,
+			expResult: &grafana.Result{
+				Configuration: &v1.Configuration{
+					Meta: meta.Meta{Version: "v1"},
+					V1Dashboard: v1.Dashboard{
+						Title:     "test",
+						Grid:      model.Grid{FixedWidgets: true, MaxWidth: 24},
+						Variables: map[string]*model.Variable{},
+						Widgets: []model.Widget{
+							{
+								Title:   "A",
+								GridPos: model.GridPos{X: 0, Y: 0, W: 12, H: 8},
+								WidgetSource: model.WidgetSource{Singlestat: &model.SinglestatWidgetSource{
+									Query: model.Query{Expr: "a", DatasourceID: "default"},
+								}},
+							},
+							{
+								Title:   "B",
+								GridPos: model.GridPos{X: 12, Y: 0, W: 12, H: 4},
+								WidgetSource: model.WidgetSource{Singlestat: &model.SinglestatWidgetSource{
+									Query: model.Query{Expr: "b", DatasourceID: "default"},
+								}},
+							},
+							{
+								Title:   "C",
+								GridPos: model.GridPos{X: 12, Y: 8, W: 12, H: 4},
+								WidgetSource: model.WidgetSource{Singlestat: &model.SinglestatWidgetSource{
+									Query: model.Query{Expr: "c", DatasourceID: "default"},
+								}},
+							},
+							{
+								Title:   "D",
+								GridPos: model.GridPos{X: 0, Y: 12, W: 24, H: 4},
+								WidgetSource: model.WidgetSource{Singlestat: &model.SinglestatWidgetSource{
+									Query: model.Query{Expr: "d", DatasourceID: "default"},
+								}},
+							},
+						},
+					},
+				},
+				DatasourceIDs: []string{"default"},
+				Unsupported: []string{
+					"C panel starts inside the row of the panels placed on y 0, it has been moved down to y 8",
+				},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			gotRes, err := grafana.Convert(strings.NewReader(test.dashboard))
+
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(test.expResult, gotRes)
+
+			// The converted dashboards should be valid.
+			_, err = gotRes.Configuration.Dashboard()
+			assert.NoError(err)
+		})
+	}
+}
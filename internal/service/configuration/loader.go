@@ -1,10 +1,14 @@
 package configuration
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
+	"path/filepath"
+
+	yaml "gopkg.in/yaml.v3"
 
 	"github.com/slok/grafterm/internal/service/configuration/meta"
 	v1 "github.com/slok/grafterm/internal/service/configuration/v1"
@@ -22,6 +26,76 @@ func (j JSONLoader) Load(r io.Reader) (Configuration, error) {
 		return nil, err
 	}
 
+	return loadJSON(bs)
+}
+
+// YAMLLoader will load configuration in YAML format.
+// It autodetects the version configuration so the user
+// doesn't know what version of configuration is loading.
+type YAMLLoader struct{}
+
+// Load satisfies configuration.Loader interface.
+func (y YAMLLoader) Load(r io.Reader) (Configuration, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// The configuration types only know about JSON, so convert
+	// the YAML to JSON and load it as JSON.
+	bs, err = yamlToJSON(bs)
+	if err != nil {
+		return nil, err
+	}
+
+	return loadJSON(bs)
+}
+
+// FileLoader will load configuration files in any of the supported formats.
+// The format is selected using the extension of the file name, if the
+// extension is unknown it will be detected using the content.
+type FileLoader struct {
+	// Name is the name of the file.
+	Name string
+}
+
+// Load satisfies configuration.Loader interface.
+func (f FileLoader) Load(r io.Reader) (Configuration, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var loader Loader
+	switch filepath.Ext(f.Name) {
+	case ".json":
+		loader = JSONLoader{}
+	case ".yaml", ".yml":
+		loader = YAMLLoader{}
+	default:
+		// JSON configuration is always an object, YAML can be
+		// anything else.
+		if bytes.HasPrefix(bytes.TrimSpace(bs), []byte("{")) {
+			loader = JSONLoader{}
+		} else {
+			loader = YAMLLoader{}
+		}
+	}
+
+	return loader.Load(bytes.NewReader(bs))
+}
+
+// IsConfigurationFile returns true if the file name has the extension
+// of one of the supported formats.
+func IsConfigurationFile(name string) bool {
+	switch filepath.Ext(name) {
+	case ".json", ".yaml", ".yml":
+		return true
+	}
+	return false
+}
+
+func loadJSON(bs []byte) (Configuration, error) {
 	cfg, err := newConfig(bs)
 	if err != nil {
 		return nil, err
@@ -56,3 +130,64 @@ func newConfig(cfgData []byte) (Configuration, error) {
 
 	return cfg, nil
 }
+
+// yamlToJSON converts YAML data to JSON data.
+func yamlToJSON(bs []byte) ([]byte, error) {
+	var data interface{}
+	err := yaml.Unmarshal(bs, &data)
+	if err != nil {
+		return nil, fmt.Errorf("error unmarshalling yaml: %s", err)
+	}
+
+	data, err = yamlToJSONValue(data)
+	if err != nil {
+		return nil, err
+	}
+
+	// Empty documents are empty objects.
+	if data == nil {
+		data = map[string]interface{}{}
+	}
+
+	return json.Marshal(data)
+}
+
+// yamlToJSONValue converts the YAML decoded maps that have non string keys
+// to maps with string keys, so they can be encoded to JSON. The YAML is
+// decoded using YAML 1.2, so the unquoted keys like `y` are strings and not
+// booleans like on YAML 1.1.
+func yamlToJSONValue(v interface{}) (interface{}, error) {
+	switch tv := v.(type) {
+	case map[string]interface{}:
+		for k, v := range tv {
+			jv, err := yamlToJSONValue(v)
+			if err != nil {
+				return nil, err
+			}
+			tv[k] = jv
+		}
+		return tv, nil
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(tv))
+		for k, v := range tv {
+			jv, err := yamlToJSONValue(v)
+			if err != nil {
+				return nil, err
+			}
+			m[fmt.Sprintf("%v", k)] = jv
+		}
+		return m, nil
+	case []interface{}:
+		s := make([]interface{}, 0, len(tv))
+		for _, v := range tv {
+			jv, err := yamlToJSONValue(v)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, jv)
+		}
+		return s, nil
+	}
+
+	return v, nil
+}
@@ -6,8 +6,10 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/slok/grafterm/internal/service/configuration"
+	v1 "github.com/slok/grafterm/internal/service/configuration/v1"
 )
 
 func TestLoadJSON(t *testing.T) {
@@ -65,3 +67,116 @@ func TestLoadJSON(t *testing.T) {
 		})
 	}
 }
+
+func TestLoadYAML(t *testing.T) {
+	tests := []struct {
+		name       string
+		config     func() io.Reader
+		loader     func() configuration.Loader
+		expVersion string
+		expErr     bool
+	}{
+		{
+			name: "Invalid YAML should return an error.",
+			loader: func() configuration.Loader {
+				return &configuration.YAMLLoader{}
+			},
+			config: func() io.Reader {
+				return strings.NewReader("version: [v1")
+			},
+			expErr: true,
+		},
+		{
+			name: "Unknown YAML version should error.",
+			loader: func() configuration.Loader {
+				return &configuration.YAMLLoader{}
+			},
+			config: func() io.Reader {
+				return strings.NewReader("version: v0.987654321")
+			},
+			expErr: true,
+		},
+		{
+			name: "Valid YAML V1 load.",
+			loader: func() configuration.Loader {
+				return &configuration.YAMLLoader{}
+			},
+			config: func() io.Reader {
+				return strings.NewReader("# Comments are allowed.\nversion: v1\n")
+			},
+			expVersion: "v1",
+		},
+		{
+			name: "File loader should use the YAML loader based on the extension.",
+			loader: func() configuration.Loader {
+				return &configuration.FileLoader{Name: "dashboard.yml"}
+			},
+			config: func() io.Reader {
+				return strings.NewReader("version: v1")
+			},
+			expVersion: "v1",
+		},
+		{
+			name: "File loader should use the JSON loader based on the extension.",
+			loader: func() configuration.Loader {
+				return &configuration.FileLoader{Name: "dashboard.json"}
+			},
+			config: func() io.Reader {
+				return strings.NewReader("version: v1")
+			},
+			expErr: true,
+		},
+		{
+			name: "File loader without known extension should detect YAML content.",
+			loader: func() configuration.Loader {
+				return &configuration.FileLoader{Name: "dashboard"}
+			},
+			config: func() io.Reader {
+				return strings.NewReader("version: v1")
+			},
+			expVersion: "v1",
+		},
+		{
+			name: "File loader without known extension should detect JSON content.",
+			loader: func() configuration.Loader {
+				return &configuration.FileLoader{Name: "dashboard"}
+			},
+			config: func() io.Reader {
+				return strings.NewReader(`  {"version": "v1"}`)
+			},
+			expVersion: "v1",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			loader := test.loader()
+			gotcfg, err := loader.Load(test.config())
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expVersion, gotcfg.Version())
+			}
+		})
+	}
+}
+
+func TestLoadYAMLUnquotedKeys(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	// On YAML 1.1 an unquoted `y` key is a boolean.
+	cfg := "version: v1\ndashboard:\n  widgets:\n  - gridPos:\n      x: 2\n      y: 1\n"
+
+	gotcfg, err := configuration.YAMLLoader{}.Load(strings.NewReader(cfg))
+	require.NoError(err)
+
+	v1cfg, ok := gotcfg.(*v1.Configuration)
+	require.True(ok)
+	require.Len(v1cfg.V1Dashboard.Widgets, 1)
+	assert.Equal(2, v1cfg.V1Dashboard.Widgets[0].GridPos.X)
+	assert.Equal(1, v1cfg.V1Dashboard.Widgets[0].GridPos.Y)
+}
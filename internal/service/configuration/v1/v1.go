@@ -15,6 +15,7 @@ type Datasource = model.Datasource
 
 // Dashboard represents a configuration v1 dashboard.
 type Dashboard struct {
+	Title     string                     `json:"title,omitempty"`
 	Grid      model.Grid                 `json:"grid,omitempty"`
 	Variables map[string]*model.Variable `json:"variables,omitempty"`
 	Widgets   []model.Widget             `json:"widgets,omitempty"`
@@ -41,6 +42,7 @@ func (c *Configuration) Dashboard() (model.Dashboard, error) {
 		vars = append(vars, *v)
 	}
 	dashboard := model.Dashboard{
+		Title:     c.V1Dashboard.Title,
 		Grid:      c.V1Dashboard.Grid,
 		Variables: vars,
 		Widgets:   c.V1Dashboard.Widgets,
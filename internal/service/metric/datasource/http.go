@@ -0,0 +1,142 @@
+package datasource
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+)
+
+// newHTTPClient returns an HTTP client for the datasources based on the
+// HTTP options, if the options don't have a timeout the default one
+// will be used.
+func newHTTPClient(opts model.HTTPOptions, defTimeout time.Duration) (*http.Client, error) {
+	rt, err := newHTTPRoundTripper(opts, defTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := httpTimeout(opts, defTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: rt,
+	}, nil
+}
+
+// newHTTPRoundTripper returns the transport of the HTTP clients with the
+// TLS, proxy, auth and headers options set. The timeout is set as the
+// response header timeout so clients that only accept a round tripper
+// also have it.
+func newHTTPRoundTripper(opts model.HTTPOptions, defTimeout time.Duration) (http.RoundTripper, error) {
+	tlsCfg, err := httpTLSConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	timeout, err := httpTimeout(opts, defTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy, err := httpProxy(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &authRoundTripper{
+		opts: opts,
+		next: &http.Transport{
+			Proxy:                 proxy,
+			TLSClientConfig:       tlsCfg,
+			ResponseHeaderTimeout: timeout,
+			MaxIdleConns:          100,
+			IdleConnTimeout:       90 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+		},
+	}, nil
+}
+
+func httpTLSConfig(opts model.HTTPOptions) (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: opts.Insecure}
+
+	if opts.CAFile != "" {
+		ca, err := ioutil.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA file: %s", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no valid certificates on CA file %s", opts.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %s", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+func httpProxy(opts model.HTTPOptions) (func(*http.Request) (*url.URL, error), error) {
+	if opts.ProxyURL == "" {
+		return http.ProxyFromEnvironment, nil
+	}
+
+	u, err := url.Parse(opts.ProxyURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL: %s", err)
+	}
+
+	return http.ProxyURL(u), nil
+}
+
+func httpTimeout(opts model.HTTPOptions, defTimeout time.Duration) (time.Duration, error) {
+	if opts.Timeout == "" {
+		return defTimeout, nil
+	}
+
+	t, err := time.ParseDuration(opts.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout: %s", err)
+	}
+
+	return t, nil
+}
+
+// authRoundTripper sets the auth and the custom headers on the requests.
+type authRoundTripper struct {
+	opts model.HTTPOptions
+	next http.RoundTripper
+}
+
+func (a *authRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	// Round trippers shouldn't modify the received request.
+	r = r.Clone(r.Context())
+
+	for k, v := range a.opts.Headers {
+		r.Header.Set(k, v)
+	}
+
+	switch {
+	case a.opts.BearerToken != "":
+		r.Header.Set("Authorization", "Bearer "+a.opts.BearerToken)
+	case a.opts.Username != "":
+		r.SetBasicAuth(a.opts.Username, a.opts.Password)
+	}
+
+	return a.next.RoundTrip(r)
+}
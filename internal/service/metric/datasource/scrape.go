@@ -0,0 +1,79 @@
+package datasource
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+	"github.com/slok/grafterm/internal/service/metric/scrape"
+)
+
+// scraperKey identifies the scrape gatherers that can be shared.
+type scraperKey struct {
+	ctx context.Context
+	ds  string
+}
+
+// scrapers are the running scrape gatherers, the gatherers created with the
+// same context (e.g the dashboards of the app) share the scrapers of the same
+// datasource settings instead of scraping the endpoint once per gatherer.
+var scrapers = struct {
+	gs map[scraperKey]metric.Gatherer
+	mu sync.Mutex
+}{gs: map[scraperKey]metric.Gatherer{}}
+
+// newScraper returns the scrape gatherer of the datasource, the gatherer
+// scrapes the endpoint until the context is done.
+func newScraper(ctx context.Context, ds model.ScrapeDatasource) (metric.Gatherer, error) {
+	// The datasource settings (with the secrets resolved) identify the scraper.
+	bs, err := json.Marshal(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	scrapers.mu.Lock()
+	defer scrapers.mu.Unlock()
+
+	key := scraperKey{ctx: ctx, ds: string(bs)}
+	if g, ok := scrapers.gs[key]; ok {
+		return g, nil
+	}
+
+	httpCli, err := newHTTPClient(ds.HTTPOptions, defScrapeTimeout)
+	if err != nil {
+		return nil, err
+	}
+
+	// The durations are validated by the model.
+	interval, _ := time.ParseDuration(ds.Interval)
+	retention, _ := time.ParseDuration(ds.Retention)
+
+	g, err := scrape.NewGatherer(ctx, scrape.ConfigGatherer{
+		Address:   ds.Address,
+		Interval:  interval,
+		Retention: retention,
+		HTTPCli:   httpCli,
+	})
+	if err != nil {
+		return nil, err
+	}
+	scrapers.gs[key] = g
+
+	// Contexts that are never done don't stop the scraper.
+	if ctx.Done() == nil {
+		return g, nil
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		scrapers.mu.Lock()
+		delete(scrapers.gs, key)
+		scrapers.mu.Unlock()
+	}()
+
+	return g, nil
+}
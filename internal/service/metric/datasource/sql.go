@@ -0,0 +1,57 @@
+package datasource
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+// sqlDBKey identifies the SQL databases that can be shared.
+type sqlDBKey struct {
+	ctx    context.Context
+	driver string
+	dsn    string
+}
+
+// sqlDBs are the opened SQL databases, the gatherers created with the same
+// context (e.g the dashboards of the app) share the databases with the same
+// driver and DSN instead of opening a connection pool per gatherer.
+var sqlDBs = struct {
+	dbs map[sqlDBKey]*sql.DB
+	mu  sync.Mutex
+}{dbs: map[sqlDBKey]*sql.DB{}}
+
+// openSQLDB returns the database of the driver and DSN, the database is
+// closed when the context is done.
+func openSQLDB(ctx context.Context, driver, dsn string) (*sql.DB, error) {
+	sqlDBs.mu.Lock()
+	defer sqlDBs.mu.Unlock()
+
+	key := sqlDBKey{ctx: ctx, driver: driver, dsn: dsn}
+	if db, ok := sqlDBs.dbs[key]; ok {
+		return db, nil
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(defSQLMaxOpenConns)
+	sqlDBs.dbs[key] = db
+
+	// Contexts that are never done don't close the database.
+	if ctx.Done() == nil {
+		return db, nil
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		sqlDBs.mu.Lock()
+		delete(sqlDBs.dbs, key)
+		sqlDBs.mu.Unlock()
+		db.Close()
+	}()
+
+	return db, nil
+}
@@ -0,0 +1,426 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+const (
+	defTimeField  = "@timestamp"
+	defTermsSize  = 10
+	defMetricType = "count"
+	instantRange  = 5 * time.Minute
+	instantStep   = 1 * time.Minute
+	minInterval   = 1 * time.Second
+
+	histogramAggName = "histogram"
+	metricAggName    = "metric"
+	termsAggPrefix   = "terms"
+)
+
+// ConfigGatherer is the configuration of the Elasticsearch gatherer.
+type ConfigGatherer struct {
+	// Address is the address of the Elasticsearch API.
+	Address string
+	// Index is the index or index pattern where the documents are searched.
+	Index string
+	// TimeField is the field of the documents with the timestamp.
+	TimeField string
+	// HTTPCli is the HTTP client used on the requests, it should
+	// set the auth of the requests if required.
+	HTTPCli *http.Client
+}
+
+func (c *ConfigGatherer) defaults() {
+	if c.TimeField == "" {
+		c.TimeField = defTimeField
+	}
+
+	if c.HTTPCli == nil {
+		c.HTTPCli = http.DefaultClient
+	}
+}
+
+type gatherer struct {
+	cfg       ConfigGatherer
+	searchURL string
+}
+
+// NewGatherer returns a new metric gatherer for Elasticsearch backends, it
+// gathers the metrics from the date histogram aggregations of the documents.
+func NewGatherer(cfg ConfigGatherer) (metric.Gatherer, error) {
+	cfg.defaults()
+
+	u, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, cfg.Index, "_search")
+
+	return &gatherer{
+		cfg:       cfg,
+		searchURL: u.String(),
+	}, nil
+}
+
+// query is the Elasticsearch query on the query expression, e.g:
+// `{"query": "status:500", "metric": {"type": "avg", "field": "latency"}, "terms": ["service"]}`.
+type query struct {
+	// Query is the Lucene query string that filters the documents.
+	Query string `json:"query,omitempty"`
+	// Metric is the metric aggregation of the histogram buckets, by
+	// default the number of documents.
+	Metric struct {
+		Type  string `json:"type,omitempty"`
+		Field string `json:"field,omitempty"`
+	} `json:"metric,omitempty"`
+	// Terms are the fields that split the documents in multiple series,
+	// the terms will be the labels of the series.
+	Terms []string `json:"terms,omitempty"`
+	// Size is the maximum number of terms of each of the term fields.
+	Size int `json:"size,omitempty"`
+}
+
+func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	res, err := g.GatherRange(ctx, query, t.Add(-1*instantRange), t, instantStep)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// Get the latest bucket of each series.
+	mss := []model.MetricSeries{}
+	for _, ms := range res {
+		if len(ms.Metrics) < 1 {
+			continue
+		}
+		ms.Metrics = ms.Metrics[len(ms.Metrics)-1:]
+		mss = append(mss, ms)
+	}
+
+	if len(mss) < 1 {
+		return []model.MetricSeries{}, fmt.Errorf("server didn't return any metric series")
+	}
+
+	return mss, nil
+}
+
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	q, err := g.parseQuery(query.Expr)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	body, err := json.Marshal(g.searchBody(q, start, end, step))
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	aggs, err := g.search(ctx, body)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	mss := []model.MetricSeries{}
+	err = g.collectSeries(q, aggs, 0, map[string]string{}, &mss)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	sort.Slice(mss, func(i, j int) bool { return mss[i].ID < mss[j].ID })
+
+	return mss, nil
+}
+
+func (g *gatherer) parseQuery(expr string) (query, error) {
+	q := query{}
+	expr = strings.TrimSpace(expr)
+	switch {
+	case expr == "":
+	// Not being a JSON object means that the expression is the Lucene query.
+	case !strings.HasPrefix(expr, "{"):
+		q.Query = expr
+	default:
+		err := json.Unmarshal([]byte(expr), &q)
+		if err != nil {
+			return q, fmt.Errorf("invalid Elasticsearch query: %s", err)
+		}
+	}
+
+	if q.Metric.Type == "" {
+		q.Metric.Type = defMetricType
+	}
+	if q.Metric.Type != defMetricType && q.Metric.Field == "" {
+		return q, fmt.Errorf("%s metric requires a field", q.Metric.Type)
+	}
+
+	if q.Size <= 0 {
+		q.Size = defTermsSize
+	}
+
+	return q, nil
+}
+
+// searchBody returns the search request body, the aggregations are one terms
+// aggregation per term field nested in order, and in the deepest level the
+// date histogram aggregation with the metric aggregation.
+func (g *gatherer) searchBody(q query, start, end time.Time, step time.Duration) map[string]interface{} {
+	filters := []interface{}{
+		map[string]interface{}{
+			"range": map[string]interface{}{
+				g.cfg.TimeField: map[string]interface{}{
+					"gte":    start.UnixNano() / int64(time.Millisecond),
+					"lte":    end.UnixNano() / int64(time.Millisecond),
+					"format": "epoch_millis",
+				},
+			},
+		},
+	}
+	if q.Query != "" {
+		filters = append(filters, map[string]interface{}{
+			"query_string": map[string]interface{}{
+				"query": q.Query,
+			},
+		})
+	}
+
+	histogram := map[string]interface{}{
+		"date_histogram": map[string]interface{}{
+			"field":          g.cfg.TimeField,
+			"fixed_interval": stepToInterval(step),
+			"min_doc_count":  0,
+			"extended_bounds": map[string]interface{}{
+				"min": start.UnixNano() / int64(time.Millisecond),
+				"max": end.UnixNano() / int64(time.Millisecond),
+			},
+		},
+	}
+	if q.Metric.Type != defMetricType {
+		histogram["aggs"] = map[string]interface{}{
+			metricAggName: map[string]interface{}{
+				q.Metric.Type: map[string]interface{}{
+					"field": q.Metric.Field,
+				},
+			},
+		}
+	}
+	aggs := map[string]interface{}{
+		histogramAggName: histogram,
+	}
+
+	// Wrap the aggregations with the terms from the deepest level.
+	for i := len(q.Terms) - 1; i >= 0; i-- {
+		aggs = map[string]interface{}{
+			termsAggName(i): map[string]interface{}{
+				"terms": map[string]interface{}{
+					"field": q.Terms[i],
+					"size":  q.Size,
+				},
+				"aggs": aggs,
+			},
+		}
+	}
+
+	return map[string]interface{}{
+		"size": 0,
+		"query": map[string]interface{}{
+			"bool": map[string]interface{}{
+				"filter": filters,
+			},
+		},
+		"aggs": aggs,
+	}
+}
+
+// searchError is the error returned by the Elasticsearch API.
+type searchError struct {
+	Error struct {
+		Type   string `json:"type"`
+		Reason string `json:"reason"`
+	} `json:"error"`
+}
+
+// search makes the search request and returns the aggregations of the response.
+func (g *gatherer) search(ctx context.Context, body []byte) (map[string]json.RawMessage, error) {
+	req, err := http.NewRequest(http.MethodPost, g.searchURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.cfg.HTTPCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	rbody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		serr := searchError{}
+		if json.Unmarshal(rbody, &serr) == nil && serr.Error.Reason != "" {
+			return nil, metric.StatusError(resp.StatusCode, fmt.Errorf("Elasticsearch error (%d): %s: %s", resp.StatusCode, serr.Error.Type, serr.Error.Reason))
+		}
+		return nil, metric.StatusError(resp.StatusCode, fmt.Errorf("Elasticsearch error (%d): %s", resp.StatusCode, strings.TrimSpace(string(rbody))))
+	}
+
+	res := struct {
+		Aggregations map[string]json.RawMessage `json:"aggregations"`
+	}{}
+	err = json.Unmarshal(rbody, &res)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Elasticsearch response: %s", err)
+	}
+
+	return res.Aggregations, nil
+}
+
+// bucketAggregation is the result of a bucket aggregation.
+type bucketAggregation struct {
+	Buckets []map[string]json.RawMessage `json:"buckets"`
+}
+
+// collectSeries walks the terms aggregations results setting the labels of the
+// series, and creates the series from the date histogram results.
+func (g *gatherer) collectSeries(q query, aggs map[string]json.RawMessage, level int, labels map[string]string, mss *[]model.MetricSeries) error {
+	// Deepest level, the histogram.
+	if level >= len(q.Terms) {
+		ms, err := g.histogramToSeries(q, aggs[histogramAggName], labels)
+		if err != nil {
+			return err
+		}
+		*mss = append(*mss, ms)
+		return nil
+	}
+
+	agg := bucketAggregation{}
+	err := json.Unmarshal(aggs[termsAggName(level)], &agg)
+	if err != nil {
+		return fmt.Errorf("invalid Elasticsearch terms aggregation: %s", err)
+	}
+
+	for _, b := range agg.Buckets {
+		bLabels := map[string]string{}
+		for k, v := range labels {
+			bLabels[k] = v
+		}
+		bLabels[q.Terms[level]] = bucketKey(b)
+
+		err := g.collectSeries(q, b, level+1, bLabels, mss)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (g *gatherer) histogramToSeries(q query, histogram json.RawMessage, labels map[string]string) (model.MetricSeries, error) {
+	agg := bucketAggregation{}
+	err := json.Unmarshal(histogram, &agg)
+	if err != nil {
+		return model.MetricSeries{}, fmt.Errorf("invalid Elasticsearch date histogram aggregation: %s", err)
+	}
+
+	metrics := []model.Metric{}
+	for _, b := range agg.Buckets {
+		var ts int64
+		err := json.Unmarshal(b["key"], &ts)
+		if err != nil {
+			return model.MetricSeries{}, fmt.Errorf("invalid Elasticsearch date histogram bucket key: %s", err)
+		}
+
+		// Count metric is the number of documents of the bucket, the other
+		// metrics are on the metric aggregation.
+		var value *float64
+		if q.Metric.Type == defMetricType {
+			err = json.Unmarshal(b["doc_count"], &value)
+		} else {
+			res := struct {
+				Value *float64 `json:"value"`
+			}{}
+			err = json.Unmarshal(b[metricAggName], &res)
+			value = res.Value
+		}
+		if err != nil {
+			return model.MetricSeries{}, fmt.Errorf("invalid Elasticsearch metric aggregation: %s", err)
+		}
+
+		// Buckets without documents don't have value on most metrics.
+		if value == nil {
+			continue
+		}
+
+		metrics = append(metrics, model.Metric{
+			TS:    time.Unix(0, ts*int64(time.Millisecond)),
+			Value: *value,
+		})
+	}
+
+	return model.MetricSeries{
+		ID:      seriesID(q, labels),
+		Labels:  labels,
+		Metrics: metrics,
+	}, nil
+}
+
+// seriesID returns the ID of a series based on the metric and the labels, e.g:
+// `avg(latency){service="api",status="500"}`.
+func seriesID(q query, labels map[string]string) string {
+	id := q.Metric.Type
+	if q.Metric.Field != "" {
+		id = fmt.Sprintf("%s(%s)", q.Metric.Type, q.Metric.Field)
+	}
+
+	if len(labels) == 0 {
+		return id
+	}
+
+	kvs := make([]string, 0, len(labels))
+	for _, t := range q.Terms {
+		kvs = append(kvs, fmt.Sprintf("%s=%q", t, labels[t]))
+	}
+
+	return fmt.Sprintf("%s{%s}", id, strings.Join(kvs, ","))
+}
+
+// bucketKey returns the key of a terms bucket as a string.
+func bucketKey(b map[string]json.RawMessage) string {
+	var key string
+	if err := json.Unmarshal(b["key_as_string"], &key); err == nil {
+		return key
+	}
+	if err := json.Unmarshal(b["key"], &key); err == nil {
+		return key
+	}
+
+	// Not a string key (e.g numbers).
+	return string(b["key"])
+}
+
+func termsAggName(level int) string {
+	return fmt.Sprintf("%s%d", termsAggPrefix, level)
+}
+
+// stepToInterval returns the date histogram interval of a step.
+func stepToInterval(step time.Duration) string {
+	if step < minInterval {
+		step = minInterval
+	}
+	return fmt.Sprintf("%ds", int64(step/time.Second))
+}
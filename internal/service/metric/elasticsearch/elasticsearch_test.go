@@ -0,0 +1,275 @@
+package elasticsearch_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/elasticsearch"
+)
+
+const (
+	countResponse = `
+{
+  "aggregations": {
+    "histogram": {
+      "buckets": [
+        {"key": 1558275600000, "doc_count": 12},
+        {"key": 1558275660000, "doc_count": 0},
+        {"key": 1558275720000, "doc_count": 7}
+      ]
+    }
+  }
+}`
+	termsResponse = `
+{
+  "aggregations": {
+    "terms0": {
+      "buckets": [
+        {
+          "key": "api",
+          "doc_count": 10,
+          "terms1": {
+            "buckets": [
+              {
+                "key": 500,
+                "doc_count": 10,
+                "histogram": {
+                  "buckets": [
+                    {"key": 1558275600000, "doc_count": 6, "metric": {"value": 120.5}},
+                    {"key": 1558275660000, "doc_count": 0, "metric": {"value": null}},
+                    {"key": 1558275720000, "doc_count": 4, "metric": {"value": 98}}
+                  ]
+                }
+              }
+            ]
+          }
+        },
+        {
+          "key": "web",
+          "doc_count": 2,
+          "terms1": {
+            "buckets": [
+              {
+                "key": 200,
+                "doc_count": 2,
+                "histogram": {
+                  "buckets": [
+                    {"key": 1558275600000, "doc_count": 2, "metric": {"value": 12}}
+                  ]
+                }
+              }
+            ]
+          }
+        }
+      ]
+    }
+  }
+}`
+)
+
+func ts(ms int64) time.Time {
+	return time.Unix(0, ms*int64(time.Millisecond))
+}
+
+func TestGathererGatherRange(t *testing.T) {
+	start := ts(1558275600000)
+	end := ts(1558275780000)
+
+	tests := map[string]struct {
+		cfg             elasticsearch.ConfigGatherer
+		query           string
+		step            time.Duration
+		esStatus        int
+		esResponse      string
+		expPath         string
+		expRequest      string
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"A Lucene query should count the documents of each histogram bucket.": {
+			cfg:        elasticsearch.ConfigGatherer{Index: "logs-*"},
+			query:      "status:500",
+			step:       time.Minute,
+			esResponse: countResponse,
+			expPath:    "/logs-*/_search",
+			expRequest: `{
+  "size": 0,
+  "query": {"bool": {"filter": [
+    {"range": {"@timestamp": {"gte": 1558275600000, "lte": 1558275780000, "format": "epoch_millis"}}},
+    {"query_string": {"query": "status:500"}}
+  ]}},
+  "aggs": {
+    "histogram": {"date_histogram": {
+      "field": "@timestamp", "fixed_interval": "60s", "min_doc_count": 0,
+      "extended_bounds": {"min": 1558275600000, "max": 1558275780000}
+    }}
+  }
+}`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     "count",
+					Labels: map[string]string{},
+					Metrics: []model.Metric{
+						{TS: ts(1558275600000), Value: 12},
+						{TS: ts(1558275660000), Value: 0},
+						{TS: ts(1558275720000), Value: 7},
+					},
+				},
+			},
+		},
+		"A metric with terms should return one series per term bucket with the terms as labels.": {
+			cfg:        elasticsearch.ConfigGatherer{Index: "logs", TimeField: "ts"},
+			query:      `{"metric": {"type": "avg", "field": "latency"}, "terms": ["service", "status"], "size": 5}`,
+			step:       90 * time.Second,
+			esResponse: termsResponse,
+			expPath:    "/logs/_search",
+			expRequest: `{
+  "size": 0,
+  "query": {"bool": {"filter": [
+    {"range": {"ts": {"gte": 1558275600000, "lte": 1558275780000, "format": "epoch_millis"}}}
+  ]}},
+  "aggs": {
+    "terms0": {
+      "terms": {"field": "service", "size": 5},
+      "aggs": {
+        "terms1": {
+          "terms": {"field": "status", "size": 5},
+          "aggs": {
+            "histogram": {
+              "date_histogram": {
+                "field": "ts", "fixed_interval": "90s", "min_doc_count": 0,
+                "extended_bounds": {"min": 1558275600000, "max": 1558275780000}
+              },
+              "aggs": {"metric": {"avg": {"field": "latency"}}}
+            }
+          }
+        }
+      }
+    }
+  }
+}`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `avg(latency){service="api",status="500"}`,
+					Labels: map[string]string{"service": "api", "status": "500"},
+					Metrics: []model.Metric{
+						{TS: ts(1558275600000), Value: 120.5},
+						{TS: ts(1558275720000), Value: 98},
+					},
+				},
+				{
+					ID:     `avg(latency){service="web",status="200"}`,
+					Labels: map[string]string{"service": "web", "status": "200"},
+					Metrics: []model.Metric{
+						{TS: ts(1558275600000), Value: 12},
+					},
+				},
+			},
+		},
+		"A metric without field should fail.": {
+			cfg:    elasticsearch.ConfigGatherer{Index: "logs"},
+			query:  `{"metric": {"type": "avg"}}`,
+			expErr: true,
+		},
+		"An invalid JSON query should fail.": {
+			cfg:    elasticsearch.ConfigGatherer{Index: "logs"},
+			query:  `{"metric": `,
+			expErr: true,
+		},
+		"An error response should fail.": {
+			cfg:        elasticsearch.ConfigGatherer{Index: "logs"},
+			query:      "status:500",
+			esStatus:   http.StatusBadRequest,
+			esResponse: `{"error": {"type": "search_phase_execution_exception", "reason": "all shards failed"}, "status": 400}`,
+			expErr:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mock server response.
+			var gotPath string
+			var gotBody []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotBody, _ = ioutil.ReadAll(r.Body)
+				if test.esStatus != 0 {
+					w.WriteHeader(test.esStatus)
+				}
+				w.Write([]byte(test.esResponse))
+			}))
+			defer srv.Close()
+			test.cfg.Address = srv.URL
+
+			g, err := elasticsearch.NewGatherer(test.cfg)
+			assert.NoError(err)
+			gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: test.query}, start, end, test.step)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expPath, gotPath)
+				assert.JSONEq(test.expRequest, string(gotBody))
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+		})
+	}
+}
+
+func TestGathererGatherSingle(t *testing.T) {
+	tests := map[string]struct {
+		esResponse      string
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"Getting a single metric should return the latest bucket of each series.": {
+			esResponse: termsResponse,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:      `avg(latency){service="api",status="500"}`,
+					Labels:  map[string]string{"service": "api", "status": "500"},
+					Metrics: []model.Metric{{TS: ts(1558275720000), Value: 98}},
+				},
+				{
+					ID:      `avg(latency){service="web",status="200"}`,
+					Labels:  map[string]string{"service": "web", "status": "200"},
+					Metrics: []model.Metric{{TS: ts(1558275600000), Value: 12}},
+				},
+			},
+		},
+		"Getting 0 metric series should error.": {
+			esResponse: `{"aggregations": {"terms0": {"buckets": []}}}`,
+			expErr:     true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mock server response.
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(test.esResponse))
+			}))
+			defer srv.Close()
+
+			g, err := elasticsearch.NewGatherer(elasticsearch.ConfigGatherer{Address: srv.URL, Index: "logs"})
+			assert.NoError(err)
+			query := `{"metric": {"type": "avg", "field": "latency"}, "terms": ["service", "status"]}`
+			gotms, err := g.GatherSingle(context.TODO(), model.Query{Expr: query}, time.Now())
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+		})
+	}
+}
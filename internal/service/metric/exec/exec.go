@@ -0,0 +1,317 @@
+package exec
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	osexec "os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+const (
+	defTimeout        = 10 * time.Second
+	defMaxOutputBytes = 1024 * 1024
+	maxStderrBytes    = 1024
+	waitDelay         = time.Second
+
+	envStart     = "GRAFTERM_START"
+	envEnd       = "GRAFTERM_END"
+	envStep      = "GRAFTERM_STEP"
+	envQueryType = "GRAFTERM_QUERY_TYPE"
+
+	queryTypeSingle = "single"
+	queryTypeRange  = "range"
+)
+
+// ConfigGatherer is the configuration of the exec gatherer.
+type ConfigGatherer struct {
+	// Dir is the working directory of the commands, by default the
+	// current directory.
+	Dir string
+	// Timeout is the maximum time the commands can run.
+	Timeout time.Duration
+	// MaxOutputBytes is the maximum size of the command output.
+	MaxOutputBytes int
+	// Shell is the command that runs the query expressions, the expression
+	// is added as the last argument. By default `sh -c`.
+	Shell []string
+}
+
+func (c *ConfigGatherer) defaults() {
+	if c.Timeout <= 0 {
+		c.Timeout = defTimeout
+	}
+
+	if c.MaxOutputBytes <= 0 {
+		c.MaxOutputBytes = defMaxOutputBytes
+	}
+
+	if len(c.Shell) == 0 {
+		c.Shell = []string{"sh", "-c"}
+	}
+}
+
+type gatherer struct {
+	cfg ConfigGatherer
+}
+
+// NewGatherer returns a new metric gatherer that runs the query expressions
+// as commands and gets the metrics from their output. The time range of the
+// query is set on the environment of the commands:
+// - `GRAFTERM_START` and `GRAFTERM_END`: The time range in Unix seconds.
+// - `GRAFTERM_STEP`: The step in seconds.
+// - `GRAFTERM_QUERY_TYPE`: `range` or `single`.
+func NewGatherer(cfg ConfigGatherer) (metric.Gatherer, error) {
+	cfg.defaults()
+
+	return &gatherer{
+		cfg: cfg,
+	}, nil
+}
+
+func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	res, err := g.gather(ctx, query, queryTypeSingle, t, t, 0)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// Get the latest datapoint of each series.
+	mss := []model.MetricSeries{}
+	for _, ms := range res {
+		if len(ms.Metrics) < 1 {
+			continue
+		}
+		ms.Metrics = ms.Metrics[len(ms.Metrics)-1:]
+		mss = append(mss, ms)
+	}
+
+	return mss, nil
+}
+
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	return g.gather(ctx, query, queryTypeRange, start, end, step)
+}
+
+func (g *gatherer) gather(ctx context.Context, query model.Query, queryType string, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.Timeout)
+	defer cancel()
+
+	args := append(g.cfg.Shell[1:len(g.cfg.Shell):len(g.cfg.Shell)], query.Expr)
+	cmd := osexec.CommandContext(ctx, g.cfg.Shell[0], args...)
+	cmd.Dir = g.cfg.Dir
+	// Don't wait forever for the subprocesses that keep the output open
+	// after the command has been killed.
+	cmd.WaitDelay = waitDelay
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%d", envStart, start.Unix()),
+		fmt.Sprintf("%s=%d", envEnd, end.Unix()),
+		fmt.Sprintf("%s=%s", envStep, strconv.FormatFloat(step.Seconds(), 'f', -1, 64)),
+		fmt.Sprintf("%s=%s", envQueryType, queryType),
+	)
+	stdout := &limitedBuffer{max: g.cfg.MaxOutputBytes, cancel: cancel}
+	stderr := &limitedBuffer{max: maxStderrBytes, truncate: true}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	err := cmd.Run()
+	switch {
+	case stdout.exceeded:
+		return nil, fmt.Errorf("command output exceeds the limit of %d bytes", g.cfg.MaxOutputBytes)
+	case ctx.Err() == context.DeadlineExceeded:
+		return nil, fmt.Errorf("command timed out after %s", g.cfg.Timeout)
+	case err != nil:
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("command failed: %s: %s", err, msg)
+		}
+		return nil, fmt.Errorf("command failed: %s", err)
+	}
+
+	return parseOutput(stdout.Bytes(), query.Expr)
+}
+
+// parseOutput parses the command output, it can be a JSON list of series or
+// lines with `timestamp value label=value...` format. The series without
+// ID nor labels will use the expression as the ID.
+func parseOutput(out []byte, expr string) ([]model.MetricSeries, error) {
+	out = bytes.TrimSpace(out)
+	if bytes.HasPrefix(out, []byte("[")) {
+		return parseJSON(out, expr)
+	}
+	return parseLines(out, expr)
+}
+
+type jsonSeries struct {
+	ID      string            `json:"id"`
+	Labels  map[string]string `json:"labels"`
+	Metrics []struct {
+		TS    json.RawMessage `json:"ts"`
+		Value float64         `json:"value"`
+	} `json:"metrics"`
+}
+
+func parseJSON(out []byte, expr string) ([]model.MetricSeries, error) {
+	jss := []jsonSeries{}
+	err := json.Unmarshal(out, &jss)
+	if err != nil {
+		return nil, fmt.Errorf("invalid command JSON output: %s", err)
+	}
+
+	res := make([]model.MetricSeries, 0, len(jss))
+	for _, js := range jss {
+		ms := model.MetricSeries{
+			ID:      js.ID,
+			Labels:  js.Labels,
+			Metrics: make([]model.Metric, 0, len(js.Metrics)),
+		}
+		if ms.Labels == nil {
+			ms.Labels = map[string]string{}
+		}
+		if ms.ID == "" {
+			ms.ID = expr
+			if len(ms.Labels) > 0 {
+				ms.ID = seriesID(ms.Labels)
+			}
+		}
+
+		for _, m := range js.Metrics {
+			ts := strings.Trim(string(m.TS), `"`)
+			t, err := parseTimestamp(ts)
+			if err != nil {
+				return nil, err
+			}
+			ms.Metrics = append(ms.Metrics, model.Metric{TS: t, Value: m.Value})
+		}
+		res = append(res, ms)
+	}
+
+	return res, nil
+}
+
+// parseLines parses the `timestamp value label=value...` lines, the lines
+// with the same labels are the same series.
+func parseLines(out []byte, expr string) ([]model.MetricSeries, error) {
+	res := []model.MetricSeries{}
+	index := map[string]int{}
+
+	sc := bufio.NewScanner(bytes.NewReader(out))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("invalid command output line %d: timestamp and value are required", n)
+		}
+		t, err := parseTimestamp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid command output line %d: %s", n, err)
+		}
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid command output line %d: invalid value: %s", n, err)
+		}
+
+		labels := map[string]string{}
+		for _, f := range fields[2:] {
+			kv := strings.SplitN(f, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("invalid command output line %d: invalid label %s", n, f)
+			}
+			labels[kv[0]] = strings.Trim(kv[1], `"`)
+		}
+
+		id := expr
+		if len(labels) > 0 {
+			id = seriesID(labels)
+		}
+		i, ok := index[id]
+		if !ok {
+			i = len(res)
+			index[id] = i
+			res = append(res, model.MetricSeries{
+				ID:      id,
+				Labels:  labels,
+				Metrics: []model.Metric{},
+			})
+		}
+		res[i].Metrics = append(res[i].Metrics, model.Metric{TS: t, Value: v})
+	}
+
+	return res, sc.Err()
+}
+
+// parseTimestamp parses Unix timestamps in seconds (with decimals) or
+// RFC3339 timestamps.
+func parseTimestamp(ts string) (time.Time, error) {
+	if f, err := strconv.ParseFloat(ts, 64); err == nil {
+		sec, dec := math.Modf(f)
+		return time.Unix(int64(sec), int64(dec*float64(time.Second))), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s, should be Unix seconds or RFC3339", ts)
+	}
+	return t, nil
+}
+
+// seriesID returns the ID of a series based on the labels, e.g:
+// `{queue="jobs",region="eu"}`.
+func seriesID(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(kvs, ","))
+}
+
+// limitedBuffer is a buffer with a maximum size. When the size is exceeded the
+// extra data is discarded if truncate is set, otherwise it cancels the command.
+type limitedBuffer struct {
+	// buf is not embedded so the buffer ReadFrom doesn't bypass the limit.
+	buf      bytes.Buffer
+	max      int
+	truncate bool
+	exceeded bool
+	cancel   func()
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	free := l.max - l.buf.Len()
+	if len(p) <= free {
+		return l.buf.Write(p)
+	}
+
+	l.buf.Write(p[:free])
+	if l.truncate {
+		return len(p), nil
+	}
+
+	l.exceeded = true
+	if l.cancel != nil {
+		l.cancel()
+	}
+	return free, fmt.Errorf("output limit exceeded")
+}
+
+func (l *limitedBuffer) Bytes() []byte  { return l.buf.Bytes() }
+func (l *limitedBuffer) String() string { return l.buf.String() }
@@ -0,0 +1,144 @@
+package exec_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/exec"
+)
+
+func TestGathererGatherRange(t *testing.T) {
+	start := time.Unix(1558275600, 0)
+	end := time.Unix(1558279200, 0)
+
+	tests := map[string]struct {
+		cfg             exec.ConfigGatherer
+		expr            string
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"Lines output should be grouped in series by labels.": {
+			expr: `printf '1558275600 10 queue=jobs\n1558275660.5 12 queue=jobs\n# comment\n\n1558275600 3 queue=mails\n'`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `{queue="jobs"}`,
+					Labels: map[string]string{"queue": "jobs"},
+					Metrics: []model.Metric{
+						{TS: time.Unix(1558275600, 0), Value: 10},
+						{TS: time.Unix(1558275660, 500000000), Value: 12},
+					},
+				},
+				{
+					ID:      `{queue="mails"}`,
+					Labels:  map[string]string{"queue": "mails"},
+					Metrics: []model.Metric{{TS: time.Unix(1558275600, 0), Value: 3}},
+				},
+			},
+		},
+		"Lines output without labels should use the expression as the ID.": {
+			expr: `echo 2019-05-19T14:20:00Z 42`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:      `echo 2019-05-19T14:20:00Z 42`,
+					Labels:  map[string]string{},
+					Metrics: []model.Metric{{TS: time.Date(2019, 5, 19, 14, 20, 0, 0, time.UTC), Value: 42}},
+				},
+			},
+		},
+		"The time range should be on the environment.": {
+			expr: `echo $GRAFTERM_START 1 type=$GRAFTERM_QUERY_TYPE; echo $GRAFTERM_END $GRAFTERM_STEP type=$GRAFTERM_QUERY_TYPE`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `{type="range"}`,
+					Labels: map[string]string{"type": "range"},
+					Metrics: []model.Metric{
+						{TS: start, Value: 1},
+						{TS: end, Value: 30},
+					},
+				},
+			},
+		},
+		"JSON output should be parsed as series.": {
+			expr: `echo '[{"id": "jobs", "labels": {"queue": "jobs"}, "metrics": [{"ts": 1558275600, "value": 10}, {"ts": "2019-05-19T14:21:00Z", "value": 11}]}]'`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     "jobs",
+					Labels: map[string]string{"queue": "jobs"},
+					Metrics: []model.Metric{
+						{TS: time.Unix(1558275600, 0), Value: 10},
+						{TS: time.Date(2019, 5, 19, 14, 21, 0, 0, time.UTC), Value: 11},
+					},
+				},
+			},
+		},
+		"Invalid output should fail.": {
+			expr:   `echo 1558275600 ten`,
+			expErr: true,
+		},
+		"A failed command should fail.": {
+			expr:   `echo wanted >&2; exit 1`,
+			expErr: true,
+		},
+		"A command that exceeds the timeout should fail.": {
+			cfg:    exec.ConfigGatherer{Timeout: 50 * time.Millisecond},
+			expr:   `sleep 5`,
+			expErr: true,
+		},
+		"A command that exceeds the output limit should fail.": {
+			cfg:    exec.ConfigGatherer{MaxOutputBytes: 10},
+			expr:   `echo 1558275600 1 queue=jobs`,
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g, err := exec.NewGatherer(test.cfg)
+			require.NoError(t, err)
+
+			gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: test.expr}, start, end, 30*time.Second)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				// Make the times comparable.
+				for _, ms := range gotms {
+					for i := range ms.Metrics {
+						ms.Metrics[i].TS = ms.Metrics[i].TS.UTC()
+					}
+				}
+				for _, ms := range test.expMetricSeries {
+					for i := range ms.Metrics {
+						ms.Metrics[i].TS = ms.Metrics[i].TS.UTC()
+					}
+				}
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+		})
+	}
+}
+
+func TestGathererGatherSingle(t *testing.T) {
+	assert := assert.New(t)
+
+	dir, err := ioutil.TempDir("", "grafterm-exec")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	require.NoError(t, ioutil.WriteFile(dir+"/depth", []byte("1558275600 5\n1558275660 7\n"), 0644))
+
+	// The commands should run on the working directory.
+	g, err := exec.NewGatherer(exec.ConfigGatherer{Dir: dir})
+	require.NoError(t, err)
+	gotms, err := g.GatherSingle(context.TODO(), model.Query{Expr: "cat depth; echo 1558275660 $GRAFTERM_QUERY_TYPE-1 | sed 's/single-//'"}, time.Now())
+	if assert.NoError(err) && assert.Len(gotms, 1) {
+		assert.Equal([]model.Metric{{TS: time.Unix(1558275660, 0), Value: 1}}, gotms[0].Metrics)
+	}
+}
@@ -0,0 +1,370 @@
+package file
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+	"github.com/slok/grafterm/internal/service/metric/selector"
+)
+
+// Format is the format of the file.
+type Format string
+
+const (
+	// FormatCSV is a CSV file with header.
+	FormatCSV Format = "csv"
+	// FormatJSONLines is a file with one JSON object per line.
+	FormatJSONLines Format = "jsonl"
+)
+
+const nameLabel = "name"
+
+// timestampKeys are the columns or keys that can have the timestamp.
+var timestampKeys = map[string]bool{"timestamp": true, "time": true, "ts": true}
+
+const valueKey = "value"
+
+// ConfigGatherer is the configuration of the file gatherer.
+type ConfigGatherer struct {
+	// Path is the path of the file with the samples.
+	Path string
+	// Format is the format of the file, by default is detected by
+	// the file extension.
+	Format Format
+}
+
+func (c *ConfigGatherer) defaults() error {
+	if c.Path == "" {
+		return fmt.Errorf("file path is required")
+	}
+
+	if c.Format == "" {
+		switch strings.ToLower(filepath.Ext(c.Path)) {
+		case ".csv":
+			c.Format = FormatCSV
+		case ".jsonl", ".json", ".ndjson":
+			c.Format = FormatJSONLines
+		default:
+			return fmt.Errorf("unknown file format of %s", c.Path)
+		}
+	}
+
+	return nil
+}
+
+type gatherer struct {
+	cfg    ConfigGatherer
+	series []*series
+}
+
+// series is a series of the file with the samples sorted by time.
+type series struct {
+	id      string
+	labels  map[string]string
+	metrics []model.Metric
+}
+
+// NewGatherer returns a new metric gatherer that gets the metrics from the
+// samples of a CSV or JSON lines file. The file is loaded on the creation.
+func NewGatherer(cfg ConfigGatherer) (metric.Gatherer, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	l := newLoader()
+	switch cfg.Format {
+	case FormatCSV:
+		err = l.loadCSV(f)
+	case FormatJSONLines:
+		err = l.loadJSONLines(f)
+	default:
+		err = fmt.Errorf("unknown file format %s", cfg.Format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not load %s: %s", cfg.Path, err)
+	}
+
+	return &gatherer{
+		cfg:    cfg,
+		series: l.result(),
+	}, nil
+}
+
+func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	sel, err := selector.Parse(query.Expr, nameLabel)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	res := []model.MetricSeries{}
+	for _, s := range g.series {
+		if !sel.Matches(s.labels[nameLabel], s.labels) {
+			continue
+		}
+
+		// Get the latest sample at the required time.
+		i := sort.Search(len(s.metrics), func(i int) bool { return s.metrics[i].TS.After(t) })
+		if i == 0 {
+			continue
+		}
+		res = append(res, s.metricSeries(s.metrics[i-1:i]))
+	}
+
+	return res, nil
+}
+
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	sel, err := selector.Parse(query.Expr, nameLabel)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	res := []model.MetricSeries{}
+	for _, s := range g.series {
+		if !sel.Matches(s.labels[nameLabel], s.labels) {
+			continue
+		}
+
+		ms := resample(s.metrics, start, end, step)
+		if len(ms) > 0 {
+			res = append(res, s.metricSeries(ms))
+		}
+	}
+
+	return res, nil
+}
+
+// resample returns one metric per step of the time range, the value of
+// every step is the latest sample of the step. Without step the samples
+// of the time range are returned as they are.
+func resample(metrics []model.Metric, start, end time.Time, step time.Duration) []model.Metric {
+	first := sort.Search(len(metrics), func(i int) bool { return !metrics[i].TS.Before(start) })
+	last := sort.Search(len(metrics), func(i int) bool { return metrics[i].TS.After(end) })
+	if first >= last {
+		return nil
+	}
+	metrics = metrics[first:last]
+
+	if step <= 0 {
+		return append([]model.Metric(nil), metrics...)
+	}
+
+	res := []model.Metric{}
+	i := 0
+	for t := start; !t.After(end); t = t.Add(step) {
+		// Samples on (t-step, t].
+		var m *model.Metric
+		for ; i < len(metrics) && !metrics[i].TS.After(t); i++ {
+			m = &metrics[i]
+		}
+		if m != nil {
+			res = append(res, model.Metric{TS: t, Value: m.Value})
+		}
+	}
+
+	return res
+}
+
+func (s *series) metricSeries(ms []model.Metric) model.MetricSeries {
+	labels := make(map[string]string, len(s.labels))
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+
+	return model.MetricSeries{
+		ID:      s.id,
+		Labels:  labels,
+		Metrics: append([]model.Metric(nil), ms...),
+	}
+}
+
+// loader groups the samples of the file by series.
+type loader struct {
+	series map[string]*series
+}
+
+func newLoader() *loader {
+	return &loader{series: map[string]*series{}}
+}
+
+func (l *loader) add(labels map[string]string, ts string, value string) error {
+	t, err := parseTimestamp(ts)
+	if err != nil {
+		return err
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return fmt.Errorf("invalid value %s", value)
+	}
+
+	id := seriesID(labels)
+	s, ok := l.series[id]
+	if !ok {
+		s = &series{id: id, labels: labels}
+		l.series[id] = s
+	}
+	s.metrics = append(s.metrics, model.Metric{TS: t, Value: v})
+
+	return nil
+}
+
+// result returns the series sorted by ID with the samples sorted by time.
+func (l *loader) result() []*series {
+	res := make([]*series, 0, len(l.series))
+	for _, s := range l.series {
+		sort.SliceStable(s.metrics, func(i, j int) bool { return s.metrics[i].TS.Before(s.metrics[j].TS) })
+		res = append(res, s)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].id < res[j].id })
+
+	return res
+}
+
+// loadCSV loads a CSV file with header, the timestamp and value columns
+// are the sample and the rest of the columns are the labels.
+func (l *loader) loadCSV(r io.Reader) error {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("invalid CSV header: %s", err)
+	}
+	tsCol, valueCol := -1, -1
+	for i, c := range header {
+		c = strings.TrimSpace(c)
+		header[i] = c
+		switch {
+		case timestampKeys[strings.ToLower(c)]:
+			tsCol = i
+		case strings.ToLower(c) == valueKey:
+			valueCol = i
+		}
+	}
+	if tsCol < 0 || valueCol < 0 {
+		return fmt.Errorf("CSV header requires timestamp and value columns")
+	}
+
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		labels := map[string]string{}
+		for i, v := range record {
+			if i != tsCol && i != valueCol && v != "" {
+				labels[header[i]] = v
+			}
+		}
+
+		err = l.add(labels, record[tsCol], record[valueCol])
+		if err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+}
+
+// loadJSONLines loads a file with a JSON object per line, the timestamp and
+// value keys are the sample and the rest of the keys are the labels (a
+// `labels` object is also accepted).
+func (l *loader) loadJSONLines(r io.Reader) error {
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; sc.Scan(); line++ {
+		if strings.TrimSpace(sc.Text()) == "" {
+			continue
+		}
+
+		obj := map[string]interface{}{}
+		dec := json.NewDecoder(strings.NewReader(sc.Text()))
+		dec.UseNumber()
+		if err := dec.Decode(&obj); err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+
+		var ts, value string
+		labels := map[string]string{}
+		for k, v := range obj {
+			switch {
+			case timestampKeys[strings.ToLower(k)]:
+				ts = fmt.Sprint(v)
+			case strings.ToLower(k) == valueKey:
+				value = fmt.Sprint(v)
+			case k == "labels":
+				ls, ok := v.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("line %d: labels should be an object", line)
+				}
+				for lk, lv := range ls {
+					labels[lk] = fmt.Sprint(lv)
+				}
+			default:
+				labels[k] = fmt.Sprint(v)
+			}
+		}
+
+		if err := l.add(labels, ts, value); err != nil {
+			return fmt.Errorf("line %d: %s", line, err)
+		}
+	}
+
+	return sc.Err()
+}
+
+// parseTimestamp parses Unix timestamps in seconds (with decimals) or
+// RFC3339 timestamps.
+func parseTimestamp(ts string) (time.Time, error) {
+	if f, err := strconv.ParseFloat(ts, 64); err == nil {
+		sec, dec := math.Modf(f)
+		return time.Unix(int64(sec), int64(dec*float64(time.Second))), nil
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s, should be Unix seconds or RFC3339", ts)
+	}
+	return t, nil
+}
+
+// seriesID returns the ID of a series based on the labels, the name
+// label is used as the prefix, e.g: `http_requests{code="500",job="api"}`.
+func seriesID(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		if k != nameLabel {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	kvs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+
+	return fmt.Sprintf("%s{%s}", labels[nameLabel], strings.Join(kvs, ","))
+}
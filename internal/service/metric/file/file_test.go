@@ -0,0 +1,198 @@
+package file_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/file"
+)
+
+const (
+	csvSamples = `timestamp,value,name,job,code
+1558275600,1,http_requests,api,200
+1558275630,2,http_requests,api,200
+1558275660,3,http_requests,api,200
+1558275720,4,http_requests,api,200
+1558275600,10,http_requests,api,500
+2019-05-19T14:21:00Z,20,http_requests,api,500
+1558275600,100,http_requests,web,200
+`
+	jsonlSamples = `{"ts": 1558275600, "value": 1, "name": "http_requests", "job": "api", "code": 200}
+{"ts": 1558275630, "value": 2, "labels": {"job": "api", "code": "200"}, "name": "http_requests"}
+
+{"ts": 1558275660, "value": 3, "name": "http_requests", "job": "api", "code": "200"}
+{"ts": 1558275720, "value": 4, "name": "http_requests", "job": "api", "code": "200"}
+{"ts": 1558275600, "value": 10, "name": "http_requests", "job": "api", "code": "500"}
+{"ts": "2019-05-19T14:21:00Z", "value": 20, "name": "http_requests", "job": "api", "code": "500"}
+{"ts": 1558275600, "value": 100, "name": "http_requests", "job": "web", "code": "200"}
+`
+)
+
+func ts(s int64) time.Time {
+	return time.Unix(s, 0)
+}
+
+func writeFiles(t *testing.T) (dir string) {
+	dir, err := ioutil.TempDir("", "grafterm-file")
+	require.NoError(t, err)
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "samples.csv"), []byte(csvSamples), 0644))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(dir, "samples.jsonl"), []byte(jsonlSamples), 0644))
+	return dir
+}
+
+func TestGathererGatherRange(t *testing.T) {
+	dir := writeFiles(t)
+	defer os.RemoveAll(dir)
+
+	api200 := map[string]string{"name": "http_requests", "job": "api", "code": "200"}
+	api500 := map[string]string{"name": "http_requests", "job": "api", "code": "500"}
+
+	tests := map[string]struct {
+		expr            string
+		start           time.Time
+		end             time.Time
+		step            time.Duration
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"The samples should be resampled by step with the latest sample of each step.": {
+			expr:  `http_requests{job="api",code="200"}`,
+			start: ts(1558275600),
+			end:   ts(1558275780),
+			step:  time.Minute,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `http_requests{code="200",job="api"}`,
+					Labels: api200,
+					Metrics: []model.Metric{
+						{TS: ts(1558275600), Value: 1},
+						{TS: ts(1558275660), Value: 3},
+						{TS: ts(1558275720), Value: 4},
+					},
+				},
+			},
+		},
+		"The samples should be sliced by the time range.": {
+			expr:  `{code="200", job!="web"}`,
+			start: ts(1558275630),
+			end:   ts(1558275660),
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `http_requests{code="200",job="api"}`,
+					Labels: api200,
+					Metrics: []model.Metric{
+						{TS: ts(1558275630), Value: 2},
+						{TS: ts(1558275660), Value: 3},
+					},
+				},
+			},
+		},
+		"Regex matchers should select the series.": {
+			expr:  `{code=~"5.."}`,
+			start: ts(1558275600),
+			end:   ts(1558275720),
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `http_requests{code="500",job="api"}`,
+					Labels: api500,
+					Metrics: []model.Metric{
+						{TS: ts(1558275600), Value: 10},
+						{TS: ts(1558275660), Value: 20},
+					},
+				},
+			},
+		},
+		"An invalid selector should fail.": {
+			expr:   `{code=200}`,
+			expErr: true,
+		},
+		"An invalid regex should fail.": {
+			expr:   `{code=~"5(("}`,
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		for _, f := range []string{"samples.csv", "samples.jsonl"} {
+			t.Run(name+" ("+f+")", func(t *testing.T) {
+				assert := assert.New(t)
+
+				g, err := file.NewGatherer(file.ConfigGatherer{Path: filepath.Join(dir, f)})
+				require.NoError(t, err)
+
+				gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: test.expr}, test.start, test.end, test.step)
+				if test.expErr {
+					assert.Error(err)
+				} else if assert.NoError(err) {
+					// Make the times comparable.
+					for _, ms := range gotms {
+						for i := range ms.Metrics {
+							ms.Metrics[i].TS = ms.Metrics[i].TS.Local()
+						}
+					}
+					assert.Equal(test.expMetricSeries, gotms)
+				}
+			})
+		}
+	}
+}
+
+func TestGathererGatherSingle(t *testing.T) {
+	assert := assert.New(t)
+
+	dir := writeFiles(t)
+	defer os.RemoveAll(dir)
+
+	g, err := file.NewGatherer(file.ConfigGatherer{Path: filepath.Join(dir, "samples.csv")})
+	require.NoError(t, err)
+
+	// Should return the latest sample at the time of each series.
+	gotms, err := g.GatherSingle(context.TODO(), model.Query{Expr: `http_requests{job="api"}`}, ts(1558275700))
+	if assert.NoError(err) && assert.Len(gotms, 2) {
+		assert.Equal([]model.Metric{{TS: ts(1558275660), Value: 3}}, gotms[0].Metrics)
+		if assert.Len(gotms[1].Metrics, 1) {
+			assert.True(ts(1558275660).Equal(gotms[1].Metrics[0].TS))
+			assert.Equal(20.0, gotms[1].Metrics[0].Value)
+		}
+	}
+
+	// Series without samples at the time are ignored.
+	gotms, err = g.GatherSingle(context.TODO(), model.Query{Expr: `http_requests`}, ts(1558275000))
+	if assert.NoError(err) {
+		assert.Len(gotms, 0)
+	}
+}
+
+func TestNewGathererInvalidFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grafterm-file")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	tests := map[string]struct {
+		file    string
+		content string
+	}{
+		"A CSV without value column should fail.":     {file: "a.csv", content: "timestamp,job\n1558275600,api\n"},
+		"A CSV with an invalid value should fail.":    {file: "b.csv", content: "timestamp,value\n1558275600,ten\n"},
+		"A JSON lines with invalid JSON should fail.": {file: "c.jsonl", content: "{\"ts\": 1558275600, \n"},
+		"An unknown file format should fail.":         {file: "d.txt", content: "1558275600 1\n"},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, test.file)
+			require.NoError(t, ioutil.WriteFile(path, []byte(test.content), 0644))
+
+			_, err := file.NewGatherer(file.ConfigGatherer{Path: path})
+			assert.Error(t, err)
+		})
+	}
+}
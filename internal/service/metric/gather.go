@@ -16,3 +16,25 @@ type Gatherer interface {
 	// The returned metrics on the series should be ordered.
 	GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error)
 }
+
+// ServerError is the error of a query that failed because of the datasource
+// server (e.g a 5xx HTTP status code), these errors are transient and the
+// query could succeed if retried.
+type ServerError struct {
+	Err error
+}
+
+func (s *ServerError) Error() string { return s.Err.Error() }
+
+// Unwrap returns the wrapped error.
+func (s *ServerError) Unwrap() error { return s.Err }
+
+// StatusError returns the error of a datasource HTTP API error response,
+// the errors of the server error status codes (5xx) are wrapped with
+// ServerError.
+func StatusError(statusCode int, err error) error {
+	if statusCode >= 500 {
+		return &ServerError{Err: err}
+	}
+	return err
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strings"
 	"time"
 
 	_ "github.com/influxdata/influxdb1-client" // needed due to go mod bug
@@ -54,30 +56,43 @@ func NewGatherer(cfg ConfigGatherer) (metric.Gatherer, error) {
 	}, nil
 }
 
+const (
+	instantRange = 5 * time.Minute
+	instantStep  = 1 * time.Minute
+	minInterval  = 1 * time.Millisecond
+	// columnLabelKey is the label of the series with the column name
+	// when the results have multiple value columns.
+	columnLabelKey = "column"
+)
+
 func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
-	res, err := g.GatherRange(ctx, query, t.Add(-1*5), t, 0)
+	res, err := g.GatherRange(ctx, query, t.Add(-1*instantRange), t, instantStep)
 	if err != nil {
 		return []model.MetricSeries{}, err
 	}
 
-	if len(res) < 1 {
-		return []model.MetricSeries{}, fmt.Errorf("server didn't return any metric series")
-	}
-	if len(res) > 1 {
-		return []model.MetricSeries{}, fmt.Errorf("server returned more than one metric series, got %d", len(res))
+	// Get the latest datapoint of each series.
+	mss := []model.MetricSeries{}
+	for _, ms := range res {
+		if len(ms.Metrics) < 1 {
+			continue
+		}
+		ms.Metrics = ms.Metrics[len(ms.Metrics)-1:]
+		mss = append(mss, ms)
 	}
 
-	// Get the latest datapoint
-	res[0].Metrics = res[0].Metrics[len(res[0].Metrics)-1:]
+	if len(mss) < 1 {
+		return []model.MetricSeries{}, fmt.Errorf("server didn't return any metric series")
+	}
 
-	return res, nil
+	return mss, nil
 }
 
-func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, _ time.Duration) ([]model.MetricSeries, error) {
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
 	res := []model.MetricSeries{}
 
 	// Get the data from the InfluxDB API
-	q := influxdbv2.NewQuery(query.Expr, g.cfg.Database, "ms")
+	q := influxdbv2.NewQuery(renderQuery(query.Expr, start, end, step), g.cfg.Database, "ms")
 	resp, err := g.cli.Query(q)
 	if err != nil {
 		return res, err
@@ -86,46 +101,120 @@ func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, en
 		return res, resp.Error()
 	}
 
-	// Build the metric series
+	// Build the metric series, one per value column of each series.
 	for _, result := range resp.Results {
 		for _, serie := range result.Series {
-			metrics := []model.Metric{}
-			for _, value := range serie.Values {
-				v, err := value[1].(json.Number).Float64()
+			valueColumns := len(serie.Columns) - 1
+			for col := 1; col <= valueColumns; col++ {
+				metrics, err := columnMetrics(serie.Values, col)
 				if err != nil {
 					return res, err
 				}
-				t := time.Time{}
-				switch value[0].(type) {
-				case string:
-					t, err = time.Parse("2006-01-02T15:04:05Z", value[0].(string))
-					if err != nil {
-						return res, err
-					}
-				case json.Number:
-					c, err := value[0].(json.Number).Int64()
-					if err != nil {
-						return res, err
-					}
-					t = time.Unix(c/1000, c%1000)
-				default:
+
+				labels := map[string]string{}
+				for k, v := range serie.Tags {
+					labels[k] = v
 				}
-				m := model.Metric{
-					TS:    t,
-					Value: v,
+				name := serie.Name
+				if valueColumns > 1 {
+					labels[columnLabelKey] = serie.Columns[col]
+					name = fmt.Sprintf("%s.%s", serie.Name, serie.Columns[col])
 				}
-				metrics = append(metrics, m)
+
+				res = append(res, model.MetricSeries{
+					ID:      seriesID(name, serie.Tags),
+					Labels:  labels,
+					Metrics: metrics,
+				})
 			}
-			label := serie.Name
-			//TODO(rochaporto): Allow alias based on a tag value
-			//if serie.Tags != nil {
-			//	if v1, ok := serie.Tags["version"]; ok {
-			//		label = v1
-			//	}
-			//}
-			res = append(res, model.MetricSeries{ID: label, Metrics: metrics})
 		}
 	}
 
 	return res, nil
 }
+
+// columnMetrics returns the metrics of a value column, the first column is the time.
+func columnMetrics(values [][]interface{}, col int) ([]model.Metric, error) {
+	metrics := []model.Metric{}
+	for _, value := range values {
+		if col >= len(value) {
+			continue
+		}
+
+		// Ignore null values (e.g `fill(null)`).
+		n, ok := value[col].(json.Number)
+		if !ok {
+			continue
+		}
+		v, err := n.Float64()
+		if err != nil {
+			return nil, err
+		}
+
+		t := time.Time{}
+		switch ts := value[0].(type) {
+		case string:
+			t, err = time.Parse(time.RFC3339Nano, ts)
+			if err != nil {
+				return nil, err
+			}
+		case json.Number:
+			ms, err := ts.Int64()
+			if err != nil {
+				return nil, err
+			}
+			t = time.Unix(0, ms*int64(time.Millisecond))
+		}
+
+		metrics = append(metrics, model.Metric{
+			TS:    t,
+			Value: v,
+		})
+	}
+
+	return metrics, nil
+}
+
+// seriesID returns the ID of a series based on the name and the tags, e.g:
+// `cpu{host="server1",region="eu"}`.
+func seriesID(name string, tags map[string]string) string {
+	if len(tags) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, fmt.Sprintf("%s=%q", k, tags[k]))
+	}
+
+	return fmt.Sprintf("%s{%s}", name, strings.Join(kvs, ","))
+}
+
+// renderQuery replaces the time range variables of the query with the
+// gather arguments:
+// - `$timeFilter`: the time range condition (e.g `time >= 1558275600000ms and time <= 1558279200000ms`).
+// - `$interval` and `$__interval`: the step as an InfluxDB duration (e.g `30s`).
+func renderQuery(expr string, start, end time.Time, step time.Duration) string {
+	timeFilter := fmt.Sprintf("time >= %dms and time <= %dms", start.UnixNano()/int64(time.Millisecond), end.UnixNano()/int64(time.Millisecond))
+
+	if step < minInterval {
+		step = minInterval
+	}
+	interval := fmt.Sprintf("%dms", step/time.Millisecond)
+	if step%time.Second == 0 {
+		interval = fmt.Sprintf("%ds", step/time.Second)
+	}
+
+	r := strings.NewReplacer(
+		"$timeFilter", timeFilter,
+		"$__interval", interval,
+		"$interval", interval,
+	)
+	return r.Replace(expr)
+}
@@ -34,7 +34,8 @@ func TestGathererGatherSingle(t *testing.T) {
 ]}`,
 			expMetricSeries: []model.MetricSeries{
 				{
-					ID: "myseries",
+					ID:     "myseries",
+					Labels: map[string]string{},
 					Metrics: []model.Metric{
 						{
 							Value: 45.67,
@@ -83,9 +84,15 @@ func TestGathererGatherSingle(t *testing.T) {
 }
 
 func TestGathererGatherRange(t *testing.T) {
+	start := time.Date(2017, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := time.Date(2017, 3, 1, 1, 0, 0, 0, time.UTC)
+
 	tests := map[string]struct {
 		influxdbResponse string
 		cfg              influxdb.ConfigGatherer
+		query            string
+		step             time.Duration
+		expQuery         string
 		expMetricSeries  []model.MetricSeries
 		expErr           bool
 	}{
@@ -101,14 +108,16 @@ func TestGathererGatherRange(t *testing.T) {
 ]}`,
 			expMetricSeries: []model.MetricSeries{
 				{
-					ID: "myseries1",
+					ID:     "myseries1",
+					Labels: map[string]string{},
 					Metrics: []model.Metric{
 						{Value: 12.34, TS: time.Date(2017, 3, 1, 0, 16, 18, 0, time.UTC)},
 						{Value: 23.45, TS: time.Date(2017, 3, 1, 0, 17, 18, 0, time.UTC)},
 					},
 				},
 				{
-					ID: "myseries2",
+					ID:     "myseries2",
+					Labels: map[string]string{},
 					Metrics: []model.Metric{
 						{Value: 34.56, TS: time.Date(2017, 3, 1, 0, 18, 18, 0, time.UTC)},
 						{Value: 45.67, TS: time.Date(2017, 3, 1, 0, 19, 18, 0, time.UTC)},
@@ -116,6 +125,72 @@ func TestGathererGatherRange(t *testing.T) {
 				},
 			},
 		},
+		"Time range variables on the query should be replaced with the gather arguments.": {
+			query:    "SELECT mean(value) FROM cpu WHERE $timeFilter GROUP BY time($interval), time($__interval)",
+			step:     30 * time.Second,
+			expQuery: "SELECT mean(value) FROM cpu WHERE time >= 1488326400000ms and time <= 1488330000000ms GROUP BY time(30s), time(30s)",
+			influxdbResponse: `
+{"results":[
+  {
+  "series": [
+     {"name":"cpu","columns":["time","mean"],"values":[[1488326400000,12.34],[1488326430000,null]]}
+  ]
+  }
+]}`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     "cpu",
+					Labels: map[string]string{},
+					Metrics: []model.Metric{
+						{Value: 12.34, TS: time.Unix(1488326400, 0)},
+					},
+				},
+			},
+		},
+		"Series tags should be the labels of the series and multiple columns should be different series.": {
+			step: 500 * time.Millisecond,
+			influxdbResponse: `
+{"results":[
+  {
+  "series": [
+     {"name":"cpu","tags":{"host":"server1"},"columns":["time","mean","max"],"values":[[1488326400000,12.34,20],[1488326430000,23.45,30]]},
+     {"name":"cpu","tags":{"host":"server2"},"columns":["time","mean","max"],"values":[[1488326400000,1,2]]}
+  ]
+  }
+]}`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `cpu.mean{host="server1"}`,
+					Labels: map[string]string{"host": "server1", "column": "mean"},
+					Metrics: []model.Metric{
+						{Value: 12.34, TS: time.Unix(1488326400, 0)},
+						{Value: 23.45, TS: time.Unix(1488326430, 0)},
+					},
+				},
+				{
+					ID:     `cpu.max{host="server1"}`,
+					Labels: map[string]string{"host": "server1", "column": "max"},
+					Metrics: []model.Metric{
+						{Value: 20, TS: time.Unix(1488326400, 0)},
+						{Value: 30, TS: time.Unix(1488326430, 0)},
+					},
+				},
+				{
+					ID:     `cpu.mean{host="server2"}`,
+					Labels: map[string]string{"host": "server2", "column": "mean"},
+					Metrics: []model.Metric{
+						{Value: 1, TS: time.Unix(1488326400, 0)},
+					},
+				},
+				{
+					ID:     `cpu.max{host="server2"}`,
+					Labels: map[string]string{"host": "server2", "column": "max"},
+					Metrics: []model.Metric{
+						{Value: 2, TS: time.Unix(1488326400, 0)},
+					},
+				},
+			},
+		},
 	}
 
 	for name, test := range tests {
@@ -123,8 +198,10 @@ func TestGathererGatherRange(t *testing.T) {
 			assert := assert.New(t)
 
 			// Mock server response.
+			var gotQuery string
 			srv := httptest.NewServer(
 				http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					gotQuery = r.FormValue("q")
 					w.Header().Set("Content-Type", "application/json")
 					w.Write([]byte(test.influxdbResponse))
 				}))
@@ -135,10 +212,11 @@ func TestGathererGatherRange(t *testing.T) {
 			test.cfg.Database = "dummy"
 
 			g, _ := influxdb.NewGatherer(test.cfg)
-			gotms, err := g.GatherRange(context.TODO(), model.Query{}, time.Now(), time.Now(), 0)
+			gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: test.query}, start, end, test.step)
 			if test.expErr {
 				assert.Error(err)
 			} else if assert.NoError(err) {
+				assert.Equal(test.expQuery, gotQuery)
 				// We don't control the order of the MetricSeries and sorting is harder than checking in
 				// two steps.
 				assert.Len(gotms, len(test.expMetricSeries))
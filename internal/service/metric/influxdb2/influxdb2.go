@@ -0,0 +1,336 @@
+package influxdb2
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+const (
+	instantRange = 5 * time.Minute
+	instantStep  = 1 * time.Minute
+	minInterval  = 1 * time.Millisecond
+
+	annotationDatatype = "#datatype"
+	annotationGroup    = "#group"
+	annotationDefault  = "#default"
+
+	columnResult = "result"
+	columnTable  = "table"
+	columnStart  = "_start"
+	columnStop   = "_stop"
+	columnTime   = "_time"
+	columnValue  = "_value"
+	columnError  = "error"
+)
+
+// ConfigGatherer is the configuration of the InfluxDB 2 gatherer.
+type ConfigGatherer struct {
+	// Address is the address of the InfluxDB 2 API.
+	Address string
+	Org     string
+	// Bucket is the bucket that the queries can use as `v.bucket`.
+	Bucket  string
+	Token   string
+	HTTPCli *http.Client
+}
+
+func (c *ConfigGatherer) defaults() error {
+	if c.Org == "" {
+		return fmt.Errorf("no influxdb org given")
+	}
+
+	if c.HTTPCli == nil {
+		c.HTTPCli = http.DefaultClient
+	}
+
+	return nil
+}
+
+type gatherer struct {
+	cfg      ConfigGatherer
+	queryURL string
+}
+
+// NewGatherer returns a new metric gatherer for InfluxDB 2 backends using Flux
+// queries.
+func NewGatherer(cfg ConfigGatherer) (metric.Gatherer, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, err
+	}
+
+	u, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, "/api/v2/query")
+	u.RawQuery = url.Values{"org": []string{cfg.Org}}.Encode()
+
+	return &gatherer{
+		cfg:      cfg,
+		queryURL: u.String(),
+	}, nil
+}
+
+func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	res, err := g.GatherRange(ctx, query, t.Add(-1*instantRange), t, instantStep)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// Get the latest datapoint of each series.
+	mss := []model.MetricSeries{}
+	for _, ms := range res {
+		if len(ms.Metrics) < 1 {
+			continue
+		}
+		ms.Metrics = ms.Metrics[len(ms.Metrics)-1:]
+		mss = append(mss, ms)
+	}
+
+	if len(mss) < 1 {
+		return []model.MetricSeries{}, fmt.Errorf("server didn't return any metric series")
+	}
+
+	return mss, nil
+}
+
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	body, err := json.Marshal(map[string]interface{}{
+		"query": g.fluxQuery(query.Expr, start, end, step),
+		"type":  "flux",
+		"dialect": map[string]interface{}{
+			"header":      true,
+			"annotations": []string{"datatype", "group", "default"},
+		},
+	})
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.queryURL, bytes.NewReader(body))
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/csv")
+	if g.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+g.cfg.Token)
+	}
+
+	resp, err := g.cfg.HTTPCli.Do(req)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		rbody, _ := ioutil.ReadAll(resp.Body)
+		apiErr := struct {
+			Message string `json:"message"`
+		}{}
+		if json.Unmarshal(rbody, &apiErr) == nil && apiErr.Message != "" {
+			return []model.MetricSeries{}, metric.StatusError(resp.StatusCode, fmt.Errorf("InfluxDB error (%d): %s", resp.StatusCode, apiErr.Message))
+		}
+		return []model.MetricSeries{}, metric.StatusError(resp.StatusCode, fmt.Errorf("InfluxDB error (%d): %s", resp.StatusCode, strings.TrimSpace(string(rbody))))
+	}
+
+	return parseAnnotatedCSV(resp.Body)
+}
+
+// fluxQuery sets the `v` option on the Flux query with the gather arguments:
+// - `v.timeRangeStart` and `v.timeRangeStop`: the time range.
+// - `v.windowPeriod`: the step as a duration (e.g `30s`).
+// - `v.bucket`: the bucket of the datasource.
+func (g *gatherer) fluxQuery(expr string, start, end time.Time, step time.Duration) string {
+	if step < minInterval {
+		step = minInterval
+	}
+	window := fmt.Sprintf("%dms", step/time.Millisecond)
+	if step%time.Second == 0 {
+		window = fmt.Sprintf("%ds", step/time.Second)
+	}
+
+	option := fmt.Sprintf("option v = {timeRangeStart: %s, timeRangeStop: %s, windowPeriod: %s, bucket: %q}",
+		start.UTC().Format(time.RFC3339Nano),
+		end.UTC().Format(time.RFC3339Nano),
+		window,
+		g.cfg.Bucket)
+
+	return option + "\n" + expr
+}
+
+// table is a table of the annotated CSV response.
+type table struct {
+	groups   []string
+	defaults []string
+	header   []string
+}
+
+// parseAnnotatedCSV parses a Flux annotated CSV response, every Flux table
+// (result and table columns) will be a series. The labels of the series are
+// the group key columns of the table.
+func parseAnnotatedCSV(r io.Reader) ([]model.MetricSeries, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+
+	res := []model.MetricSeries{}
+	index := map[string]int{}
+	var tbl *table
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid InfluxDB CSV response: %s", err)
+		}
+
+		// Annotations start a new table schema.
+		switch record[0] {
+		case annotationDatatype:
+			tbl = &table{}
+			continue
+		case annotationGroup:
+			if tbl != nil {
+				tbl.groups = record
+			}
+			continue
+		case annotationDefault:
+			if tbl != nil {
+				tbl.defaults = record
+			}
+			continue
+		}
+		if tbl == nil {
+			return nil, fmt.Errorf("invalid InfluxDB CSV response: missing annotations")
+		}
+
+		// The first row after the annotations is the header.
+		if tbl.header == nil {
+			tbl.header = record
+			continue
+		}
+
+		row := tbl.row(record)
+		if msg, ok := row[columnError]; ok {
+			return nil, fmt.Errorf("InfluxDB query error: %s", msg)
+		}
+
+		labels := tbl.labels(row)
+		key := row[columnResult] + "/" + row[columnTable]
+		i, ok := index[key]
+		if !ok {
+			i = len(res)
+			index[key] = i
+			res = append(res, model.MetricSeries{
+				ID:      seriesID(labels),
+				Labels:  labels,
+				Metrics: []model.Metric{},
+			})
+		}
+
+		m, ok, err := rowMetric(row)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			res[i].Metrics = append(res[i].Metrics, m)
+		}
+	}
+
+	return res, nil
+}
+
+// row returns the row values by column name with the default values applied.
+func (t *table) row(record []string) map[string]string {
+	row := map[string]string{}
+	for i, c := range t.header {
+		if i == 0 || i >= len(record) {
+			continue
+		}
+
+		v := record[i]
+		if v == "" && i < len(t.defaults) {
+			v = t.defaults[i]
+		}
+		row[c] = v
+	}
+
+	return row
+}
+
+// labels returns the group key of the table without the time range columns.
+func (t *table) labels(row map[string]string) map[string]string {
+	labels := map[string]string{}
+	for i, c := range t.header {
+		if i >= len(t.groups) || t.groups[i] != "true" {
+			continue
+		}
+
+		switch c {
+		case columnResult, columnTable, columnStart, columnStop, columnTime, columnValue:
+			continue
+		}
+		labels[c] = row[c]
+	}
+
+	return labels
+}
+
+// rowMetric returns the metric of a row, rows without a numeric value are ignored.
+func rowMetric(row map[string]string) (model.Metric, bool, error) {
+	value, ok := row[columnValue]
+	if !ok || value == "" {
+		return model.Metric{}, false, nil
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return model.Metric{}, false, nil
+	}
+
+	// Aggregated tables without time use the end of the time range.
+	ts, ok := row[columnTime]
+	if !ok || ts == "" {
+		ts = row[columnStop]
+	}
+	t, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return model.Metric{}, false, fmt.Errorf("invalid InfluxDB time: %s", err)
+	}
+
+	return model.Metric{TS: t, Value: v}, true, nil
+}
+
+// seriesID returns the ID of a series based on the labels, e.g:
+// `{_field="usage",_measurement="cpu",host="server1"}`.
+func seriesID(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(kvs, ","))
+}
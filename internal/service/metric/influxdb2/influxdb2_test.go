@@ -0,0 +1,147 @@
+package influxdb2_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/influxdb2"
+)
+
+const cpuResponse = `#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,dateTime:RFC3339,double,string,string,string
+#group,false,false,true,true,false,false,true,true,true
+#default,_result,,,,,,,,
+,result,table,_start,_stop,_time,_value,_field,_measurement,host
+,,0,2019-05-19T14:00:00Z,2019-05-19T15:00:00Z,2019-05-19T14:00:00Z,12.5,usage,cpu,server1
+,,0,2019-05-19T14:00:00Z,2019-05-19T15:00:00Z,2019-05-19T14:30:00Z,20,usage,cpu,server1
+,,1,2019-05-19T14:00:00Z,2019-05-19T15:00:00Z,2019-05-19T14:00:00Z,3,usage,cpu,server2
+
+#datatype,string,long,dateTime:RFC3339,dateTime:RFC3339,long,string
+#group,false,false,true,true,false,true
+#default,_result,,,,,
+,result,table,_start,_stop,_value,_measurement
+,,2,2019-05-19T14:00:00Z,2019-05-19T15:00:00Z,42,mem
+`
+
+func TestGathererGatherRange(t *testing.T) {
+	start := time.Date(2019, 5, 19, 14, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 5, 19, 15, 0, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		cfg             influxdb2.ConfigGatherer
+		query           string
+		step            time.Duration
+		status          int
+		response        string
+		expQuery        string
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"Annotated CSV tables should be returned as series with the group key as labels.": {
+			cfg:      influxdb2.ConfigGatherer{Org: "my-org", Bucket: "telegraf", Token: "secret"},
+			query:    `from(bucket: v.bucket) |> range(start: v.timeRangeStart, stop: v.timeRangeStop) |> aggregateWindow(every: v.windowPeriod, fn: mean)`,
+			step:     30 * time.Second,
+			response: cpuResponse,
+			expQuery: `option v = {timeRangeStart: 2019-05-19T14:00:00Z, timeRangeStop: 2019-05-19T15:00:00Z, windowPeriod: 30s, bucket: "telegraf"}
+from(bucket: v.bucket) |> range(start: v.timeRangeStart, stop: v.timeRangeStop) |> aggregateWindow(every: v.windowPeriod, fn: mean)`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `{_field="usage",_measurement="cpu",host="server1"}`,
+					Labels: map[string]string{"_field": "usage", "_measurement": "cpu", "host": "server1"},
+					Metrics: []model.Metric{
+						{TS: time.Date(2019, 5, 19, 14, 0, 0, 0, time.UTC), Value: 12.5},
+						{TS: time.Date(2019, 5, 19, 14, 30, 0, 0, time.UTC), Value: 20},
+					},
+				},
+				{
+					ID:     `{_field="usage",_measurement="cpu",host="server2"}`,
+					Labels: map[string]string{"_field": "usage", "_measurement": "cpu", "host": "server2"},
+					Metrics: []model.Metric{
+						{TS: time.Date(2019, 5, 19, 14, 0, 0, 0, time.UTC), Value: 3},
+					},
+				},
+				{
+					ID:     `{_measurement="mem"}`,
+					Labels: map[string]string{"_measurement": "mem"},
+					Metrics: []model.Metric{
+						{TS: time.Date(2019, 5, 19, 15, 0, 0, 0, time.UTC), Value: 42},
+					},
+				},
+			},
+		},
+		"A Flux error table should fail.": {
+			cfg: influxdb2.ConfigGatherer{Org: "my-org"},
+			response: `#datatype,string,string
+#group,true,true
+#default,,
+,error,reference
+,failed to execute query,
+`,
+			expErr: true,
+		},
+		"An error response should fail.": {
+			cfg:      influxdb2.ConfigGatherer{Org: "my-org"},
+			status:   http.StatusUnauthorized,
+			response: `{"code":"unauthorized","message":"unauthorized access"}`,
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mock server response.
+			var gotQuery, gotOrg, gotAuth string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body := struct {
+					Query string `json:"query"`
+				}{}
+				json.NewDecoder(r.Body).Decode(&body)
+				gotQuery = body.Query
+				gotOrg = r.URL.Query().Get("org")
+				gotAuth = r.Header.Get("Authorization")
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+				}
+				w.Write([]byte(test.response))
+			}))
+			defer srv.Close()
+			test.cfg.Address = srv.URL
+
+			g, err := influxdb2.NewGatherer(test.cfg)
+			assert.NoError(err)
+			gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: test.query}, start, end, test.step)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expQuery, gotQuery)
+				assert.Equal(test.cfg.Org, gotOrg)
+				assert.Equal("Token "+test.cfg.Token, gotAuth)
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+		})
+	}
+}
+
+func TestGathererGatherSingle(t *testing.T) {
+	assert := assert.New(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(cpuResponse))
+	}))
+	defer srv.Close()
+
+	g, err := influxdb2.NewGatherer(influxdb2.ConfigGatherer{Address: srv.URL, Org: "my-org"})
+	assert.NoError(err)
+	gotms, err := g.GatherSingle(context.TODO(), model.Query{}, time.Now())
+	if assert.NoError(err) && assert.Len(gotms, 3) {
+		assert.Equal([]model.Metric{{TS: time.Date(2019, 5, 19, 14, 30, 0, 0, time.UTC), Value: 20}}, gotms[0].Metrics)
+	}
+}
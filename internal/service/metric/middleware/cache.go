@@ -0,0 +1,179 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+// cacheKey identifies the queries that return the same result. The times
+// are aligned (to the step on range queries and to the TTL on single
+// queries) so the queries of consecutive syncs share the key.
+type cacheKey struct {
+	single       bool
+	datasourceID string
+	expr         string
+	start        int64
+	end          int64
+	step         time.Duration
+}
+
+type cacheEntry struct {
+	series  []model.MetricSeries
+	expires time.Time
+}
+
+// cacheCall is a query in flight that can be shared with the identical queries.
+type cacheCall struct {
+	ctx    context.Context
+	done   chan struct{}
+	series []model.MetricSeries
+	err    error
+}
+
+type cache struct {
+	ttl     time.Duration
+	next    metric.Gatherer
+	logger  log.Logger
+	entries map[cacheKey]cacheEntry
+	calls   map[cacheKey]*cacheCall
+	hits    int
+	misses  int
+	mu      sync.Mutex
+}
+
+// Cache is a gatherer middleware that wraps the real gatherer, deduplicates
+// the identical queries that are in flight (same datasource, expression,
+// time range and step) and caches the successful results for the TTL. The
+// time ranges are aligned to the step, so the queries whose range only moved
+// inside the same step (e.g the same query of the next sync) are identical.
+// The TTL should be bounded by the refresh interval so the refreshes get
+// fresh data, a TTL of 0 will only deduplicate the queries in flight.
+func Cache(ttl time.Duration, l log.Logger, next metric.Gatherer) metric.Gatherer {
+	return &cache{
+		ttl:     ttl,
+		next:    next,
+		logger:  l,
+		entries: map[cacheKey]cacheEntry{},
+		calls:   map[cacheKey]*cacheCall{},
+	}
+}
+
+func (c *cache) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	key := cacheKey{
+		single:       true,
+		datasourceID: query.DatasourceID,
+		expr:         query.Expr,
+		end:          alignTime(t, c.ttl),
+	}
+	return c.gather(ctx, key, func(ctx context.Context) ([]model.MetricSeries, error) {
+		return c.next.GatherSingle(ctx, query, t)
+	})
+}
+
+func (c *cache) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	key := cacheKey{
+		datasourceID: query.DatasourceID,
+		expr:         query.Expr,
+		start:        alignTime(start, step),
+		end:          alignTime(end, step),
+		step:         step,
+	}
+	return c.gather(ctx, key, func(ctx context.Context) ([]model.MetricSeries, error) {
+		return c.next.GatherRange(ctx, query, start, end, step)
+	})
+}
+
+func (c *cache) gather(ctx context.Context, key cacheKey, gather func(ctx context.Context) ([]model.MetricSeries, error)) ([]model.MetricSeries, error) {
+	c.mu.Lock()
+	c.purge()
+
+	// Cached result.
+	if e, ok := c.entries[key]; ok {
+		c.hits++
+		c.logger.Infof("query cache hit on %s (hits: %d, misses: %d): %s", key.datasourceID, c.hits, c.misses, key.expr)
+		c.mu.Unlock()
+		return copySeries(e.series), nil
+	}
+
+	// Identical query in flight, wait for its result.
+	if call, ok := c.calls[key]; ok {
+		c.hits++
+		c.logger.Infof("query cache in flight hit on %s (hits: %d, misses: %d): %s", key.datasourceID, c.hits, c.misses, key.expr)
+		c.mu.Unlock()
+
+		select {
+		case <-call.done:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+
+		// If the shared query has been cancelled by its context it doesn't
+		// mean ours needs to fail.
+		if call.err != nil && call.ctx.Err() != nil && ctx.Err() == nil {
+			return gather(ctx)
+		}
+		return copySeries(call.series), call.err
+	}
+
+	c.misses++
+	c.logger.Infof("query cache miss on %s (hits: %d, misses: %d): %s", key.datasourceID, c.hits, c.misses, key.expr)
+	call := &cacheCall{
+		ctx:  ctx,
+		done: make(chan struct{}),
+	}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	call.series, call.err = gather(ctx)
+
+	c.mu.Lock()
+	delete(c.calls, key)
+	if call.err == nil && c.ttl > 0 {
+		c.entries[key] = cacheEntry{
+			series:  call.series,
+			expires: time.Now().Add(c.ttl),
+		}
+	}
+	c.mu.Unlock()
+	close(call.done)
+
+	return copySeries(call.series), call.err
+}
+
+// alignTime returns the time truncated to a multiple of the duration.
+func alignTime(t time.Time, d time.Duration) int64 {
+	if d <= 0 {
+		return t.UnixNano()
+	}
+	return t.Truncate(d).UnixNano()
+}
+
+// purge removes the expired entries, must be called with the lock acquired.
+func (c *cache) purge() {
+	now := time.Now()
+	for k, e := range c.entries {
+		if !now.Before(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+}
+
+// copySeries copies the series and its metrics so the users of the
+// shared results can't modify the results of the others.
+func copySeries(series []model.MetricSeries) []model.MetricSeries {
+	if series == nil {
+		return nil
+	}
+
+	res := make([]model.MetricSeries, len(series))
+	for i, s := range series {
+		res[i] = s
+		res[i].Metrics = append([]model.Metric(nil), s.Metrics...)
+	}
+	return res
+}
@@ -0,0 +1,123 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mmetric "github.com/slok/grafterm/internal/mocks/service/metric"
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/service/metric/middleware"
+)
+
+func TestCache(t *testing.T) {
+	t0 := time.Now().Truncate(time.Minute)
+	q0 := model.Query{DatasourceID: "ds0", Expr: "up"}
+	q1 := model.Query{DatasourceID: "ds1", Expr: "up"}
+	series := []model.MetricSeries{{ID: "up", Metrics: []model.Metric{{TS: t0, Value: 1}}}}
+
+	type gather struct {
+		query model.Query
+		start time.Time
+		sleep time.Duration
+	}
+
+	tests := map[string]struct {
+		ttl     time.Duration
+		gathers []gather
+		err     error
+		expCall map[string]int
+		expErr  bool
+	}{
+		"Identical queries should be cached.": {
+			ttl:     time.Minute,
+			gathers: []gather{{query: q0, start: t0}, {query: q0, start: t0}, {query: q0, start: t0}},
+			expCall: map[string]int{"ds0": 1},
+		},
+		"Queries with different datasource or time range shouldn't be cached.": {
+			ttl:     time.Minute,
+			gathers: []gather{{query: q0, start: t0}, {query: q1, start: t0}, {query: q0, start: t0.Add(time.Minute)}},
+			expCall: map[string]int{"ds0": 2, "ds1": 1},
+		},
+		"Queries of consecutive syncs with the time range inside the same step should be cached.": {
+			ttl:     time.Minute,
+			gathers: []gather{{query: q0, start: t0}, {query: q0, start: t0.Add(10 * time.Second)}, {query: q0, start: t0.Add(20 * time.Second)}},
+			expCall: map[string]int{"ds0": 1},
+		},
+		"Queries without TTL shouldn't be cached.": {
+			gathers: []gather{{query: q0, start: t0}, {query: q0, start: t0}},
+			expCall: map[string]int{"ds0": 2},
+		},
+		"Queries should be cached only for the TTL.": {
+			ttl:     10 * time.Millisecond,
+			gathers: []gather{{query: q0, start: t0}, {query: q0, start: t0, sleep: 20 * time.Millisecond}},
+			expCall: map[string]int{"ds0": 2},
+		},
+		"Failed queries shouldn't be cached.": {
+			ttl:     time.Minute,
+			gathers: []gather{{query: q0, start: t0}, {query: q0, start: t0}},
+			err:     errors.New("wanted"),
+			expCall: map[string]int{"ds0": 2},
+			expErr:  true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mg := &mmetric.Gatherer{}
+			for id, n := range test.expCall {
+				mg.On("GatherRange", mock.Anything, mock.MatchedBy(func(q model.Query) bool { return q.DatasourceID == id }), mock.Anything, mock.Anything, mock.Anything).
+					Times(n).Return(series, test.err)
+			}
+
+			g := middleware.Cache(test.ttl, log.Dummy, mg)
+			for _, gt := range test.gathers {
+				time.Sleep(gt.sleep)
+				got, err := g.GatherRange(context.TODO(), gt.query, gt.start, gt.start.Add(time.Hour), time.Minute)
+				if test.expErr {
+					assert.Error(err)
+				} else if assert.NoError(err) {
+					assert.Equal(series, got)
+				}
+			}
+
+			mg.AssertExpectations(t)
+		})
+	}
+}
+
+func TestCacheInFlight(t *testing.T) {
+	assert := assert.New(t)
+
+	t0 := time.Now()
+	q := model.Query{DatasourceID: "ds0", Expr: "up"}
+	series := []model.MetricSeries{{ID: "up", Metrics: []model.Metric{{TS: t0, Value: 1}}}}
+
+	// The query will be in flight until all the queries have been made.
+	mg := &mmetric.Gatherer{}
+	mg.On("GatherSingle", mock.Anything, q, t0).Once().After(100*time.Millisecond).Return(series, nil)
+
+	g := middleware.Cache(0, log.Dummy, mg)
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			got, err := g.GatherSingle(context.TODO(), q, t0)
+			if assert.NoError(err) {
+				assert.Equal(series, got)
+			}
+		}()
+	}
+	wg.Wait()
+
+	mg.AssertExpectations(t)
+}
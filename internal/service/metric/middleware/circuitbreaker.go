@@ -0,0 +1,139 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+	"github.com/slok/grafterm/internal/service/unit"
+)
+
+// ErrCircuitOpen is the error returned when the circuit of a datasource
+// is open and the queries are not made.
+var ErrCircuitOpen = errors.New("circuit open")
+
+// CircuitBreakerConfig is the configuration of the CircuitBreaker middleware.
+type CircuitBreakerConfig struct {
+	// Failures is the number of consecutive failed queries of a datasource
+	// that will open its circuit, 0 disables the circuit breaker.
+	Failures int
+	// OpenTimeout is the time the circuit will be open, after this time
+	// a query will be made to check if the datasource has recovered.
+	OpenTimeout time.Duration
+}
+
+// circuit is the circuit breaker state of a datasource.
+type circuit struct {
+	failures  int
+	openUntil time.Time
+	// probing is true when a query is checking if the datasource has recovered.
+	probing bool
+}
+
+type circuitBreaker struct {
+	cfg      CircuitBreakerConfig
+	next     metric.Gatherer
+	circuits map[string]*circuit
+	mu       sync.Mutex
+}
+
+// CircuitBreaker is a gatherer middleware that wraps the real gatherer and
+// opens the circuit of a datasource after multiple consecutive failed
+// queries. While the circuit is open the queries to the datasource fail
+// fast with ErrCircuitOpen, once the open timeout is reached a single query
+// is made and depending on its result the circuit will be closed or opened
+// again.
+func CircuitBreaker(cfg CircuitBreakerConfig, next metric.Gatherer) metric.Gatherer {
+	return &circuitBreaker{
+		cfg:      cfg,
+		next:     next,
+		circuits: map[string]*circuit{},
+	}
+}
+
+func (c *circuitBreaker) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	probe, err := c.allow(query.DatasourceID)
+	if err != nil {
+		return nil, err
+	}
+	ms, err := c.next.GatherSingle(ctx, query, t)
+	c.record(ctx, query.DatasourceID, probe, err)
+	return ms, err
+}
+
+func (c *circuitBreaker) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	probe, err := c.allow(query.DatasourceID)
+	if err != nil {
+		return nil, err
+	}
+	ms, err := c.next.GatherRange(ctx, query, start, end, step)
+	c.record(ctx, query.DatasourceID, probe, err)
+	return ms, err
+}
+
+// allow returns an error if the query to the datasource is not allowed, if
+// the query is allowed it returns true when the query is the one that checks
+// if the datasource has recovered.
+func (c *circuitBreaker) allow(id string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cc := c.get(id)
+	if c.cfg.Failures <= 0 || cc.failures < c.cfg.Failures {
+		return false, nil
+	}
+
+	// Open circuit, only one query can check if the datasource has recovered.
+	wait := time.Until(cc.openUntil)
+	if wait > 0 || cc.probing {
+		if wait < 0 {
+			wait = 0
+		}
+		return false, fmt.Errorf("%s datasource %w after %d failed queries, retrying in %s", id, ErrCircuitOpen, cc.failures, unit.DurationToSimpleString(wait))
+	}
+	cc.probing = true
+
+	return true, nil
+}
+
+// record records the result of a query, only the probe query ends the
+// probing, the queries made before opening the circuit can end while the
+// probe is in flight.
+func (c *circuitBreaker) record(ctx context.Context, id string, probe bool, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	cc := c.get(id)
+	if probe {
+		cc.probing = false
+	}
+
+	// Cancelled queries don't say anything about the datasource.
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+
+	if err == nil {
+		cc.failures = 0
+		return
+	}
+
+	cc.failures++
+	if cc.failures >= c.cfg.Failures {
+		cc.openUntil = time.Now().Add(c.cfg.OpenTimeout)
+	}
+}
+
+// get returns the circuit of a datasource, must be called with the lock acquired.
+func (c *circuitBreaker) get(id string) *circuit {
+	cc, ok := c.circuits[id]
+	if !ok {
+		cc = &circuit{}
+		c.circuits[id] = cc
+	}
+	return cc
+}
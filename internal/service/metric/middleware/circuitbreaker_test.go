@@ -0,0 +1,171 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mmetric "github.com/slok/grafterm/internal/mocks/service/metric"
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/middleware"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	errWanted := errors.New("wanted")
+	q0 := model.Query{DatasourceID: "ds0"}
+	q1 := model.Query{DatasourceID: "ds1"}
+
+	type gather struct {
+		query        model.Query
+		err          error
+		sleep        time.Duration
+		expCall      bool
+		expOpenError bool
+	}
+
+	tests := map[string]struct {
+		cfg     middleware.CircuitBreakerConfig
+		gathers []gather
+	}{
+		"Consecutive failed queries should open the circuit of the datasource.": {
+			cfg: middleware.CircuitBreakerConfig{Failures: 2, OpenTimeout: time.Minute},
+			gathers: []gather{
+				{query: q0, err: errWanted, expCall: true},
+				{query: q0, err: errWanted, expCall: true},
+				{query: q0, expOpenError: true},
+				{query: q1, expCall: true},
+			},
+		},
+		"Successful queries should reset the failed queries.": {
+			cfg: middleware.CircuitBreakerConfig{Failures: 2, OpenTimeout: time.Minute},
+			gathers: []gather{
+				{query: q0, err: errWanted, expCall: true},
+				{query: q0, expCall: true},
+				{query: q0, err: errWanted, expCall: true},
+				{query: q0, expCall: true},
+			},
+		},
+		"After the open timeout a successful query should close the circuit.": {
+			cfg: middleware.CircuitBreakerConfig{Failures: 1, OpenTimeout: 10 * time.Millisecond},
+			gathers: []gather{
+				{query: q0, err: errWanted, expCall: true},
+				{query: q0, expOpenError: true},
+				{query: q0, sleep: 20 * time.Millisecond, expCall: true},
+				{query: q0, expCall: true},
+			},
+		},
+		"After the open timeout a failed query should open the circuit again.": {
+			cfg: middleware.CircuitBreakerConfig{Failures: 1, OpenTimeout: 10 * time.Millisecond},
+			gathers: []gather{
+				{query: q0, err: errWanted, expCall: true},
+				{query: q0, sleep: 20 * time.Millisecond, err: errWanted, expCall: true},
+				{query: q0, expOpenError: true},
+			},
+		},
+		"Without failures the circuit breaker should be disabled.": {
+			cfg: middleware.CircuitBreakerConfig{},
+			gathers: []gather{
+				{query: q0, err: errWanted, expCall: true},
+				{query: q0, err: errWanted, expCall: true},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mg := &mmetric.Gatherer{}
+			g := middleware.CircuitBreaker(test.cfg, mg)
+			for i, gt := range test.gathers {
+				time.Sleep(gt.sleep)
+				calls := len(mg.Calls)
+				if gt.expCall {
+					mg.On("GatherSingle", mock.Anything, gt.query, mock.Anything).Once().Return(nil, gt.err)
+				}
+
+				_, err := g.GatherSingle(context.TODO(), gt.query, time.Now())
+				switch {
+				case gt.expOpenError:
+					assert.True(errors.Is(err, middleware.ErrCircuitOpen), "gather %d should fail with an open circuit", i)
+				case gt.err != nil:
+					assert.Equal(gt.err, err, "gather %d", i)
+				default:
+					assert.NoError(err, "gather %d", i)
+				}
+
+				if gt.expCall {
+					assert.Len(mg.Calls, calls+1, "gather %d should be made", i)
+				} else {
+					assert.Len(mg.Calls, calls, "gather %d shouldn't be made", i)
+				}
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	assert := assert.New(t)
+
+	errWanted := errors.New("wanted")
+	qInFlight := model.Query{DatasourceID: "ds0", Expr: "in-flight"}
+	qFail := model.Query{DatasourceID: "ds0", Expr: "fail"}
+	qProbe := model.Query{DatasourceID: "ds0", Expr: "probe"}
+
+	block := func(started, release chan struct{}) func(mock.Arguments) {
+		return func(mock.Arguments) {
+			started <- struct{}{}
+			<-release
+		}
+	}
+	inFlightStarted, inFlightRelease := make(chan struct{}), make(chan struct{})
+	probeStarted, probeRelease := make(chan struct{}), make(chan struct{})
+
+	mg := &mmetric.Gatherer{}
+	mg.On("GatherSingle", mock.Anything, qInFlight, mock.Anything).Once().Run(block(inFlightStarted, inFlightRelease)).Return(nil, context.Canceled)
+	mg.On("GatherSingle", mock.Anything, qFail, mock.Anything).Twice().Return(nil, errWanted)
+	mg.On("GatherSingle", mock.Anything, qProbe, mock.Anything).Once().Run(block(probeStarted, probeRelease)).Return(nil, nil)
+	g := middleware.CircuitBreaker(middleware.CircuitBreakerConfig{Failures: 2, OpenTimeout: 10 * time.Millisecond}, mg)
+
+	// A query in flight since before the circuit is open.
+	ctx, cancel := context.WithCancel(context.Background())
+	inFlightDone := make(chan struct{})
+	go func() {
+		g.GatherSingle(ctx, qInFlight, time.Now())
+		close(inFlightDone)
+	}()
+	<-inFlightStarted
+
+	// Open the circuit and wait to the open timeout.
+	g.GatherSingle(context.TODO(), qFail, time.Now())
+	g.GatherSingle(context.TODO(), qFail, time.Now())
+	time.Sleep(20 * time.Millisecond)
+
+	// Start the probe.
+	probeDone := make(chan error)
+	go func() {
+		_, err := g.GatherSingle(context.TODO(), qProbe, time.Now())
+		probeDone <- err
+	}()
+	<-probeStarted
+
+	// Ending the query that isn't the probe shouldn't allow another probe.
+	cancel()
+	close(inFlightRelease)
+	<-inFlightDone
+	_, err := g.GatherSingle(context.TODO(), qProbe, time.Now())
+	assert.True(errors.Is(err, middleware.ErrCircuitOpen))
+
+	// A successful probe should close the circuit.
+	close(probeRelease)
+	assert.NoError(<-probeDone)
+	mg.On("GatherSingle", mock.Anything, qProbe, mock.Anything).Once().Return(nil, nil)
+	_, err = g.GatherSingle(context.TODO(), qProbe, time.Now())
+	assert.NoError(err)
+
+	mg.AssertExpectations(t)
+}
@@ -0,0 +1,127 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+// DatasourceHealth is the health of a datasource based on the queries
+// that have been made to it.
+type DatasourceHealth struct {
+	// ID is the datasource ID.
+	ID string
+	// LastSuccess is the time of the last successful query, zero if
+	// there isn't any.
+	LastSuccess time.Time
+	// Errors is the number of failed queries.
+	Errors int
+	// Latency is the duration of the last query.
+	Latency time.Duration
+	// LastErr is the error of the last query, nil if the last query
+	// was successful.
+	LastErr error
+}
+
+// HealthTracker tracks the health of the datasources, it's safe to use
+// it concurrently.
+type HealthTracker struct {
+	ids    []string
+	health map[string]*DatasourceHealth
+	mu     sync.Mutex
+}
+
+// NewHealthTracker returns a new health tracker.
+func NewHealthTracker() *HealthTracker {
+	return &HealthTracker{
+		health: map[string]*DatasourceHealth{},
+	}
+}
+
+// Register registers datasource IDs so they are tracked before
+// the first query is made to them.
+func (h *HealthTracker) Register(ids ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, id := range ids {
+		h.get(id)
+	}
+}
+
+// Health returns the health of the tracked datasources in registration order.
+func (h *HealthTracker) Health() []DatasourceHealth {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	res := make([]DatasourceHealth, 0, len(h.ids))
+	for _, id := range h.ids {
+		res = append(res, *h.health[id])
+	}
+	return res
+}
+
+func (h *HealthTracker) record(id string, start time.Time, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	dh := h.get(id)
+	dh.Latency = time.Since(start)
+	dh.LastErr = err
+	if err != nil {
+		dh.Errors++
+		return
+	}
+	dh.LastSuccess = start.Add(dh.Latency)
+}
+
+// get returns the health of a datasource, registering it if required,
+// must be called with the lock acquired.
+func (h *HealthTracker) get(id string) *DatasourceHealth {
+	dh, ok := h.health[id]
+	if !ok {
+		dh = &DatasourceHealth{ID: id}
+		h.health[id] = dh
+		h.ids = append(h.ids, id)
+	}
+	return dh
+}
+
+type health struct {
+	next    metric.Gatherer
+	tracker *HealthTracker
+}
+
+// Health is a gatherer middleware that wraps the real gatherer and records
+// the result and the latency of the queries on the tracker by datasource ID.
+func Health(tracker *HealthTracker, next metric.Gatherer) metric.Gatherer {
+	return &health{
+		next:    next,
+		tracker: tracker,
+	}
+}
+
+func (h *health) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	st := time.Now()
+	ms, err := h.next.GatherSingle(ctx, query, t)
+	h.record(ctx, query, st, err)
+	return ms, err
+}
+
+func (h *health) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	st := time.Now()
+	ms, err := h.next.GatherRange(ctx, query, start, end, step)
+	h.record(ctx, query, st, err)
+	return ms, err
+}
+
+func (h *health) record(ctx context.Context, query model.Query, start time.Time, err error) {
+	// Cancelled queries don't say anything about the datasource health.
+	if err != nil && ctx.Err() != nil {
+		return
+	}
+	h.tracker.record(query.DatasourceID, start, err)
+}
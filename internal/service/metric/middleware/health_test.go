@@ -0,0 +1,92 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mmetric "github.com/slok/grafterm/internal/mocks/service/metric"
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/middleware"
+)
+
+func TestHealth(t *testing.T) {
+	tests := map[string]struct {
+		registered []string
+		queries    []string
+		errs       []error
+		cancelCtx  bool
+		expIDs     []string
+		expErrors  map[string]int
+		expSuccess map[string]bool
+		expLastErr map[string]bool
+	}{
+		"Registered datasources should be tracked without queries.": {
+			registered: []string{"ds0", "ds1"},
+			expIDs:     []string{"ds0", "ds1"},
+			expErrors:  map[string]int{"ds0": 0, "ds1": 0},
+			expSuccess: map[string]bool{"ds0": false, "ds1": false},
+			expLastErr: map[string]bool{"ds0": false, "ds1": false},
+		},
+		"Queries should be tracked by datasource ID.": {
+			registered: []string{"ds0"},
+			queries:    []string{"ds0", "ds1", "ds1", "ds0"},
+			errs:       []error{nil, errors.New("wanted"), nil, errors.New("wanted")},
+			expIDs:     []string{"ds0", "ds1"},
+			expErrors:  map[string]int{"ds0": 1, "ds1": 1},
+			expSuccess: map[string]bool{"ds0": true, "ds1": true},
+			expLastErr: map[string]bool{"ds0": true, "ds1": false},
+		},
+		"Errors of cancelled queries should be ignored.": {
+			registered: []string{"ds0"},
+			queries:    []string{"ds0"},
+			errs:       []error{context.Canceled},
+			cancelCtx:  true,
+			expIDs:     []string{"ds0"},
+			expErrors:  map[string]int{"ds0": 0},
+			expSuccess: map[string]bool{"ds0": false},
+			expLastErr: map[string]bool{"ds0": false},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancelCtx {
+				cancel()
+			}
+
+			tracker := middleware.NewHealthTracker()
+			tracker.Register(test.registered...)
+
+			mg := &mmetric.Gatherer{}
+			for i, id := range test.queries {
+				q := model.Query{DatasourceID: id}
+				mg.On("GatherRange", mock.Anything, q, mock.Anything, mock.Anything, mock.Anything).Once().Return(nil, test.errs[i])
+			}
+
+			g := middleware.Health(tracker, mg)
+			for _, id := range test.queries {
+				g.GatherRange(ctx, model.Query{DatasourceID: id}, time.Now(), time.Now(), time.Second)
+			}
+
+			got := tracker.Health()
+			gotIDs := []string{}
+			for _, h := range got {
+				gotIDs = append(gotIDs, h.ID)
+				assert.Equal(test.expErrors[h.ID], h.Errors)
+				assert.Equal(test.expSuccess[h.ID], !h.LastSuccess.IsZero())
+				assert.Equal(test.expLastErr[h.ID], h.LastErr != nil)
+			}
+			assert.Equal(test.expIDs, gotIDs)
+			mg.AssertExpectations(t)
+		})
+	}
+}
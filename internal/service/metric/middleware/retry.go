@@ -0,0 +1,96 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+// RetryConfig is the configuration of the Retry middleware.
+type RetryConfig struct {
+	// Retries is the number of times a failed query is retried.
+	Retries int
+	// Backoff is the wait before the first retry, the wait is doubled
+	// on every retry.
+	Backoff time.Duration
+	// MaxBackoff is the maximum wait between retries, 0 means no limit.
+	MaxBackoff time.Duration
+}
+
+type retry struct {
+	cfg  RetryConfig
+	next metric.Gatherer
+}
+
+// Retry is a gatherer middleware that wraps the real gatherer and retries
+// the queries that failed with a transient error (network errors and
+// datasource server errors) with an exponential backoff. The queries are
+// not retried once the context is done.
+func Retry(cfg RetryConfig, next metric.Gatherer) metric.Gatherer {
+	return &retry{
+		cfg:  cfg,
+		next: next,
+	}
+}
+
+func (r *retry) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	var ms []model.MetricSeries
+	err := r.do(ctx, func() (err error) {
+		ms, err = r.next.GatherSingle(ctx, query, t)
+		return err
+	})
+	return ms, err
+}
+
+func (r *retry) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	var ms []model.MetricSeries
+	err := r.do(ctx, func() (err error) {
+		ms, err = r.next.GatherRange(ctx, query, start, end, step)
+		return err
+	})
+	return ms, err
+}
+
+func (r *retry) do(ctx context.Context, f func() error) error {
+	backoff := r.cfg.Backoff
+	err := f()
+	for i := 0; i < r.cfg.Retries && transient(err); i++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if r.cfg.MaxBackoff > 0 && backoff > r.cfg.MaxBackoff {
+			backoff = r.cfg.MaxBackoff
+		}
+
+		err = f()
+	}
+
+	return err
+}
+
+// transient returns true if the query error is temporary and the query
+// could succeed if retried (e.g network errors or 5xx status codes), the
+// rest of errors (e.g an invalid query) will fail again.
+func transient(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	var srvErr *metric.ServerError
+	return errors.As(err, &srvErr)
+}
@@ -0,0 +1,94 @@
+package middleware_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mmetric "github.com/slok/grafterm/internal/mocks/service/metric"
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+	"github.com/slok/grafterm/internal/service/metric/middleware"
+)
+
+func TestRetry(t *testing.T) {
+	series := []model.MetricSeries{{ID: "up"}}
+	errWanted := &metric.ServerError{Err: errors.New("wanted")}
+	errNetwork := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	errQuery := errors.New("invalid query")
+
+	tests := map[string]struct {
+		retries   int
+		errs      []error
+		cancelCtx bool
+		expCalls  int
+		expErr    bool
+	}{
+		"A successful query shouldn't be retried.": {
+			retries:  3,
+			errs:     []error{nil},
+			expCalls: 1,
+		},
+		"A failed query should be retried until it succeeds.": {
+			retries:  3,
+			errs:     []error{errWanted, errWanted, nil},
+			expCalls: 3,
+		},
+		"A query failed with a network error should be retried.": {
+			retries:  3,
+			errs:     []error{errNetwork, nil},
+			expCalls: 2,
+		},
+		"A query failed with a non transient error shouldn't be retried.": {
+			retries:  3,
+			errs:     []error{errQuery},
+			expCalls: 1,
+			expErr:   true,
+		},
+		"A failed query should be retried only the configured retries.": {
+			retries:  2,
+			errs:     []error{errWanted, errWanted, errWanted},
+			expCalls: 3,
+			expErr:   true,
+		},
+		"A failed query shouldn't be retried when the context is done.": {
+			retries:   3,
+			errs:      []error{errWanted},
+			cancelCtx: true,
+			expCalls:  1,
+			expErr:    true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			mg := &mmetric.Gatherer{}
+			for _, err := range test.errs {
+				mg.On("GatherRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Once().Return(series, err)
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if test.cancelCtx {
+				cancel()
+			}
+
+			g := middleware.Retry(middleware.RetryConfig{Retries: test.retries, Backoff: time.Millisecond}, mg)
+			_, err := g.GatherRange(ctx, model.Query{}, time.Now(), time.Now(), time.Second)
+			if test.expErr {
+				assert.Error(err)
+			} else {
+				assert.NoError(err)
+			}
+
+			mg.AssertNumberOfCalls(t, "GatherRange", test.expCalls)
+		})
+	}
+}
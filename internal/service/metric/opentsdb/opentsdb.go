@@ -0,0 +1,284 @@
+package opentsdb
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+const (
+	instantRange = 5 * time.Minute
+	instantStep  = 1 * time.Minute
+
+	defDownsampleAggregator = "avg"
+)
+
+var (
+	queryRegexp = regexp.MustCompile(`^\s*([^{\s]+)\s*(?:\{(.*)\})?\s*$`)
+	tagRegexp   = regexp.MustCompile(`^\s*([^=\s]+)\s*=\s*(\S+)\s*$`)
+)
+
+// ConfigGatherer is the configuration of the OpenTSDB gatherer.
+type ConfigGatherer struct {
+	// Address is the address of the OpenTSDB HTTP API.
+	Address string
+	HTTPCli *http.Client
+}
+
+func (c *ConfigGatherer) defaults() {
+	if c.HTTPCli == nil {
+		c.HTTPCli = http.DefaultClient
+	}
+}
+
+type gatherer struct {
+	cfg      ConfigGatherer
+	queryURL string
+}
+
+// NewGatherer returns a new metric gatherer for OpenTSDB backends using
+// the `/api/query` HTTP API.
+func NewGatherer(cfg ConfigGatherer) (metric.Gatherer, error) {
+	cfg.defaults()
+
+	u, err := url.Parse(cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	u.Path = path.Join(u.Path, "/api/query")
+
+	return &gatherer{
+		cfg:      cfg,
+		queryURL: u.String(),
+	}, nil
+}
+
+func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	res, err := g.GatherRange(ctx, query, t.Add(-1*instantRange), t, instantStep)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// Get the latest datapoint of each series.
+	mss := []model.MetricSeries{}
+	for _, ms := range res {
+		if len(ms.Metrics) < 1 {
+			continue
+		}
+		ms.Metrics = ms.Metrics[len(ms.Metrics)-1:]
+		mss = append(mss, ms)
+	}
+
+	if len(mss) < 1 {
+		return []model.MetricSeries{}, fmt.Errorf("server didn't return any metric series")
+	}
+
+	return mss, nil
+}
+
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	sq, err := parseQuery(query.Expr, step)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	body, err := json.Marshal(apiRequest{
+		Start:   start.UnixNano() / int64(time.Millisecond),
+		End:     end.UnixNano() / int64(time.Millisecond),
+		Queries: []subQuery{*sq},
+	})
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, g.queryURL, bytes.NewReader(body))
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.cfg.HTTPCli.Do(req)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+	defer resp.Body.Close()
+
+	rbody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		apiErr := struct {
+			Error struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}{}
+		if json.Unmarshal(rbody, &apiErr) == nil && apiErr.Error.Message != "" {
+			return []model.MetricSeries{}, metric.StatusError(resp.StatusCode, fmt.Errorf("OpenTSDB error (%d): %s", resp.StatusCode, apiErr.Error.Message))
+		}
+		return []model.MetricSeries{}, metric.StatusError(resp.StatusCode, fmt.Errorf("OpenTSDB error (%d): %s", resp.StatusCode, strings.TrimSpace(string(rbody))))
+	}
+
+	results := []apiResult{}
+	err = json.Unmarshal(rbody, &results)
+	if err != nil {
+		return []model.MetricSeries{}, fmt.Errorf("invalid OpenTSDB response: %s", err)
+	}
+
+	return transformResults(results)
+}
+
+// apiRequest is the body of the `/api/query` requests.
+type apiRequest struct {
+	Start   int64      `json:"start"`
+	End     int64      `json:"end"`
+	Queries []subQuery `json:"queries"`
+}
+
+// subQuery is a metric query of the `/api/query` requests.
+type subQuery struct {
+	Aggregator string            `json:"aggregator"`
+	Metric     string            `json:"metric"`
+	Rate       bool              `json:"rate,omitempty"`
+	Downsample string            `json:"downsample,omitempty"`
+	Tags       map[string]string `json:"tags,omitempty"`
+}
+
+// parseQuery parses the query expression with the OpenTSDB metric query
+// format, e.g: `sum:rate:sys.cpu.user{host=*,dc=lga}`. The aggregator and the
+// metric are required, `rate` and the downsample (e.g `5m-max`) are optional.
+// Without downsample the step is used with the average.
+func parseQuery(expr string, step time.Duration) (*subQuery, error) {
+	m := queryRegexp.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("invalid OpenTSDB query: %s", expr)
+	}
+
+	parts := strings.Split(m[1], ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		return nil, fmt.Errorf("invalid OpenTSDB query %s, should be `aggregator:[rate:][downsample:]metric`", expr)
+	}
+
+	sq := &subQuery{
+		Aggregator: parts[0],
+		Metric:     parts[len(parts)-1],
+	}
+	for _, p := range parts[1 : len(parts)-1] {
+		switch {
+		case p == "rate":
+			sq.Rate = true
+		case strings.Contains(p, "-"):
+			sq.Downsample = p
+		default:
+			return nil, fmt.Errorf("invalid OpenTSDB query option %s", p)
+		}
+	}
+	if sq.Aggregator == "" || sq.Metric == "" {
+		return nil, fmt.Errorf("invalid OpenTSDB query %s, aggregator and metric are required", expr)
+	}
+
+	if sq.Downsample == "" && step > 0 {
+		secs := int64(step / time.Second)
+		if secs < 1 {
+			secs = 1
+		}
+		sq.Downsample = fmt.Sprintf("%ds-%s", secs, defDownsampleAggregator)
+	}
+
+	if strings.TrimSpace(m[2]) != "" {
+		sq.Tags = map[string]string{}
+		for _, tag := range strings.Split(m[2], ",") {
+			tm := tagRegexp.FindStringSubmatch(tag)
+			if tm == nil {
+				return nil, fmt.Errorf("invalid OpenTSDB query tag: %s", tag)
+			}
+			sq.Tags[tm[1]] = tm[2]
+		}
+	}
+
+	return sq, nil
+}
+
+// apiResult is a series of the `/api/query` response.
+type apiResult struct {
+	Metric string             `json:"metric"`
+	Tags   map[string]string  `json:"tags"`
+	DPS    map[string]float64 `json:"dps"`
+}
+
+// transformResults returns the series of the results with the tags as the
+// labels and the datapoints sorted by time.
+func transformResults(results []apiResult) ([]model.MetricSeries, error) {
+	mss := []model.MetricSeries{}
+	for _, r := range results {
+		ms := []model.Metric{}
+		for ts, v := range r.DPS {
+			t, err := parseTimestamp(ts)
+			if err != nil {
+				return []model.MetricSeries{}, err
+			}
+			ms = append(ms, model.Metric{TS: t, Value: v})
+		}
+		sort.Slice(ms, func(i, j int) bool { return ms[i].TS.Before(ms[j].TS) })
+
+		labels := map[string]string{}
+		for k, v := range r.Tags {
+			labels[k] = v
+		}
+
+		mss = append(mss, model.MetricSeries{
+			ID:      seriesID(r.Metric, labels),
+			Labels:  labels,
+			Metrics: ms,
+		})
+	}
+
+	return mss, nil
+}
+
+// parseTimestamp parses the datapoint timestamps, in seconds or in
+// milliseconds when the query has millisecond resolution.
+func parseTimestamp(ts string) (time.Time, error) {
+	i, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid OpenTSDB timestamp %s", ts)
+	}
+
+	// Timestamps with more than 10 digits are in milliseconds.
+	if len(ts) > 10 {
+		return time.Unix(0, i*int64(time.Millisecond)), nil
+	}
+	return time.Unix(i, 0), nil
+}
+
+// seriesID returns the ID of a series based on the metric and the tags, e.g:
+// `sys.cpu.user{dc="lga",host="web01"}`.
+func seriesID(metric string, tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, fmt.Sprintf("%s=%q", k, tags[k]))
+	}
+
+	return fmt.Sprintf("%s{%s}", metric, strings.Join(kvs, ","))
+}
@@ -0,0 +1,189 @@
+package opentsdb_test
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/opentsdb"
+)
+
+const hostsResponse = `
+[
+  {
+    "metric": "sys.cpu.user",
+    "tags": {"host": "web01", "dc": "lga"},
+    "aggregateTags": [],
+    "dps": {"1558275720": 18.5, "1558275600": 12, "1558275660": 15}
+  },
+  {
+    "metric": "sys.cpu.user",
+    "tags": {"host": "web02", "dc": "lga"},
+    "aggregateTags": [],
+    "dps": {"1558275600": 9}
+  }
+]`
+
+func TestGathererGatherRange(t *testing.T) {
+	start := time.Unix(1558275600, 0)
+	end := time.Unix(1558275780, 0)
+
+	tests := map[string]struct {
+		query           string
+		step            time.Duration
+		tsdbStatus      int
+		tsdbResponse    string
+		expRequest      string
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"A query should be mapped to the API query with the step as the downsample and the tags as labels.": {
+			query:        "sum:sys.cpu.user{host=*,dc=lga}",
+			step:         time.Minute,
+			tsdbResponse: hostsResponse,
+			expRequest: `{
+  "start": 1558275600000,
+  "end": 1558275780000,
+  "queries": [
+    {"aggregator": "sum", "metric": "sys.cpu.user", "downsample": "60s-avg", "tags": {"host": "*", "dc": "lga"}}
+  ]
+}`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `sys.cpu.user{dc="lga",host="web01"}`,
+					Labels: map[string]string{"dc": "lga", "host": "web01"},
+					Metrics: []model.Metric{
+						{TS: time.Unix(1558275600, 0), Value: 12},
+						{TS: time.Unix(1558275660, 0), Value: 15},
+						{TS: time.Unix(1558275720, 0), Value: 18.5},
+					},
+				},
+				{
+					ID:     `sys.cpu.user{dc="lga",host="web02"}`,
+					Labels: map[string]string{"dc": "lga", "host": "web02"},
+					Metrics: []model.Metric{
+						{TS: time.Unix(1558275600, 0), Value: 9},
+					},
+				},
+			},
+		},
+		"A query with rate and downsample should use them.": {
+			query:        "max:rate:5m-max:net.bytes",
+			step:         time.Minute,
+			tsdbResponse: `[{"metric": "net.bytes", "tags": {}, "dps": {"1558275600000": 1024}}]`,
+			expRequest: `{
+  "start": 1558275600000,
+  "end": 1558275780000,
+  "queries": [
+    {"aggregator": "max", "metric": "net.bytes", "rate": true, "downsample": "5m-max"}
+  ]
+}`,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:      `net.bytes{}`,
+					Labels:  map[string]string{},
+					Metrics: []model.Metric{{TS: time.Unix(1558275600, 0), Value: 1024}},
+				},
+			},
+		},
+		"A query without aggregator should fail.": {
+			query:  "sys.cpu.user{host=web01}",
+			expErr: true,
+		},
+		"A query with an invalid tag should fail.": {
+			query:  "sum:sys.cpu.user{host}",
+			expErr: true,
+		},
+		"An error response should fail.": {
+			query:        "sum:sys.cpu.nope",
+			tsdbStatus:   http.StatusBadRequest,
+			tsdbResponse: `{"error": {"code": 400, "message": "No such name for 'metrics': 'sys.cpu.nope'"}}`,
+			expErr:       true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mock server response.
+			var gotPath string
+			var gotBody []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotPath = r.URL.Path
+				gotBody, _ = ioutil.ReadAll(r.Body)
+				if test.tsdbStatus != 0 {
+					w.WriteHeader(test.tsdbStatus)
+				}
+				w.Write([]byte(test.tsdbResponse))
+			}))
+			defer srv.Close()
+
+			g, err := opentsdb.NewGatherer(opentsdb.ConfigGatherer{Address: srv.URL})
+			assert.NoError(err)
+			gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: test.query}, start, end, test.step)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal("/api/query", gotPath)
+				assert.JSONEq(test.expRequest, string(gotBody))
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+		})
+	}
+}
+
+func TestGathererGatherSingle(t *testing.T) {
+	tests := map[string]struct {
+		tsdbResponse    string
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"Getting a single metric should return the latest datapoint of each series.": {
+			tsdbResponse: hostsResponse,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:      `sys.cpu.user{dc="lga",host="web01"}`,
+					Labels:  map[string]string{"dc": "lga", "host": "web01"},
+					Metrics: []model.Metric{{TS: time.Unix(1558275720, 0), Value: 18.5}},
+				},
+				{
+					ID:      `sys.cpu.user{dc="lga",host="web02"}`,
+					Labels:  map[string]string{"dc": "lga", "host": "web02"},
+					Metrics: []model.Metric{{TS: time.Unix(1558275600, 0), Value: 9}},
+				},
+			},
+		},
+		"Getting 0 metric series should error.": {
+			tsdbResponse: `[]`,
+			expErr:       true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mock server response.
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte(test.tsdbResponse))
+			}))
+			defer srv.Close()
+
+			g, err := opentsdb.NewGatherer(opentsdb.ConfigGatherer{Address: srv.URL})
+			assert.NoError(err)
+			gotms, err := g.GatherSingle(context.TODO(), model.Query{Expr: "sum:sys.cpu.user{host=*}"}, time.Now())
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+		})
+	}
+}
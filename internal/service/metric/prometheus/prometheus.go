@@ -39,7 +39,7 @@ func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.T
 	// Get value from Prometheus.
 	val, _, err := g.cli.Query(ctx, query.Expr, t)
 	if err != nil {
-		return []model.MetricSeries{}, err
+		return []model.MetricSeries{}, serverError(err)
 	}
 
 	// Translate prom values to domain.
@@ -59,7 +59,7 @@ func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, en
 		Step:  step,
 	})
 	if err != nil {
-		return []model.MetricSeries{}, err
+		return []model.MetricSeries{}, serverError(err)
 	}
 
 	// Translate prom values to domain.
@@ -72,6 +72,15 @@ func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, en
 }
 
 // promToModel converts a prometheus result metric to a domain model one.
+// serverError wraps the Prometheus API server errors with metric.ServerError.
+func serverError(err error) error {
+	var perr *promv1.Error
+	if errors.As(err, &perr) && perr.Type == promv1.ErrServer {
+		return &metric.ServerError{Err: err}
+	}
+	return err
+}
+
 func (g *gatherer) promToModel(pm prommodel.Value) ([]model.MetricSeries, error) {
 	res := []model.MetricSeries{}
 
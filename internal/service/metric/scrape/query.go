@@ -0,0 +1,102 @@
+package scrape
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/selector"
+)
+
+const nameLabel = "__name__"
+
+var rateRegexp = regexp.MustCompile(`^\s*rate\s*\((.*)\[\s*([0-9a-z.]+)\s*\]\s*\)\s*$`)
+
+// query is a series selector with an optional rate, e.g:
+// `rate(http_requests_total{code=~"5.."}[1m])`.
+type query struct {
+	sel    *selector.Selector
+	rate   bool
+	window time.Duration
+}
+
+func parseQuery(expr string) (*query, error) {
+	q := &query{}
+	if m := rateRegexp.FindStringSubmatch(expr); m != nil {
+		window, err := time.ParseDuration(m[2])
+		if err != nil || window <= 0 {
+			return nil, fmt.Errorf("invalid rate range: %s", m[2])
+		}
+		q.rate = true
+		q.window = window
+		expr = m[1]
+	}
+
+	// Unlike Prometheus style selectors, the queries need to select something.
+	if strings.TrimSpace(expr) == "" {
+		return nil, fmt.Errorf("invalid series selector: %s", expr)
+	}
+	sel, err := selector.Parse(expr, nameLabel)
+	if err != nil {
+		return nil, err
+	}
+	q.sel = sel
+
+	return q, nil
+}
+
+// eval returns the value of the query at a point in time from the samples
+// of a series.
+func (q *query) eval(metrics []model.Metric, t time.Time) (model.Metric, bool) {
+	last := sort.Search(len(metrics), func(i int) bool { return metrics[i].TS.After(t) })
+
+	if !q.rate {
+		if last == 0 || t.Sub(metrics[last-1].TS) > lookback {
+			return model.Metric{}, false
+		}
+		return model.Metric{TS: t, Value: metrics[last-1].Value}, true
+	}
+
+	// Rate of the samples on the window, the counter resets are handled like
+	// Prometheus but without extrapolation.
+	first := sort.Search(len(metrics), func(i int) bool { return !metrics[i].TS.Before(t.Add(-1 * q.window)) })
+	if last-first < 2 {
+		return model.Metric{}, false
+	}
+
+	inc := 0.0
+	for i := first + 1; i < last; i++ {
+		prev, cur := metrics[i-1].Value, metrics[i].Value
+		if cur < prev {
+			inc += cur
+			continue
+		}
+		inc += cur - prev
+	}
+	secs := metrics[last-1].TS.Sub(metrics[first].TS).Seconds()
+
+	return model.Metric{TS: t, Value: inc / secs}, true
+}
+
+// metricSeries returns the result series of the query, the rate series
+// don't have the metric name like on Prometheus.
+func (q *query) metricSeries(s *series, ms []model.Metric) model.MetricSeries {
+	labels := make(map[string]string, len(s.labels))
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+
+	id := s.id
+	if q.rate {
+		id = seriesID("", labels)
+	}
+
+	return model.MetricSeries{
+		ID:      id,
+		Labels:  labels,
+		Metrics: ms,
+	}
+}
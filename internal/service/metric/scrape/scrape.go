@@ -0,0 +1,369 @@
+package scrape
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+	"github.com/slok/grafterm/internal/service/metric/selector"
+)
+
+const (
+	defInterval  = 5 * time.Second
+	defRetention = time.Hour
+	// lookback is the max age of the sample used as the value at a point in
+	// time, the same as the Prometheus staleness.
+	lookback = 5 * time.Minute
+)
+
+// ConfigGatherer is the configuration of the scrape gatherer.
+type ConfigGatherer struct {
+	// Address is the URL of the metrics endpoint (e.g `http://127.0.0.1:8080/metrics`).
+	Address string
+	// Interval is the interval between the scrapes.
+	Interval time.Duration
+	// Retention is the time the scraped samples are kept in memory.
+	Retention time.Duration
+	HTTPCli   *http.Client
+}
+
+func (c *ConfigGatherer) defaults() error {
+	if c.Address == "" {
+		return fmt.Errorf("metrics endpoint address is required")
+	}
+
+	if c.Interval <= 0 {
+		c.Interval = defInterval
+	}
+
+	if c.Retention <= 0 {
+		c.Retention = defRetention
+	}
+
+	if c.HTTPCli == nil {
+		c.HTTPCli = http.DefaultClient
+	}
+
+	return nil
+}
+
+type gatherer struct {
+	cfg     ConfigGatherer
+	series  map[string]*series
+	lastErr error
+	mu      sync.RWMutex
+}
+
+// NewGatherer returns a new metric gatherer that scrapes a Prometheus metrics
+// endpoint (text exposition format) without a Prometheus server. The endpoint
+// is scraped in background since the gatherer is created until the context is
+// done, and the samples are kept in memory for the retention time.
+func NewGatherer(ctx context.Context, cfg ConfigGatherer) (metric.Gatherer, error) {
+	g, err := newGatherer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	go g.run(ctx)
+
+	return g, nil
+}
+
+func newGatherer(cfg ConfigGatherer) (*gatherer, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, err
+	}
+
+	return &gatherer{
+		cfg:    cfg,
+		series: map[string]*series{},
+	}, nil
+}
+
+// run scrapes the endpoint on every interval until the context is done.
+func (g *gatherer) run(ctx context.Context) {
+	// The endpoint can be unavailable when grafterm starts (e.g the app is
+	// starting), the scrape errors are returned when gathering.
+	g.scrape(ctx, time.Now())
+
+	t := time.NewTicker(g.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			g.scrape(ctx, now)
+		}
+	}
+}
+
+// scrape gets the samples of the endpoint and stores them with the scrape time.
+func (g *gatherer) scrape(ctx context.Context, now time.Time) {
+	samples, err := g.fetch(ctx)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lastErr = err
+	if err == nil {
+		for _, smpl := range samples {
+			s, ok := g.series[smpl.id]
+			if !ok {
+				s = &series{id: smpl.id, name: smpl.name, labels: smpl.labels}
+				g.series[smpl.id] = s
+			}
+			s.add(model.Metric{TS: now, Value: smpl.value})
+		}
+	}
+
+	// Forget the samples out of the retention and the series without samples.
+	cutoff := now.Add(-1 * g.cfg.Retention)
+	for id, s := range g.series {
+		s.prune(cutoff)
+		if len(s.metrics) == 0 {
+			delete(g.series, id)
+		}
+	}
+}
+
+func (g *gatherer) fetch(ctx context.Context) ([]sample, error) {
+	ctx, cancel := context.WithTimeout(ctx, g.cfg.Interval)
+	defer cancel()
+
+	req, err := http.NewRequest(http.MethodGet, g.cfg.Address, nil)
+	if err != nil {
+		return nil, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Accept", "text/plain;version=0.0.4")
+
+	resp, err := g.cfg.HTTPCli.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		ioutil.ReadAll(resp.Body)
+		return nil, fmt.Errorf("metrics endpoint returned %d status code", resp.StatusCode)
+	}
+
+	var parser expfmt.TextParser
+	mfs, err := parser.TextToMetricFamilies(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid metrics: %s", err)
+	}
+
+	samples := []sample{}
+	for _, mf := range mfs {
+		samples = append(samples, familySamples(mf)...)
+	}
+
+	return samples, nil
+}
+
+func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	q, err := parseQuery(query.Expr)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.lastErr != nil {
+		return []model.MetricSeries{}, fmt.Errorf("could not scrape %s: %s", g.cfg.Address, g.lastErr)
+	}
+
+	res := []model.MetricSeries{}
+	for _, s := range g.match(q.sel) {
+		if m, ok := q.eval(s.metrics, t); ok {
+			res = append(res, q.metricSeries(s, []model.Metric{m}))
+		}
+	}
+
+	return res, nil
+}
+
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	q, err := parseQuery(query.Expr)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	if step <= 0 {
+		step = g.cfg.Interval
+	}
+
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.lastErr != nil {
+		return []model.MetricSeries{}, fmt.Errorf("could not scrape %s: %s", g.cfg.Address, g.lastErr)
+	}
+
+	res := []model.MetricSeries{}
+	for _, s := range g.match(q.sel) {
+		ms := []model.Metric{}
+		for t := start; !t.After(end); t = t.Add(step) {
+			if m, ok := q.eval(s.metrics, t); ok {
+				ms = append(ms, m)
+			}
+		}
+		if len(ms) > 0 {
+			res = append(res, q.metricSeries(s, ms))
+		}
+	}
+
+	return res, nil
+}
+
+// match returns the series that match the selector sorted by ID.
+func (g *gatherer) match(sel *selector.Selector) []*series {
+	res := []*series{}
+	for _, s := range g.series {
+		if sel.Matches(s.name, s.labels) {
+			res = append(res, s)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].id < res[j].id })
+
+	return res
+}
+
+// series is a scraped series with the samples sorted by time.
+type series struct {
+	id      string
+	name    string
+	labels  map[string]string
+	metrics []model.Metric
+}
+
+func (s *series) add(m model.Metric) {
+	if n := len(s.metrics); n > 0 && !m.TS.After(s.metrics[n-1].TS) {
+		return
+	}
+	s.metrics = append(s.metrics, m)
+}
+
+// prune removes the samples older than the cutoff.
+func (s *series) prune(cutoff time.Time) {
+	i := sort.Search(len(s.metrics), func(i int) bool { return !s.metrics[i].TS.Before(cutoff) })
+	s.metrics = s.metrics[i:]
+}
+
+// sample is a value of a series on a scrape.
+type sample struct {
+	id     string
+	name   string
+	labels map[string]string
+	value  float64
+}
+
+func newSample(name string, labels map[string]string, value float64) sample {
+	return sample{
+		id:     seriesID(name, labels),
+		name:   name,
+		labels: labels,
+		value:  value,
+	}
+}
+
+// familySamples returns the samples of a metric family, the summaries and
+// histograms are expanded in the same series that Prometheus would store
+// (e.g `_bucket`, `_sum` and `_count`).
+func familySamples(mf *dto.MetricFamily) []sample {
+	name := mf.GetName()
+	res := []sample{}
+	for _, m := range mf.GetMetric() {
+		labels := map[string]string{}
+		for _, lp := range m.GetLabel() {
+			labels[lp.GetName()] = lp.GetValue()
+		}
+
+		switch mf.GetType() {
+		case dto.MetricType_COUNTER:
+			res = append(res, newSample(name, labels, m.GetCounter().GetValue()))
+		case dto.MetricType_GAUGE:
+			res = append(res, newSample(name, labels, m.GetGauge().GetValue()))
+		case dto.MetricType_UNTYPED:
+			res = append(res, newSample(name, labels, m.GetUntyped().GetValue()))
+		case dto.MetricType_SUMMARY:
+			sm := m.GetSummary()
+			for _, q := range sm.GetQuantile() {
+				ls := withLabel(labels, "quantile", formatFloat(q.GetQuantile()))
+				res = append(res, newSample(name, ls, q.GetValue()))
+			}
+			res = append(res,
+				newSample(name+"_sum", labels, sm.GetSampleSum()),
+				newSample(name+"_count", labels, float64(sm.GetSampleCount())))
+		case dto.MetricType_HISTOGRAM:
+			h := m.GetHistogram()
+			inf := false
+			for _, b := range h.GetBucket() {
+				inf = inf || math.IsInf(b.GetUpperBound(), 1)
+				ls := withLabel(labels, "le", formatFloat(b.GetUpperBound()))
+				res = append(res, newSample(name+"_bucket", ls, float64(b.GetCumulativeCount())))
+			}
+			if !inf {
+				ls := withLabel(labels, "le", "+Inf")
+				res = append(res, newSample(name+"_bucket", ls, float64(h.GetSampleCount())))
+			}
+			res = append(res,
+				newSample(name+"_sum", labels, h.GetSampleSum()),
+				newSample(name+"_count", labels, float64(h.GetSampleCount())))
+		}
+	}
+
+	return res
+}
+
+// withLabel returns a copy of the labels with a new label.
+func withLabel(labels map[string]string, k, v string) map[string]string {
+	res := make(map[string]string, len(labels)+1)
+	for lk, lv := range labels {
+		res[lk] = lv
+	}
+	res[k] = v
+
+	return res
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// seriesID returns the ID of a series based on the name and the labels, e.g:
+// `http_requests_total{code="500",job="api"}`.
+func seriesID(name string, labels map[string]string) string {
+	if name != "" && len(labels) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+
+	return fmt.Sprintf("%s{%s}", name, strings.Join(kvs, ","))
+}
@@ -0,0 +1,282 @@
+package scrape
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/grafterm/internal/model"
+)
+
+var (
+	scrapes = []string{`
+# TYPE http_requests_total counter
+http_requests_total{code="200",job="api"} 100
+http_requests_total{code="500",job="api"} 10
+# TYPE goroutines gauge
+goroutines 12
+# TYPE request_duration_seconds histogram
+request_duration_seconds_bucket{le="0.1"} 3
+request_duration_seconds_bucket{le="+Inf"} 4
+request_duration_seconds_sum 0.7
+request_duration_seconds_count 4
+`, `
+# TYPE http_requests_total counter
+http_requests_total{code="200",job="api"} 130
+http_requests_total{code="500",job="api"} 16
+# TYPE goroutines gauge
+goroutines 15
+`, `
+# TYPE http_requests_total counter
+http_requests_total{code="200",job="api"} 20
+http_requests_total{code="500",job="api"} 22
+# TYPE goroutines gauge
+goroutines 9
+`}
+	t0 = time.Unix(1558275600, 0)
+)
+
+func ts(secs int) time.Time {
+	return t0.Add(time.Duration(secs) * time.Second)
+}
+
+// newTestGatherer returns a gatherer that has scraped all the test scrapes
+// every 10 seconds since t0.
+func newTestGatherer(t *testing.T) *gatherer {
+	i := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(scrapes[i]))
+	}))
+	t.Cleanup(srv.Close)
+
+	g, err := newGatherer(ConfigGatherer{Address: srv.URL, Interval: 10 * time.Second})
+	require.NoError(t, err)
+	for ; i < len(scrapes); i++ {
+		g.scrape(context.TODO(), ts(i*10))
+	}
+
+	return g
+}
+
+func TestGathererGatherRange(t *testing.T) {
+	tests := map[string]struct {
+		query           string
+		start           time.Time
+		end             time.Time
+		step            time.Duration
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"A selector should return the latest sample of every step.": {
+			query: `http_requests_total{code=~"5.."}`,
+			start: ts(0),
+			end:   ts(30),
+			step:  15 * time.Second,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `http_requests_total{code="500",job="api"}`,
+					Labels: map[string]string{"code": "500", "job": "api"},
+					Metrics: []model.Metric{
+						{TS: ts(0), Value: 10},
+						{TS: ts(15), Value: 16},
+						{TS: ts(30), Value: 22},
+					},
+				},
+			},
+		},
+		"A selector without labels should return the series with the name as the ID.": {
+			query: `goroutines`,
+			start: ts(10),
+			end:   ts(20),
+			step:  10 * time.Second,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `goroutines`,
+					Labels: map[string]string{},
+					Metrics: []model.Metric{
+						{TS: ts(10), Value: 15},
+						{TS: ts(20), Value: 9},
+					},
+				},
+			},
+		},
+		"The histograms should be expanded to the bucket series.": {
+			query: `request_duration_seconds_bucket{le="+Inf"}`,
+			start: ts(0),
+			end:   ts(0),
+			step:  10 * time.Second,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:      `request_duration_seconds_bucket{le="+Inf"}`,
+					Labels:  map[string]string{"le": "+Inf"},
+					Metrics: []model.Metric{{TS: ts(0), Value: 4}},
+				},
+			},
+		},
+		"A rate should return the per second rate of the counters handling the resets.": {
+			query: `rate(http_requests_total{job="api"}[20s])`,
+			start: ts(10),
+			end:   ts(20),
+			step:  10 * time.Second,
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `{code="200",job="api"}`,
+					Labels: map[string]string{"code": "200", "job": "api"},
+					Metrics: []model.Metric{
+						{TS: ts(10), Value: 3},
+						{TS: ts(20), Value: 2.5},
+					},
+				},
+				{
+					ID:     `{code="500",job="api"}`,
+					Labels: map[string]string{"code": "500", "job": "api"},
+					Metrics: []model.Metric{
+						{TS: ts(10), Value: 0.6},
+						{TS: ts(20), Value: 0.6},
+					},
+				},
+			},
+		},
+		"A selector without matching series should return nothing.": {
+			query:           `http_requests_total{code="404"}`,
+			start:           ts(0),
+			end:             ts(30),
+			step:            10 * time.Second,
+			expMetricSeries: []model.MetricSeries{},
+		},
+		"An invalid selector should fail.": {
+			query:  `http_requests_total{code=500}`,
+			expErr: true,
+		},
+		"An invalid rate range should fail.": {
+			query:  `rate(http_requests_total[1x])`,
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := newTestGatherer(t)
+			gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: test.query}, test.start, test.end, test.step)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+		})
+	}
+}
+
+func TestGathererGatherSingle(t *testing.T) {
+	tests := map[string]struct {
+		query           string
+		t               time.Time
+		expMetricSeries []model.MetricSeries
+	}{
+		"Getting a single metric should return the latest sample.": {
+			query: `goroutines`,
+			t:     ts(25),
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:      `goroutines`,
+					Labels:  map[string]string{},
+					Metrics: []model.Metric{{TS: ts(25), Value: 9}},
+				},
+			},
+		},
+		"Getting a single metric with stale samples should return nothing.": {
+			query:           `goroutines`,
+			t:               ts(20).Add(lookback + time.Second),
+			expMetricSeries: []model.MetricSeries{},
+		},
+		"Getting a single rate without enough samples should return nothing.": {
+			query:           `rate(http_requests_total[5s])`,
+			t:               ts(20),
+			expMetricSeries: []model.MetricSeries{},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			g := newTestGatherer(t)
+			gotms, err := g.GatherSingle(context.TODO(), model.Query{Expr: test.query}, test.t)
+			if assert.NoError(err) {
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+		})
+	}
+}
+
+func TestGathererScrapeErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte("goroutines 12\n"))
+	}))
+	defer srv.Close()
+
+	g, err := newGatherer(ConfigGatherer{Address: srv.URL, Interval: 10 * time.Second, Retention: time.Minute})
+	assert.NoError(err)
+	g.scrape(context.TODO(), ts(0))
+
+	// A failed scrape should fail the queries.
+	status = http.StatusInternalServerError
+	g.scrape(context.TODO(), ts(10))
+	_, err = g.GatherSingle(context.TODO(), model.Query{Expr: "goroutines"}, ts(10))
+	assert.Error(err)
+
+	// The samples out of the retention should be removed.
+	g.scrape(context.TODO(), ts(90))
+	assert.Empty(g.series)
+}
+
+func TestGathererBackground(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	const interval = 10 * time.Millisecond
+	scraped := make(chan struct{}, 100)
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("goroutines 12\n"))
+		scraped <- struct{}{}
+	}))
+	defer srv.Close()
+
+	// A slow endpoint shouldn't block the creation of the gatherer.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, err := NewGatherer(ctx, ConfigGatherer{Address: srv.URL, Interval: time.Second})
+	require.NoError(err)
+	got, err := g.GatherSingle(context.TODO(), model.Query{Expr: "goroutines"}, time.Now())
+	assert.NoError(err)
+	assert.Empty(got)
+	close(release)
+	<-scraped
+	cancel()
+
+	// Once the context is done the endpoint shouldn't be scraped anymore.
+	ctx, cancel = context.WithCancel(context.Background())
+	_, err = NewGatherer(ctx, ConfigGatherer{Address: srv.URL, Interval: interval})
+	require.NoError(err)
+	<-scraped
+	cancel()
+	time.Sleep(2 * interval)
+	for len(scraped) > 0 {
+		<-scraped
+	}
+	time.Sleep(5 * interval)
+	assert.Len(scraped, 0)
+}
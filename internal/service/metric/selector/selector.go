@@ -0,0 +1,103 @@
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	selectorRegexp = regexp.MustCompile(`^\s*([a-zA-Z_:][a-zA-Z0-9_:.]*)?\s*(?:\{(.*)\})?\s*$`)
+	matcherRegexp  = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(=~|!~|!=|=)\s*("(?:[^"\\]|\\.)*")\s*(?:,|$)`)
+)
+
+// matcher matches a label value.
+type matcher struct {
+	label string
+	op    string
+	value string
+	regex *regexp.Regexp
+}
+
+func (m matcher) matches(v string) bool {
+	switch m.op {
+	case "=":
+		return v == m.value
+	case "!=":
+		return v != m.value
+	case "=~":
+		return m.regex.MatchString(v)
+	case "!~":
+		return !m.regex.MatchString(v)
+	}
+	return false
+}
+
+// Selector selects the series using Prometheus style label matchers, e.g:
+// `http_requests_total{job="api",code=~"5.."}`. The name of the selector
+// matches the name of the series, the name label can be used on the label
+// matchers to match the name too. An empty selector selects all the series.
+type Selector struct {
+	name      string
+	nameLabel string
+	matchers  []matcher
+}
+
+// Parse parses a selector, the name label is the label that has the name of
+// the series (e.g `__name__` on Prometheus).
+func Parse(expr, nameLabel string) (*Selector, error) {
+	m := selectorRegexp.FindStringSubmatch(expr)
+	if m == nil {
+		return nil, fmt.Errorf("invalid series selector: %s", expr)
+	}
+
+	sel := &Selector{name: m[1], nameLabel: nameLabel}
+	if m[1] != "" {
+		sel.matchers = append(sel.matchers, matcher{label: nameLabel, op: "=", value: m[1]})
+	}
+
+	rest := m[2]
+	for strings.TrimSpace(rest) != "" {
+		mm := matcherRegexp.FindStringSubmatch(rest)
+		if mm == nil {
+			return nil, fmt.Errorf("invalid label matcher: %s", rest)
+		}
+		rest = rest[len(mm[0]):]
+
+		value, err := strconv.Unquote(mm[3])
+		if err != nil {
+			return nil, fmt.Errorf("invalid label matcher value %s: %s", mm[3], err)
+		}
+		mt := matcher{label: mm[1], op: mm[2], value: value}
+		if mt.op == "=~" || mt.op == "!~" {
+			mt.regex, err = regexp.Compile("^(?:" + value + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid label matcher regex %s: %s", value, err)
+			}
+		}
+		sel.matchers = append(sel.matchers, mt)
+	}
+
+	return sel, nil
+}
+
+// Name returns the name of the selector, empty if the selector doesn't have
+// a name (e.g `{job="api"}`).
+func (s *Selector) Name() string {
+	return s.name
+}
+
+// Matches returns true if the series with the name and labels is selected.
+func (s *Selector) Matches(name string, labels map[string]string) bool {
+	for _, m := range s.matchers {
+		v := labels[m.label]
+		if m.label == s.nameLabel {
+			v = name
+		}
+		if !m.matches(v) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,83 @@
+package selector_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/grafterm/internal/service/metric/selector"
+)
+
+func TestSelector(t *testing.T) {
+	labels := map[string]string{"job": "api", "code": "500"}
+
+	tests := map[string]struct {
+		expr       string
+		name       string
+		expName    string
+		expMatches bool
+		expErr     bool
+	}{
+		"An empty selector should select all the series.": {
+			expr:       "",
+			name:       "http_requests_total",
+			expMatches: true,
+		},
+		"A selector name should match the series name.": {
+			expr:       "http_requests_total",
+			expName:    "http_requests_total",
+			name:       "http_requests_total",
+			expMatches: true,
+		},
+		"A selector name shouldn't match other series names.": {
+			expr:       "http_requests_total",
+			expName:    "http_requests_total",
+			name:       "up",
+			expMatches: false,
+		},
+		"A selector with label matchers should match all the label matchers.": {
+			expr:       `http_requests_total{job="api",code=~"5.."}`,
+			expName:    "http_requests_total",
+			name:       "http_requests_total",
+			expMatches: true,
+		},
+		"A selector with a non matching label matcher shouldn't match.": {
+			expr:       `{job="api",code!~"5.."}`,
+			name:       "http_requests_total",
+			expMatches: false,
+		},
+		"A label matcher using the name label should match the series name.": {
+			expr:       `{__name__=~"http_.*",job!="web"}`,
+			name:       "http_requests_total",
+			expMatches: true,
+		},
+		"Names with dots should be valid.": {
+			expr:       "cpu.usage",
+			expName:    "cpu.usage",
+			name:       "cpu.usage",
+			expMatches: true,
+		},
+		"An invalid label matcher should fail.": {
+			expr:   `http_requests_total{job=api}`,
+			expErr: true,
+		},
+		"An invalid label matcher regex should fail.": {
+			expr:   `http_requests_total{job=~"(api"}`,
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			sel, err := selector.Parse(test.expr, "__name__")
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expName, sel.Name())
+				assert.Equal(test.expMatches, sel.Matches(test.name, labels))
+			}
+		})
+	}
+}
@@ -0,0 +1,281 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+const (
+	instantRange = 5 * time.Minute
+	// literalTimeFormat is RFC 3339 with an explicit offset instead of `Z`,
+	// some databases (e.g MySQL) don't support `Z` on the time literals.
+	literalTimeFormat = "2006-01-02T15:04:05-07:00"
+	// timeFormat is the format of the times returned as text (e.g MySQL
+	// DATETIME columns).
+	timeFormat = "2006-01-02 15:04:05"
+
+	defTimeColumn  = "time"
+	defValueColumn = "value"
+)
+
+// ConfigGatherer is the configuration of the SQL gatherer.
+type ConfigGatherer struct {
+	// DB is the database where the queries are made.
+	DB *sql.DB
+	// TimeColumn is the column of the rows with the time of the metric.
+	TimeColumn string
+	// ValueColumn is the column of the rows with the value of the metric.
+	ValueColumn string
+}
+
+func (c *ConfigGatherer) defaults() error {
+	if c.DB == nil {
+		return fmt.Errorf("sql database is required")
+	}
+
+	if c.TimeColumn == "" {
+		c.TimeColumn = defTimeColumn
+	}
+
+	if c.ValueColumn == "" {
+		c.ValueColumn = defValueColumn
+	}
+
+	return nil
+}
+
+type gatherer struct {
+	cfg ConfigGatherer
+}
+
+// NewGatherer returns a new metric gatherer for SQL databases. Every row of
+// the query result is a metric, the time and value columns are the metric and
+// the rest of the columns are the labels of the series.
+func NewGatherer(cfg ConfigGatherer) (metric.Gatherer, error) {
+	err := cfg.defaults()
+	if err != nil {
+		return nil, err
+	}
+
+	return &gatherer{
+		cfg: cfg,
+	}, nil
+}
+
+func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	res, err := g.GatherRange(ctx, query, t.Add(-1*instantRange), t, 0)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// Get the latest datapoint of each series.
+	mss := []model.MetricSeries{}
+	for _, ms := range res {
+		if len(ms.Metrics) < 1 {
+			continue
+		}
+		ms.Metrics = ms.Metrics[len(ms.Metrics)-1:]
+		mss = append(mss, ms)
+	}
+
+	return mss, nil
+}
+
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	rows, err := g.cfg.DB.QueryContext(ctx, renderQuery(query.Expr, start, end, step))
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+	timeCol, valueCol := -1, -1
+	for i, c := range cols {
+		switch c {
+		case g.cfg.TimeColumn:
+			timeCol = i
+		case g.cfg.ValueColumn:
+			valueCol = i
+		}
+	}
+	if timeCol < 0 || valueCol < 0 {
+		return []model.MetricSeries{}, fmt.Errorf("query result requires %s and %s columns", g.cfg.TimeColumn, g.cfg.ValueColumn)
+	}
+
+	res := []model.MetricSeries{}
+	index := map[string]int{}
+	for rows.Next() {
+		values := make([]interface{}, len(cols))
+		ptrs := make([]interface{}, len(cols))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return []model.MetricSeries{}, err
+		}
+
+		// Null values don't have a metric.
+		if values[valueCol] == nil {
+			continue
+		}
+		v, err := toFloat(values[valueCol])
+		if err != nil {
+			return []model.MetricSeries{}, fmt.Errorf("invalid %s column: %s", g.cfg.ValueColumn, err)
+		}
+		t, err := toTime(values[timeCol])
+		if err != nil {
+			return []model.MetricSeries{}, fmt.Errorf("invalid %s column: %s", g.cfg.TimeColumn, err)
+		}
+
+		labels := map[string]string{}
+		for i, c := range cols {
+			if i != timeCol && i != valueCol {
+				labels[c] = toString(values[i])
+			}
+		}
+
+		id := g.cfg.ValueColumn
+		if len(labels) > 0 {
+			id = seriesID(labels)
+		}
+		i, ok := index[id]
+		if !ok {
+			i = len(res)
+			index[id] = i
+			res = append(res, model.MetricSeries{
+				ID:      id,
+				Labels:  labels,
+				Metrics: []model.Metric{},
+			})
+		}
+		res[i].Metrics = append(res[i].Metrics, model.Metric{TS: t, Value: v})
+	}
+	if err := rows.Err(); err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// The rows could not be sorted by time.
+	for _, ms := range res {
+		ms := ms
+		sort.SliceStable(ms.Metrics, func(i, j int) bool { return ms.Metrics[i].TS.Before(ms.Metrics[j].TS) })
+	}
+
+	return res, nil
+}
+
+// renderQuery replaces the time range variables of the query:
+// - `$__timeFrom` and `$__timeTo`: The time range as RFC 3339 UTC timestamps (e.g `'2019-05-19T14:00:00+00:00'`).
+// - `$__interval`: The step in seconds.
+func renderQuery(expr string, start, end time.Time, step time.Duration) string {
+	interval := int64(math.Ceil(step.Seconds()))
+	if interval < 1 {
+		interval = 1
+	}
+
+	r := strings.NewReplacer(
+		"$__timeFrom", "'"+start.UTC().Format(literalTimeFormat)+"'",
+		"$__timeTo", "'"+end.UTC().Format(literalTimeFormat)+"'",
+		"$__interval", strconv.FormatInt(interval, 10),
+	)
+	return r.Replace(expr)
+}
+
+func toFloat(v interface{}) (float64, error) {
+	switch tv := v.(type) {
+	case float64:
+		return tv, nil
+	case float32:
+		return float64(tv), nil
+	case int64:
+		return float64(tv), nil
+	case int32:
+		return float64(tv), nil
+	case int:
+		return float64(tv), nil
+	case bool:
+		if tv {
+			return 1, nil
+		}
+		return 0, nil
+	case []byte:
+		return strconv.ParseFloat(string(tv), 64)
+	case string:
+		return strconv.ParseFloat(tv, 64)
+	}
+
+	return 0, fmt.Errorf("unsupported value type %T", v)
+}
+
+// toTime converts the time column value, the numeric values are Unix seconds.
+func toTime(v interface{}) (time.Time, error) {
+	var s string
+	switch tv := v.(type) {
+	case time.Time:
+		return tv, nil
+	case []byte:
+		s = string(tv)
+	case string:
+		s = tv
+	case nil:
+		return time.Time{}, fmt.Errorf("null time")
+	default:
+		f, err := toFloat(v)
+		if err != nil {
+			return time.Time{}, err
+		}
+		sec, dec := math.Modf(f)
+		return time.Unix(int64(sec), int64(dec*float64(time.Second))), nil
+	}
+
+	if f, err := strconv.ParseFloat(s, 64); err == nil {
+		sec, dec := math.Modf(f)
+		return time.Unix(int64(sec), int64(dec*float64(time.Second))), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, timeFormat} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unsupported time format %s", s)
+}
+
+func toString(v interface{}) string {
+	switch tv := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(tv)
+	case time.Time:
+		return tv.Format(time.RFC3339)
+	}
+	return fmt.Sprint(v)
+}
+
+// seriesID returns the ID of a series based on the labels, e.g:
+// `{country="es",plan="pro"}`.
+func seriesID(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	kvs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		kvs = append(kvs, fmt.Sprintf("%s=%q", k, labels[k]))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(kvs, ","))
+}
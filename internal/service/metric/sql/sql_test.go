@@ -0,0 +1,148 @@
+package sql_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/sql"
+)
+
+func TestGathererGatherRange(t *testing.T) {
+	start := time.Date(2019, 5, 19, 14, 0, 0, 0, time.UTC)
+	end := time.Date(2019, 5, 19, 15, 0, 0, 0, time.UTC)
+	t0 := time.Date(2019, 5, 19, 14, 10, 0, 0, time.UTC)
+	t1 := time.Date(2019, 5, 19, 14, 20, 0, 0, time.UTC)
+
+	tests := map[string]struct {
+		cfg             sql.ConfigGatherer
+		query           string
+		expQuery        string
+		rows            func() *sqlmock.Rows
+		queryErr        error
+		expMetricSeries []model.MetricSeries
+		expErr          bool
+	}{
+		"The time range variables should be replaced and the columns without time and value should be labels.": {
+			query:    "SELECT created_at AS time, plan, count(*) AS value FROM signups WHERE created_at BETWEEN $__timeFrom AND $__timeTo GROUP BY 1, 2 -- $__interval",
+			expQuery: "SELECT created_at AS time, plan, count(*) AS value FROM signups WHERE created_at BETWEEN '2019-05-19T14:00:00+00:00' AND '2019-05-19T15:00:00+00:00' GROUP BY 1, 2 -- 30",
+			rows: func() *sqlmock.Rows {
+				return sqlmock.NewRows([]string{"time", "plan", "value"}).
+					AddRow(t1, "pro", int64(3)).
+					AddRow(t0, "pro", int64(2)).
+					AddRow(t0, "free", []byte("10.5")).
+					AddRow(t1, "free", nil)
+			},
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     `{plan="pro"}`,
+					Labels: map[string]string{"plan": "pro"},
+					Metrics: []model.Metric{
+						{TS: t0, Value: 2},
+						{TS: t1, Value: 3},
+					},
+				},
+				{
+					ID:      `{plan="free"}`,
+					Labels:  map[string]string{"plan": "free"},
+					Metrics: []model.Metric{{TS: t0, Value: 10.5}},
+				},
+			},
+		},
+		"Custom time and value columns with Unix times and without labels should use the value column as the ID.": {
+			cfg:      sql.ConfigGatherer{TimeColumn: "ts", ValueColumn: "revenue"},
+			query:    "SELECT ts, revenue FROM kpis",
+			expQuery: "SELECT ts, revenue FROM kpis",
+			rows: func() *sqlmock.Rows {
+				return sqlmock.NewRows([]string{"ts", "revenue"}).
+					AddRow(t0.Unix(), 99.9).
+					AddRow("2019-05-19 14:20:00", "100")
+			},
+			expMetricSeries: []model.MetricSeries{
+				{
+					ID:     "revenue",
+					Labels: map[string]string{},
+					Metrics: []model.Metric{
+						{TS: time.Unix(t0.Unix(), 0), Value: 99.9},
+						{TS: t1, Value: 100},
+					},
+				},
+			},
+		},
+		"A result without the value column should fail.": {
+			query:    "SELECT created_at AS time FROM signups",
+			expQuery: "SELECT created_at AS time FROM signups",
+			rows: func() *sqlmock.Rows {
+				return sqlmock.NewRows([]string{"time"}).AddRow(t0)
+			},
+			expErr: true,
+		},
+		"A failed query should fail.": {
+			query:    "SELECT 1",
+			expQuery: "SELECT 1",
+			queryErr: errors.New("wanted"),
+			expErr:   true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			require.NoError(err)
+			defer db.Close()
+
+			exp := mock.ExpectQuery(test.expQuery)
+			if test.queryErr != nil {
+				exp.WillReturnError(test.queryErr)
+			} else {
+				exp.WillReturnRows(test.rows())
+			}
+
+			test.cfg.DB = db
+			g, err := sql.NewGatherer(test.cfg)
+			require.NoError(err)
+
+			gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: test.query}, start, end, 30*time.Second)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.expMetricSeries, gotms)
+			}
+			assert.NoError(mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestGathererGatherSingle(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	require.NoError(err)
+	defer db.Close()
+
+	now := time.Date(2019, 5, 19, 15, 0, 0, 0, time.UTC)
+	t0 := now.Add(-2 * time.Minute)
+	t1 := now.Add(-1 * time.Minute)
+	mock.ExpectQuery("SELECT time, value FROM kpis WHERE time > '2019-05-19T14:55:00+00:00'").
+		WillReturnRows(sqlmock.NewRows([]string{"time", "value"}).AddRow(t0, 1.0).AddRow(t1, 2.0))
+
+	g, err := sql.NewGatherer(sql.ConfigGatherer{DB: db})
+	require.NoError(err)
+
+	// Should return the latest row.
+	gotms, err := g.GatherSingle(context.TODO(), model.Query{Expr: "SELECT time, value FROM kpis WHERE time > $__timeFrom"}, now)
+	if assert.NoError(err) && assert.Len(gotms, 1) {
+		assert.Equal([]model.Metric{{TS: t1, Value: 2}}, gotms[0].Metrics)
+	}
+	assert.NoError(mock.ExpectationsWereMet())
+}
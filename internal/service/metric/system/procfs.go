@@ -0,0 +1,298 @@
+package system
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// clockTicks is the USER_HZ of the kernel, the unit of the proc CPU times.
+	clockTicks = 100
+	// sectorSize is the size of the sectors on the diskstats.
+	sectorSize = 512
+)
+
+// cpuFields are the fields of the CPU line on `/proc/stat` in order.
+var cpuFields = []string{"user", "nice", "system", "idle", "iowait", "irq", "softirq", "steal"}
+
+// raw is the raw data collected from procfs.
+type raw struct {
+	ts time.Time
+	// cpu are the CPU times by field.
+	cpu map[string]float64
+	// gauges are the values by series ID that are used as they are.
+	gauges map[string]float64
+	// counters are the values by series ID that are used as per second rates.
+	counters map[string]float64
+	// series are the series of the gauges and counters by ID.
+	series map[string]series
+}
+
+func newRaw(ts time.Time) *raw {
+	return &raw{
+		ts:       ts,
+		cpu:      map[string]float64{},
+		gauges:   map[string]float64{},
+		counters: map[string]float64{},
+		series:   map[string]series{},
+	}
+}
+
+func (r *raw) gauge(name string, labels map[string]string, v float64) {
+	s := newSeries(name, labels)
+	r.series[s.id] = s
+	r.gauges[s.id] = v
+}
+
+func (r *raw) counter(name string, labels map[string]string, v float64) {
+	s := newSeries(name, labels)
+	r.series[s.id] = s
+	r.counters[s.id] = v
+}
+
+// collect reads all the metrics from the proc filesystem, the metrics of
+// every process are only collected if processes is true.
+func collect(procPath string, processes bool, ts time.Time) (*raw, error) {
+	r := newRaw(ts)
+
+	collectors := []func(string, *raw) error{
+		collectStat,
+		collectLoad,
+		collectMemory,
+		collectDisks,
+		collectNetwork,
+		func(procPath string, r *raw) error { return collectProcesses(procPath, processes, r) },
+	}
+	for _, c := range collectors {
+		if err := c(procPath, r); err != nil {
+			return nil, err
+		}
+	}
+
+	return r, nil
+}
+
+// collectStat collects the CPU times from `/proc/stat`.
+func collectStat(procPath string, r *raw) error {
+	// The CPU usage series are calculated from the CPU times.
+	for _, name := range append([]string{"usage"}, cpuFields...) {
+		s := newSeries("cpu."+name, nil)
+		r.series[s.id] = s
+	}
+
+	return readLines(filepath.Join(procPath, "stat"), func(fields []string) error {
+		if len(fields) < 2 || fields[0] != "cpu" {
+			return nil
+		}
+
+		for i, name := range cpuFields {
+			if i+1 >= len(fields) {
+				break
+			}
+			v, err := strconv.ParseFloat(fields[i+1], 64)
+			if err != nil {
+				return fmt.Errorf("invalid cpu %s time: %s", name, err)
+			}
+			r.cpu[name] = v
+		}
+		return nil
+	})
+}
+
+// collectLoad collects the load average from `/proc/loadavg`.
+func collectLoad(procPath string, r *raw) error {
+	return readLines(filepath.Join(procPath, "loadavg"), func(fields []string) error {
+		if len(fields) < 3 {
+			return fmt.Errorf("invalid loadavg")
+		}
+
+		for i, name := range []string{"load.1", "load.5", "load.15"} {
+			v, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %s", name, err)
+			}
+			r.gauge(name, nil, v)
+		}
+		return nil
+	})
+}
+
+// collectMemory collects the memory from `/proc/meminfo`.
+func collectMemory(procPath string, r *raw) error {
+	mem := map[string]float64{}
+	err := readLines(filepath.Join(procPath, "meminfo"), func(fields []string) error {
+		if len(fields) < 2 {
+			return nil
+		}
+
+		v, err := strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return fmt.Errorf("invalid meminfo %s: %s", fields[0], err)
+		}
+		if len(fields) > 2 && fields[2] == "kB" {
+			v *= 1024
+		}
+		mem[strings.TrimSuffix(fields[0], ":")] = v
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	r.gauge("mem.total", nil, mem["MemTotal"])
+	r.gauge("mem.free", nil, mem["MemFree"])
+	r.gauge("mem.available", nil, mem["MemAvailable"])
+	r.gauge("mem.buffers", nil, mem["Buffers"])
+	r.gauge("mem.cached", nil, mem["Cached"])
+	r.gauge("mem.used", nil, mem["MemTotal"]-mem["MemAvailable"])
+	r.gauge("swap.total", nil, mem["SwapTotal"])
+	r.gauge("swap.free", nil, mem["SwapFree"])
+	r.gauge("swap.used", nil, mem["SwapTotal"]-mem["SwapFree"])
+
+	return nil
+}
+
+// collectDisks collects the disks IO from `/proc/diskstats`.
+func collectDisks(procPath string, r *raw) error {
+	return readLines(filepath.Join(procPath, "diskstats"), func(fields []string) error {
+		if len(fields) < 10 {
+			return nil
+		}
+
+		// Ignore virtual devices.
+		device := fields[2]
+		if strings.HasPrefix(device, "loop") || strings.HasPrefix(device, "ram") {
+			return nil
+		}
+
+		vs, err := parseFloats(fields[3:10])
+		if err != nil {
+			return fmt.Errorf("invalid %s diskstats: %s", device, err)
+		}
+		labels := map[string]string{"device": device}
+		r.counter("disk.reads", labels, vs[0])
+		r.counter("disk.read_bytes", labels, vs[2]*sectorSize)
+		r.counter("disk.writes", labels, vs[4])
+		r.counter("disk.write_bytes", labels, vs[6]*sectorSize)
+		return nil
+	})
+}
+
+// collectNetwork collects the network interfaces traffic from `/proc/net/dev`.
+func collectNetwork(procPath string, r *raw) error {
+	return readLines(filepath.Join(procPath, "net", "dev"), func(fields []string) error {
+		// Ignore headers.
+		if len(fields) < 17 || !strings.HasSuffix(fields[0], ":") {
+			return nil
+		}
+
+		iface := strings.TrimSuffix(fields[0], ":")
+		vs, err := parseFloats(fields[1:17])
+		if err != nil {
+			return fmt.Errorf("invalid %s net dev: %s", iface, err)
+		}
+		labels := map[string]string{"iface": iface}
+		r.counter("net.rx_bytes", labels, vs[0])
+		r.counter("net.rx_packets", labels, vs[1])
+		r.counter("net.rx_errors", labels, vs[2])
+		r.counter("net.rx_drops", labels, vs[3])
+		r.counter("net.tx_bytes", labels, vs[8])
+		r.counter("net.tx_packets", labels, vs[9])
+		r.counter("net.tx_errors", labels, vs[10])
+		r.counter("net.tx_drops", labels, vs[11])
+		return nil
+	})
+}
+
+// collectProcesses collects the number of processes and, if stats is true,
+// the stats of every process from `/proc/[pid]/stat`.
+func collectProcesses(procPath string, stats bool, r *raw) error {
+	entries, err := ioutil.ReadDir(procPath)
+	if err != nil {
+		return err
+	}
+
+	pageSize := float64(os.Getpagesize())
+	count := 0
+	for _, e := range entries {
+		pid := e.Name()
+		if _, err := strconv.Atoi(pid); err != nil || !e.IsDir() {
+			continue
+		}
+
+		// The processes can finish at any moment, ignore the ones we can't read.
+		bs, err := ioutil.ReadFile(filepath.Join(procPath, pid, "stat"))
+		if err != nil {
+			continue
+		}
+
+		// The name is between parenthesis and can have spaces and parenthesis.
+		stat := string(bs)
+		start := strings.IndexByte(stat, '(')
+		end := strings.LastIndexByte(stat, ')')
+		if start < 0 || end < start {
+			continue
+		}
+		name := stat[start+1 : end]
+		fields := strings.Fields(stat[end+1:])
+		if len(fields) < 22 {
+			continue
+		}
+		utime, err1 := strconv.ParseFloat(fields[11], 64)
+		stime, err2 := strconv.ParseFloat(fields[12], 64)
+		threads, err3 := strconv.ParseFloat(fields[17], 64)
+		rss, err4 := strconv.ParseFloat(fields[21], 64)
+		if err1 != nil || err2 != nil || err3 != nil || err4 != nil {
+			continue
+		}
+
+		count++
+		if !stats {
+			continue
+		}
+		labels := map[string]string{"pid": pid, "name": name}
+		// CPU seconds per second as a percentage.
+		r.counter("process.cpu", labels, (utime+stime)/clockTicks*100)
+		r.gauge("process.rss_bytes", labels, rss*pageSize)
+		r.gauge("process.threads", labels, threads)
+	}
+	r.gauge("process.count", nil, float64(count))
+
+	return nil
+}
+
+// readLines calls the line function with the fields of every line of the file.
+func readLines(path string, line func(fields []string) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		if err := line(strings.Fields(sc.Text())); err != nil {
+			return err
+		}
+	}
+
+	return sc.Err()
+}
+
+func parseFloats(ss []string) ([]float64, error) {
+	res := make([]float64, 0, len(ss))
+	for _, s := range ss {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, v)
+	}
+	return res, nil
+}
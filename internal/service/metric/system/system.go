@@ -0,0 +1,393 @@
+package system
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric"
+	"github.com/slok/grafterm/internal/service/metric/selector"
+)
+
+const (
+	defProcPath  = "/proc"
+	defInterval  = 2 * time.Second
+	defRetention = time.Hour
+	// nameLabel is the label that matches the metric name on the selectors.
+	nameLabel = "__name__"
+)
+
+// names are the metrics that the gatherer knows.
+var names = map[string]bool{
+	"cpu.usage": true, "cpu.user": true, "cpu.nice": true, "cpu.system": true, "cpu.idle": true,
+	"cpu.iowait": true, "cpu.irq": true, "cpu.softirq": true, "cpu.steal": true,
+	"load.1": true, "load.5": true, "load.15": true,
+	"mem.total": true, "mem.free": true, "mem.available": true, "mem.buffers": true, "mem.cached": true, "mem.used": true,
+	"swap.total": true, "swap.free": true, "swap.used": true,
+	"disk.reads": true, "disk.read_bytes": true, "disk.writes": true, "disk.write_bytes": true,
+	"net.rx_bytes": true, "net.rx_packets": true, "net.rx_errors": true, "net.rx_drops": true,
+	"net.tx_bytes": true, "net.tx_packets": true, "net.tx_errors": true, "net.tx_drops": true,
+	"process.count": true, "process.cpu": true, "process.rss_bytes": true, "process.threads": true,
+}
+
+// processNames are the metrics of every process.
+var processNames = map[string]bool{
+	"process.cpu": true, "process.rss_bytes": true, "process.threads": true,
+}
+
+// ConfigGatherer is the configuration of the system gatherer.
+type ConfigGatherer struct {
+	// ProcPath is the path of the proc filesystem, by default `/proc`.
+	ProcPath string
+	// Interval is the interval between the metrics collections.
+	Interval time.Duration
+	// Retention is the time the collected metrics are kept in memory.
+	Retention time.Duration
+	// Processes enables the collection of the metrics of every process,
+	// these are multiple series per process kept for all the retention.
+	Processes bool
+}
+
+func (c *ConfigGatherer) defaults() {
+	if c.ProcPath == "" {
+		c.ProcPath = defProcPath
+	}
+
+	if c.Interval <= 0 {
+		c.Interval = defInterval
+	}
+
+	if c.Retention <= 0 {
+		c.Retention = defRetention
+	}
+}
+
+type gatherer struct {
+	cfg     ConfigGatherer
+	samples *ring
+	last    *raw
+	lastErr error
+	series  map[string]series
+	mu      sync.RWMutex
+}
+
+// NewGatherer returns a new metric gatherer of the local system metrics that
+// reads the proc filesystem. The metrics are collected in background since the
+// gatherer is created until the context is done, and kept in memory for the
+// retention time.
+func NewGatherer(ctx context.Context, cfg ConfigGatherer) (metric.Gatherer, error) {
+	cfg.defaults()
+
+	size := int(cfg.Retention / cfg.Interval)
+	if size < 1 {
+		size = 1
+	}
+
+	// The first collection is used as the base of the rates.
+	r, err := collect(cfg.ProcPath, cfg.Processes, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("could not collect system metrics: %s", err)
+	}
+
+	g := &gatherer{
+		cfg:     cfg,
+		samples: newRing(size),
+		last:    r,
+		series:  map[string]series{},
+	}
+	go g.run(ctx)
+
+	return g, nil
+}
+
+// run collects the metrics on every interval until the context is done.
+func (g *gatherer) run(ctx context.Context) {
+	t := time.NewTicker(g.cfg.Interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-t.C:
+			g.collect(now)
+		}
+	}
+}
+
+func (g *gatherer) collect(now time.Time) {
+	r, err := collect(g.cfg.ProcPath, g.cfg.Processes, now)
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.lastErr = err
+	if err != nil {
+		return
+	}
+
+	for id, s := range r.series {
+		g.series[id] = s
+	}
+	g.samples.add(derive(g.last, r))
+	g.last = r
+
+	// Forget the series that are not on the samples anymore (e.g finished processes).
+	if g.samples.next == 0 {
+		g.series = g.samples.series(g.series)
+	}
+}
+
+func (g *gatherer) GatherSingle(ctx context.Context, query model.Query, t time.Time) ([]model.MetricSeries, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.lastErr != nil {
+		return []model.MetricSeries{}, fmt.Errorf("could not collect system metrics: %s", g.lastErr)
+	}
+
+	sel, err := g.parseSelector(query.Expr)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// Get the latest sample at the required time.
+	var smpl *sample
+	for _, s := range g.samples.list() {
+		if s.ts.After(t) {
+			break
+		}
+		s := s
+		smpl = &s
+	}
+	if smpl == nil {
+		return []model.MetricSeries{}, fmt.Errorf("there are no system metrics collected yet")
+	}
+
+	res := []model.MetricSeries{}
+	for _, s := range g.match(sel) {
+		v, ok := smpl.values[s.id]
+		if !ok {
+			continue
+		}
+		res = append(res, s.metricSeries([]model.Metric{{TS: smpl.ts, Value: v}}))
+	}
+
+	return res, nil
+}
+
+func (g *gatherer) GatherRange(ctx context.Context, query model.Query, start, end time.Time, step time.Duration) ([]model.MetricSeries, error) {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+
+	if g.lastErr != nil {
+		return []model.MetricSeries{}, fmt.Errorf("could not collect system metrics: %s", g.lastErr)
+	}
+
+	sel, err := g.parseSelector(query.Expr)
+	if err != nil {
+		return []model.MetricSeries{}, err
+	}
+
+	// Get one sample per step.
+	samples := []sample{}
+	var next time.Time
+	for _, s := range g.samples.list() {
+		if s.ts.Before(start) || s.ts.After(end) || s.ts.Before(next) {
+			continue
+		}
+		samples = append(samples, s)
+		next = s.ts.Add(step)
+	}
+
+	res := []model.MetricSeries{}
+	for _, s := range g.match(sel) {
+		ms := []model.Metric{}
+		for _, smpl := range samples {
+			if v, ok := smpl.values[s.id]; ok {
+				ms = append(ms, model.Metric{TS: smpl.ts, Value: v})
+			}
+		}
+		if len(ms) > 0 {
+			res = append(res, s.metricSeries(ms))
+		}
+	}
+
+	return res, nil
+}
+
+// parseSelector parses the selector of a query expression (e.g
+// `net.rx_bytes{iface="eth0"}`), the selector needs a known metric name and
+// the metrics of every process are only valid when their collection is enabled.
+func (g *gatherer) parseSelector(expr string) (*selector.Selector, error) {
+	sel, err := selector.Parse(expr, nameLabel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid system metric expression: %s", err)
+	}
+
+	name := sel.Name()
+	if !names[name] {
+		return nil, fmt.Errorf("unknown system metric: %s", name)
+	}
+
+	if processNames[name] && !g.cfg.Processes {
+		return nil, fmt.Errorf("%s system metric needs the collection of the processes metrics enabled", name)
+	}
+
+	return sel, nil
+}
+
+// match returns the known series that match the selector sorted by ID.
+func (g *gatherer) match(sel *selector.Selector) []series {
+	res := []series{}
+	for _, s := range g.series {
+		if sel.Matches(s.name, s.labels) {
+			res = append(res, s)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].id < res[j].id })
+
+	return res
+}
+
+// derive returns the sample with the values of the current collection using
+// the previous one to calculate the rates and the CPU usage.
+func derive(prev, cur *raw) sample {
+	s := sample{
+		ts:     cur.ts,
+		values: make(map[string]float64, len(cur.gauges)+len(cur.counters)),
+	}
+
+	for id, v := range cur.gauges {
+		s.values[id] = v
+	}
+
+	secs := cur.ts.Sub(prev.ts).Seconds()
+	if secs <= 0 {
+		return s
+	}
+
+	for id, v := range cur.counters {
+		pv, ok := prev.counters[id]
+		// Counter resets (e.g a reused pid) don't have a rate.
+		if !ok || v < pv {
+			continue
+		}
+		s.values[id] = (v - pv) / secs
+	}
+
+	total := 0.0
+	deltas := map[string]float64{}
+	for _, f := range cpuFields {
+		d := cur.cpu[f] - prev.cpu[f]
+		if d < 0 {
+			return s
+		}
+		deltas[f] = d
+		total += d
+	}
+	if total > 0 {
+		for f, d := range deltas {
+			s.values[newSeries("cpu."+f, nil).id] = d / total * 100
+		}
+		s.values[newSeries("cpu.usage", nil).id] = (total - deltas["idle"] - deltas["iowait"]) / total * 100
+	}
+
+	return s
+}
+
+// series is a system metric series.
+type series struct {
+	id     string
+	name   string
+	labels map[string]string
+}
+
+func newSeries(name string, labels map[string]string) series {
+	if labels == nil {
+		labels = map[string]string{}
+	}
+
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	id := name
+	if len(keys) > 0 {
+		kvs := make([]string, 0, len(keys))
+		for _, k := range keys {
+			kvs = append(kvs, fmt.Sprintf("%s=%q", k, labels[k]))
+		}
+		id = fmt.Sprintf("%s{%s}", name, strings.Join(kvs, ","))
+	}
+
+	return series{id: id, name: name, labels: labels}
+}
+
+func (s series) metricSeries(ms []model.Metric) model.MetricSeries {
+	labels := make(map[string]string, len(s.labels))
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+
+	return model.MetricSeries{
+		ID:      s.id,
+		Labels:  labels,
+		Metrics: ms,
+	}
+}
+
+// sample are the values of the series at a point in time.
+type sample struct {
+	ts     time.Time
+	values map[string]float64
+}
+
+// ring is a ring buffer of samples.
+type ring struct {
+	samples []sample
+	next    int
+	full    bool
+}
+
+func newRing(size int) *ring {
+	return &ring{samples: make([]sample, size)}
+}
+
+func (r *ring) add(s sample) {
+	r.samples[r.next] = s
+	r.next = (r.next + 1) % len(r.samples)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// list returns the samples from the oldest to the newest.
+func (r *ring) list() []sample {
+	if !r.full {
+		return r.samples[:r.next]
+	}
+
+	res := make([]sample, 0, len(r.samples))
+	res = append(res, r.samples[r.next:]...)
+	return append(res, r.samples[:r.next]...)
+}
+
+// series returns the known series that are on the samples.
+func (r *ring) series(known map[string]series) map[string]series {
+	res := map[string]series{}
+	for _, s := range r.list() {
+		for id := range s.values {
+			if ss, ok := known[id]; ok {
+				res[id] = ss
+			}
+		}
+	}
+	return res
+}
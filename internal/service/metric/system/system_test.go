@@ -0,0 +1,260 @@
+package system_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/metric/system"
+)
+
+const testInterval = 10 * time.Millisecond
+
+// writeProc writes a fake proc filesystem, the counters are multiplied by n.
+func writeProc(t *testing.T, dir string, n int) {
+	files := map[string]string{
+		"stat":    fmt.Sprintf("cpu  %d 0 0 %d 0 0 0 0 0 0\ncpu0 %d 0 0 %d 0 0 0 0 0 0\n", 100*n, 300*n, 100*n, 300*n),
+		"loadavg": "0.50 0.25 0.10 2/72 31715\n",
+		"meminfo": `MemTotal:        1000 kB
+MemFree:          200 kB
+MemAvailable:     400 kB
+Buffers:           10 kB
+Cached:           100 kB
+SwapTotal:        100 kB
+SwapFree:          50 kB
+`,
+		"diskstats": fmt.Sprintf("   8       0 sda %d 0 %d 0 %d 0 %d 0 0 0 0\n   7       0 loop0 1 0 1 0 1 0 1 0 0 0 0\n", n, 10*n, n, 20*n),
+		"net/dev": fmt.Sprintf(`Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: %d 1 0 0 0 0 0 0 %d 1 0 0 0 0 0 0
+  eth0: %d 1 0 0 0 0 0 0 %d 1 0 0 0 0 0 0
+`, 10*n, 10*n, 1000*n, 500*n),
+		"1234/stat": fmt.Sprintf("1234 (my (app)) S 1 1234 1234 0 -1 4194304 82 0 0 0 %d %d 0 0 20 0 4 0 495790 2703360 10 18446744073709551615 0\n", 10*n, 10*n),
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		// Write atomically so the gatherer doesn't read partial files.
+		require.NoError(t, ioutil.WriteFile(path+".tmp", []byte(content), 0644))
+		require.NoError(t, os.Rename(path+".tmp", path))
+	}
+}
+
+func TestGathererGatherSingle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grafterm-proc")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	writeProc(t, dir, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, err := system.NewGatherer(ctx, system.ConfigGatherer{ProcPath: dir, Interval: testInterval, Processes: true})
+	require.NoError(t, err)
+	time.Sleep(5 * testInterval)
+
+	tests := map[string]struct {
+		expr      string
+		expValues map[string]float64
+		expErr    bool
+	}{
+		"A metric without labels should return its value.": {
+			expr:      "load.1",
+			expValues: map[string]float64{"load.1": 0.5},
+		},
+		"Memory should be returned in bytes.": {
+			expr:      "mem.used",
+			expValues: map[string]float64{"mem.used": 600 * 1024},
+		},
+		"A metric with labels should return all the series.": {
+			expr:      "process.threads",
+			expValues: map[string]float64{`process.threads{name="my (app)",pid="1234"}`: 4},
+		},
+		"A label matcher should select the series.": {
+			expr:      `net.rx_bytes{iface="eth0"}`,
+			expValues: map[string]float64{`net.rx_bytes{iface="eth0"}`: 0},
+		},
+		"A label matcher without matches should return 0 series.": {
+			expr:      `net.rx_bytes{iface="eth1"}`,
+			expValues: map[string]float64{},
+		},
+		"A regex label matcher should select the series.": {
+			expr:      `net.rx_bytes{iface=~"eth.*|lo"}`,
+			expValues: map[string]float64{`net.rx_bytes{iface="eth0"}`: 0, `net.rx_bytes{iface="lo"}`: 0},
+		},
+		"A label matcher value with commas should select the series.": {
+			expr:      `process.threads{name!="my,app",pid="1234"}`,
+			expValues: map[string]float64{`process.threads{name="my (app)",pid="1234"}`: 4},
+		},
+		"A selector without a metric name should fail.": {
+			expr:   `{iface="eth0"}`,
+			expErr: true,
+		},
+		"An unknown metric should fail.": {
+			expr:   "cpu.unknown",
+			expErr: true,
+		},
+		"An invalid label matcher should fail.": {
+			expr:   `net.rx_bytes{iface=eth0}`,
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			gotms, err := g.GatherSingle(context.TODO(), model.Query{Expr: test.expr}, time.Now())
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				got := map[string]float64{}
+				for _, ms := range gotms {
+					if assert.Len(ms.Metrics, 1) {
+						got[ms.ID] = ms.Metrics[0].Value
+					}
+				}
+				assert.Equal(test.expValues, got)
+			}
+		})
+	}
+}
+
+func TestGathererGatherRange(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "grafterm-proc")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	writeProc(t, dir, 1)
+
+	start := time.Now()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, err := system.NewGatherer(ctx, system.ConfigGatherer{ProcPath: dir, Interval: testInterval})
+	require.NoError(err)
+	time.Sleep(5 * testInterval)
+	writeProc(t, dir, 2)
+	time.Sleep(5 * testInterval)
+
+	// The counters should be returned as rates and the CPU times as percentages.
+	gotms, err := g.GatherRange(context.TODO(), model.Query{Expr: `net.rx_bytes{iface="eth0"}`}, start, time.Now(), time.Millisecond)
+	require.NoError(err)
+	require.Len(gotms, 1)
+	assert.Equal(map[string]string{"iface": "eth0"}, gotms[0].Labels)
+	assert.True(len(gotms[0].Metrics) > 5)
+	maxRate := 0.0
+	for _, m := range gotms[0].Metrics {
+		if m.Value > maxRate {
+			maxRate = m.Value
+		}
+	}
+	assert.True(maxRate > 0, "the rate of the counter should be > 0 after it increased")
+
+	gotms, err = g.GatherRange(context.TODO(), model.Query{Expr: "cpu.user"}, start, time.Now(), time.Millisecond)
+	require.NoError(err)
+	if assert.Len(gotms, 1) && assert.Len(gotms[0].Metrics, 1) {
+		assert.Equal(25.0, gotms[0].Metrics[0].Value)
+	}
+
+	// Virtual disks are ignored.
+	gotms, err = g.GatherRange(context.TODO(), model.Query{Expr: "disk.reads"}, start, time.Now(), time.Millisecond)
+	require.NoError(err)
+	if assert.Len(gotms, 1) {
+		assert.Equal(`disk.reads{device="sda"}`, gotms[0].ID)
+	}
+
+	// The step should limit the number of datapoints.
+	gotms, err = g.GatherRange(context.TODO(), model.Query{Expr: "load.1"}, start, time.Now(), time.Hour)
+	require.NoError(err)
+	if assert.Len(gotms, 1) {
+		assert.Len(gotms[0].Metrics, 1)
+	}
+}
+
+func TestGathererProcessesDisabled(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "grafterm-proc")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	writeProc(t, dir, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, err := system.NewGatherer(ctx, system.ConfigGatherer{ProcPath: dir, Interval: testInterval})
+	require.NoError(err)
+	time.Sleep(5 * testInterval)
+
+	// The number of processes is collected without the processes metrics.
+	gotms, err := g.GatherSingle(context.TODO(), model.Query{Expr: "process.count"}, time.Now())
+	require.NoError(err)
+	if assert.Len(gotms, 1) && assert.Len(gotms[0].Metrics, 1) {
+		assert.Equal(1.0, gotms[0].Metrics[0].Value)
+	}
+
+	_, err = g.GatherSingle(context.TODO(), model.Query{Expr: "process.threads"}, time.Now())
+	assert.Error(err)
+}
+
+func TestGathererCollectErrors(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "grafterm-proc")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	writeProc(t, dir, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	g, err := system.NewGatherer(ctx, system.ConfigGatherer{ProcPath: dir, Interval: testInterval})
+	require.NoError(err)
+	time.Sleep(5 * testInterval)
+
+	// The failed collections should fail the queries.
+	require.NoError(os.RemoveAll(dir))
+	time.Sleep(5 * testInterval)
+	_, err = g.GatherSingle(context.TODO(), model.Query{Expr: "load.1"}, time.Now())
+	assert.Error(err)
+	_, err = g.GatherRange(context.TODO(), model.Query{Expr: "load.1"}, time.Now().Add(-time.Minute), time.Now(), time.Millisecond)
+	assert.Error(err)
+}
+
+func TestGathererStop(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	dir, err := ioutil.TempDir("", "grafterm-proc")
+	require.NoError(err)
+	defer os.RemoveAll(dir)
+	writeProc(t, dir, 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	g, err := system.NewGatherer(ctx, system.ConfigGatherer{ProcPath: dir, Interval: testInterval})
+	require.NoError(err)
+	time.Sleep(5 * testInterval)
+
+	// Once the context is done the metrics shouldn't be collected anymore.
+	cancel()
+	time.Sleep(2 * testInterval)
+	before, err := g.GatherSingle(context.TODO(), model.Query{Expr: "load.1"}, time.Now())
+	require.NoError(err)
+	writeProc(t, dir, 2)
+	time.Sleep(5 * testInterval)
+	after, err := g.GatherSingle(context.TODO(), model.Query{Expr: "load.1"}, time.Now())
+	require.NoError(err)
+	if assert.Len(before, 1) && assert.Len(after, 1) {
+		assert.Equal(before[0].Metrics[0].TS, after[0].Metrics[0].TS)
+	}
+}
@@ -0,0 +1,82 @@
+package secret
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+const (
+	envPrefix  = "env:"
+	filePrefix = "file:"
+	execPrefix = "exec:"
+
+	execTimeout = 10 * time.Second
+)
+
+// Resolve returns the value of a secret. The secret can be a reference to
+// the real value so configuration files don't need to have the secrets:
+// - `env:VAR`: The value of the `VAR` environment variable.
+// - `file:/path`: The content of the file.
+// - `exec:command`: The output of the command executed by the shell.
+// The values without a reference prefix are returned as they are.
+func Resolve(secret string) (string, error) {
+	switch {
+	case strings.HasPrefix(secret, envPrefix):
+		name := strings.TrimPrefix(secret, envPrefix)
+		v, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(secret, filePrefix):
+		path := strings.TrimPrefix(secret, filePrefix)
+		bs, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("could not read secret file: %s", err)
+		}
+		return trimNewline(string(bs)), nil
+	case strings.HasPrefix(secret, execPrefix):
+		return execCommand(strings.TrimPrefix(secret, execPrefix))
+	}
+
+	return secret, nil
+}
+
+// IsReference returns true if the secret is a reference to the real value
+// instead of the value.
+func IsReference(secret string) bool {
+	for _, prefix := range []string{envPrefix, filePrefix, execPrefix} {
+		if strings.HasPrefix(secret, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func execCommand(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), execTimeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("secret command failed: %s: %s", err, strings.TrimSpace(stderr.String()))
+	}
+
+	return trimNewline(string(out)), nil
+}
+
+// trimNewline removes the trailing new line that most of the files and
+// commands have.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
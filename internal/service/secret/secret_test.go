@@ -0,0 +1,76 @@
+package secret_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/grafterm/internal/service/secret"
+)
+
+func TestResolve(t *testing.T) {
+	dir, err := ioutil.TempDir("", "grafterm-secret")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	secretFile := filepath.Join(dir, "password")
+	require.NoError(t, ioutil.WriteFile(secretFile, []byte("file-secret\n"), 0600))
+	os.Setenv("GRAFTERM_TEST_SECRET", "env-secret")
+	defer os.Unsetenv("GRAFTERM_TEST_SECRET")
+
+	tests := map[string]struct {
+		secret string
+		exp    string
+		expErr bool
+	}{
+		"A plain value should be returned as it is.": {
+			secret: "plain-secret",
+			exp:    "plain-secret",
+		},
+		"An empty value should be returned as it is.": {
+			secret: "",
+			exp:    "",
+		},
+		"An env reference should return the environment variable value.": {
+			secret: "env:GRAFTERM_TEST_SECRET",
+			exp:    "env-secret",
+		},
+		"An env reference of a missing environment variable should fail.": {
+			secret: "env:GRAFTERM_TEST_MISSING_SECRET",
+			expErr: true,
+		},
+		"A file reference should return the file content without the trailing new line.": {
+			secret: "file:" + secretFile,
+			exp:    "file-secret",
+		},
+		"A file reference of a missing file should fail.": {
+			secret: "file:" + filepath.Join(dir, "missing"),
+			expErr: true,
+		},
+		"An exec reference should return the command output without the trailing new line.": {
+			secret: "exec:echo exec-secret",
+			exp:    "exec-secret",
+		},
+		"An exec reference of a failing command should fail.": {
+			secret: "exec:exit 1",
+			expErr: true,
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got, err := secret.Resolve(test.secret)
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				assert.Equal(test.exp, got)
+			}
+		})
+	}
+}
@@ -19,27 +19,37 @@ type AppConfig struct {
 	TimeRangeStart    time.Time // Fixed optional time.
 	TimeRangeEnd      time.Time // Fixed optional time.
 	RelativeTimeRange time.Duration
+	// TimeRangeController is the controller of the time range, if not set
+	// it will create one based on the time range configuration.
+	TimeRangeController *TimeRangeController
 }
 
-func (a *AppConfig) defaults() {
-	const (
-		defRelativeTimeRange = 1 * time.Hour
-		defRefreshInterval   = 10 * time.Second
-	)
+const (
+	defRelativeTimeRange = 1 * time.Hour
+	defRefreshInterval   = 10 * time.Second
+)
 
+func (a *AppConfig) defaults() {
 	if a.RefreshInterval == 0 {
 		a.RefreshInterval = defRefreshInterval
 	}
 	if a.RelativeTimeRange == 0 {
 		a.RelativeTimeRange = defRelativeTimeRange
 	}
+	if a.TimeRangeController == nil {
+		a.TimeRangeController = NewTimeRangeController(a.TimeRangeStart, a.TimeRangeEnd, a.RelativeTimeRange)
+	}
 }
 
 // App represents the application that will render the metrics dashboard.
 type App struct {
-	syncer viewsync.Syncer
-	cfg    AppConfig
-	logger log.Logger
+	syncer    viewsync.Syncer
+	cfg       AppConfig
+	timeRange *TimeRangeController
+	logger    log.Logger
+
+	// cancelSync cancels the context of the last sync.
+	cancelSync context.CancelFunc
 
 	running bool
 	mu      sync.Mutex
@@ -50,9 +60,10 @@ func NewApp(cfg AppConfig, syncer viewsync.Syncer, logger log.Logger) *App {
 	cfg.defaults()
 
 	return &App{
-		cfg:    cfg,
-		syncer: syncer,
-		logger: logger,
+		cfg:       cfg,
+		syncer:    syncer,
+		timeRange: cfg.TimeRangeController,
+		logger:    logger,
 	}
 }
 
@@ -71,8 +82,13 @@ func (a *App) Run(ctx context.Context) error {
 }
 
 func (a *App) run(ctx context.Context) error {
+	// Cancel the in-flight syncs on exit.
+	defer func() {
+		a.cancelSync()
+	}()
+
 	// Start the sync loop. This operation blocks.
-	a.sync()
+	a.sync(ctx)
 
 	tk := time.NewTicker(a.cfg.RefreshInterval)
 	defer tk.Stop()
@@ -82,14 +98,27 @@ func (a *App) run(ctx context.Context) error {
 		case <-ctx.Done():
 			return nil
 		case <-tk.C:
+			// If paused, don't refresh.
+			if a.timeRange.Paused() {
+				continue
+			}
+		// The user changed the time range, sync immediately.
+		case <-a.timeRange.Changes():
 		}
 
-		a.sync()
+		a.sync(ctx)
 	}
 }
 
-func (a *App) sync() {
-	ctx := context.Background()
+// sync syncs the syncer with a context derived from the app context that
+// has the refresh interval as timeout, a new sync cancels the previous one
+// so a hung datasource doesn't block the next syncs.
+func (a *App) sync(ctx context.Context) {
+	if a.cancelSync != nil {
+		a.cancelSync()
+	}
+	ctx, a.cancelSync = context.WithTimeout(ctx, a.cfg.RefreshInterval)
+
 	r := a.syncRequest()
 	err := a.syncer.Sync(ctx, r)
 	if err != nil {
@@ -98,18 +127,12 @@ func (a *App) sync() {
 }
 
 func (a *App) syncRequest() *viewsync.Request {
+	// The time range controller knows if the time range is fixed or relative
+	// to now.
+	start, end := a.timeRange.TimeRange()
 	r := &viewsync.Request{
-		TimeRangeStart: a.cfg.TimeRangeStart,
-		TimeRangeEnd:   a.cfg.TimeRangeEnd,
-	}
-
-	// If we don't have fixed time, make the time ranges work in relative mode
-	// based on now timestamp.
-	if r.TimeRangeEnd.IsZero() {
-		r.TimeRangeEnd = time.Now().UTC()
-	}
-	if r.TimeRangeStart.IsZero() {
-		r.TimeRangeStart = r.TimeRangeEnd.Add(-1 * a.cfg.RelativeTimeRange)
+		TimeRangeStart: start,
+		TimeRangeEnd:   end,
 	}
 
 	// Create the template data for each sync.
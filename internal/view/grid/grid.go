@@ -1,6 +1,7 @@
 package grid
 
 import (
+	"fmt"
 	"math"
 	"sort"
 
@@ -9,6 +10,8 @@ import (
 
 const (
 	maxWidthPercent = 100
+	// defHeight is the height used by the widgets that don't set a height.
+	defHeight = 1
 )
 
 // Element is a "placeable" element on the grid, depending on the
@@ -30,6 +33,9 @@ type Row struct {
 	Elements []*Element
 	// PercentSize is the size in percentage of the total vertical axis.
 	PercentSize int
+	// Height is the height of the row, this is the height of the
+	// tallest widget of the row.
+	Height int
 }
 
 // Grid is the grid itself, it's composed by rows that inside of the rows
@@ -45,7 +51,8 @@ type Row struct {
 type Grid struct {
 	// Is the max size of the X axis. This is equal to a 100 percentage.
 	MaxWidth int
-	// Is the max size of the y axis. This is equal to a 100 percentage.
+	// Is the max size of the y axis (the sum of all the row heights).
+	// This is equal to a 100 percentage.
 	MaxHeight int
 	// Rows are the rows the grid has (inside the rows are the columns).
 	// the rows are vertically placed, also know as the Y axis.
@@ -104,18 +111,24 @@ func (g *Grid) fillAdaptiveGrid(widgets []model.Widget) {
 			r = g.Rows[currentRow]
 		}
 
-		// Add widget to row.
+		// Add widget to row, the row will be as tall as the tallest widget.
 		filledRow += e.PercentSize
 		r.Elements = append(r.Elements, e)
+		if h := widgetHeight(cfg); r.Height < h {
+			r.Height = h
+		}
 	}
 
 	// Set the size of the rows, the rows have been dinamically created so until
 	// we had all the rows we can't be sure what is the total of the vertical axis,
-	// set the same vertical percent size of the rows to all the rows
-	// (e.g 4 rows of 25% or 3 rows of 33% or 10 rows of 10% ).
-	totalRows := len(g.Rows) // This is the 100%.
+	// set the vertical percent size of the rows based on their height
+	// (e.g 4 rows of the same height of 25% or 3 rows of 33% or 10 rows of 10% ).
+	g.MaxHeight = 0
+	for _, row := range g.Rows {
+		g.MaxHeight += row.Height
+	}
 	for _, row := range g.Rows {
-		row.PercentSize = percent(1, totalRows)
+		row.PercentSize = percent(row.Height, g.MaxHeight)
 	}
 }
 
@@ -123,35 +136,70 @@ func (g *Grid) fillAdaptiveGrid(widgets []model.Widget) {
 // of the widgets letting empty elements between them if required. This kind
 // of grid needs the widgets to be exactly placed on the grid it doesn't adapt
 // horizontally nor vertically.
+//
+// The widgets with the same Y position are placed on the same row, and the
+// row will be as tall as the tallest widget of the row. The vertical space
+// between rows will be filled with empty rows. The widgets can't start
+// inside the rows of the previous widgets (e.g under a shorter widget of
+// the row), the rows can't be split vertically.
 func NewFixedGrid(maxWidth int, widgets []model.Widget) (*Grid, error) {
-	maxHeight := 0
-	for _, w := range widgets {
-		if maxHeight < w.GridPos.Y+1 {
-			maxHeight = w.GridPos.Y + 1
-		}
-	}
-
 	g := &Grid{
-		MaxHeight: maxHeight,
-		MaxWidth:  maxWidth,
-		Rows:      []*Row{},
+		MaxWidth: maxWidth,
+		Rows:     []*Row{},
 	}
 
-	g.fillFixedGrid(widgets)
+	err := g.fillFixedGrid(widgets)
+	if err != nil {
+		return nil, err
+	}
 
 	return g, nil
 }
 
-func (g *Grid) fillFixedGrid(widgets []model.Widget) {
+func (g *Grid) fillFixedGrid(widgets []model.Widget) error {
 	sortwidgets(widgets)
-	g.initRows()
-	// Create the widgets.
+
+	// Create the rows with the widgets.
+	var row *Row
+	rowY := 0
+	filledY := 0
 	for _, cfg := range widgets {
-		row := g.Rows[cfg.GridPos.Y]
+		// New row.
+		if row == nil || cfg.GridPos.Y != rowY {
+			if row != nil {
+				filledY = rowY + row.Height
+			}
+
+			if cfg.GridPos.Y < filledY {
+				return fmt.Errorf("%s widget starts inside the row of the widgets placed on Y %d, the widgets of a row should start on the same Y position", cfg.Title, rowY)
+			}
+
+			// If there is vertical space between the rows, fill with
+			// an empty row.
+			if filledY < cfg.GridPos.Y {
+				g.Rows = append(g.Rows, &Row{Height: cfg.GridPos.Y - filledY})
+			}
+
+			row = &Row{}
+			rowY = cfg.GridPos.Y
+			g.Rows = append(g.Rows, row)
+		}
+
 		row.Elements = append(row.Elements, &Element{
 			PercentSize: percent(cfg.GridPos.W, g.MaxWidth),
 			Widget:      cfg,
 		})
+		if h := widgetHeight(cfg); row.Height < h {
+			row.Height = h
+		}
+	}
+
+	// Set the vertical size of the rows based on their height.
+	for _, row := range g.Rows {
+		g.MaxHeight += row.Height
+	}
+	for _, row := range g.Rows {
+		row.PercentSize = percent(row.Height, g.MaxHeight)
 	}
 
 	// Fill the blank spaces between widgets for each row.
@@ -184,15 +232,17 @@ func (g *Grid) fillFixedGrid(widgets []model.Widget) {
 
 		row.Elements = rowElements
 	}
+
+	return nil
 }
 
-// initRows creates all the rows in empty state.
-func (g *Grid) initRows() {
-	for i := 0; i < g.MaxHeight; i++ {
-		g.Rows = append(g.Rows, &Row{
-			PercentSize: percent(1, g.MaxHeight),
-		})
+// widgetHeight returns the height of the widget, if not set the
+// default height will be used.
+func widgetHeight(w model.Widget) int {
+	if w.GridPos.H <= 0 {
+		return defHeight
 	}
+	return w.GridPos.H
 }
 
 func percent(value, total int) int {
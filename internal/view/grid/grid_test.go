@@ -59,10 +59,12 @@ func TestGrid(t *testing.T) {
 				return grid.NewAdaptiveGrid(maxWidth, widgets)
 			},
 			exp: &grid.Grid{
-				MaxWidth: 100,
+				MaxWidth:  100,
+				MaxHeight: 4,
 				Rows: []*grid.Row{
 					&grid.Row{
 						PercentSize: 25,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{W: 50}},
@@ -76,6 +78,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 25,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{W: 60}},
@@ -97,6 +100,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 25,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{W: 75}},
@@ -110,6 +114,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 25,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{W: 100}},
@@ -137,10 +142,12 @@ func TestGrid(t *testing.T) {
 				return grid.NewAdaptiveGrid(maxWidth, widgets)
 			},
 			exp: &grid.Grid{
-				MaxWidth: 1000,
+				MaxWidth:  1000,
+				MaxHeight: 4,
 				Rows: []*grid.Row{
 					&grid.Row{
 						PercentSize: 25,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{W: 500}},
@@ -154,6 +161,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 25,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{W: 600}},
@@ -175,6 +183,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 25,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{W: 750}},
@@ -188,6 +197,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 25,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{W: 1000}},
@@ -222,6 +232,7 @@ func TestGrid(t *testing.T) {
 				Rows: []*grid.Row{
 					&grid.Row{
 						PercentSize: 33,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{Y: 0, X: 0, W: 50}},
@@ -243,6 +254,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 33,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Empty:       true,
@@ -256,6 +268,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 33,
+						Height:      1,
 						Elements: []*grid.
 							Element{
 							&grid.Element{
@@ -315,6 +328,7 @@ func TestGrid(t *testing.T) {
 				Rows: []*grid.Row{
 					&grid.Row{
 						PercentSize: 33,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Widget:      model.Widget{GridPos: model.GridPos{Y: 0, X: 0, W: 500}},
@@ -336,6 +350,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 33,
+						Height:      1,
 						Elements: []*grid.Element{
 							&grid.Element{
 								Empty:       true,
@@ -349,6 +364,7 @@ func TestGrid(t *testing.T) {
 					},
 					&grid.Row{
 						PercentSize: 33,
+						Height:      1,
 						Elements: []*grid.
 							Element{
 							&grid.Element{
@@ -385,6 +401,139 @@ func TestGrid(t *testing.T) {
 			},
 			expErr: false,
 		},
+		{
+			name: "On adaptive grids the rows should be as tall as the tallest widget of the row.",
+			grid: func() (*grid.Grid, error) {
+				maxWidth := 100
+				widgets := []model.Widget{
+					model.Widget{GridPos: model.GridPos{W: 50, H: 2}},
+					model.Widget{GridPos: model.GridPos{W: 50, H: 6}},
+					model.Widget{GridPos: model.GridPos{W: 100}},
+					model.Widget{GridPos: model.GridPos{W: 100, H: 1}},
+				}
+
+				return grid.NewAdaptiveGrid(maxWidth, widgets)
+			},
+			exp: &grid.Grid{
+				MaxWidth:  100,
+				MaxHeight: 8,
+				Rows: []*grid.Row{
+					&grid.Row{
+						PercentSize: 75,
+						Height:      6,
+						Elements: []*grid.Element{
+							&grid.Element{
+								Widget:      model.Widget{GridPos: model.GridPos{W: 50, H: 2}},
+								PercentSize: 50,
+							},
+							&grid.Element{
+								Widget:      model.Widget{GridPos: model.GridPos{W: 50, H: 6}},
+								PercentSize: 50,
+							},
+						},
+					},
+					&grid.Row{
+						PercentSize: 13,
+						Height:      1,
+						Elements: []*grid.Element{
+							&grid.Element{
+								Widget:      model.Widget{GridPos: model.GridPos{W: 100}},
+								PercentSize: 100,
+							},
+						},
+					},
+					&grid.Row{
+						PercentSize: 13,
+						Height:      1,
+						Elements: []*grid.Element{
+							&grid.Element{
+								Widget:      model.Widget{GridPos: model.GridPos{W: 100, H: 1}},
+								PercentSize: 100,
+							},
+						},
+					},
+				},
+			},
+			expErr: false,
+		},
+		{
+			name: "On fixed grids the rows should be as tall as the tallest widget of the row and the vertical gaps should be empty rows.",
+			grid: func() (*grid.Grid, error) {
+				maxWidth := 100
+				widgets := []model.Widget{
+					model.Widget{GridPos: model.GridPos{Y: 10, X: 0, W: 100, H: 2}},
+					model.Widget{GridPos: model.GridPos{Y: 2, X: 50, W: 50, H: 6}},
+					model.Widget{GridPos: model.GridPos{Y: 2, X: 0, W: 50, H: 4}},
+				}
+
+				return grid.NewFixedGrid(maxWidth, widgets)
+			},
+			exp: &grid.Grid{
+				MaxWidth:  100,
+				MaxHeight: 12,
+				Rows: []*grid.Row{
+					&grid.Row{
+						PercentSize: 17,
+						Height:      2,
+						Elements: []*grid.Element{
+							&grid.Element{
+								Empty:       true,
+								PercentSize: 100,
+							},
+						},
+					},
+					&grid.Row{
+						PercentSize: 50,
+						Height:      6,
+						Elements: []*grid.Element{
+							&grid.Element{
+								Widget:      model.Widget{GridPos: model.GridPos{Y: 2, X: 0, W: 50, H: 4}},
+								PercentSize: 50,
+							},
+							&grid.Element{
+								Widget:      model.Widget{GridPos: model.GridPos{Y: 2, X: 50, W: 50, H: 6}},
+								PercentSize: 50,
+							},
+						},
+					},
+					&grid.Row{
+						PercentSize: 17,
+						Height:      2,
+						Elements: []*grid.Element{
+							&grid.Element{
+								Empty:       true,
+								PercentSize: 100,
+							},
+						},
+					},
+					&grid.Row{
+						PercentSize: 17,
+						Height:      2,
+						Elements: []*grid.Element{
+							&grid.Element{
+								Widget:      model.Widget{GridPos: model.GridPos{Y: 10, X: 0, W: 100, H: 2}},
+								PercentSize: 100,
+							},
+						},
+					},
+				},
+			},
+			expErr: false,
+		},
+		{
+			name: "On fixed grids a widget that starts inside the row of a taller widget should fail.",
+			grid: func() (*grid.Grid, error) {
+				maxWidth := 100
+				widgets := []model.Widget{
+					model.Widget{Title: "A", GridPos: model.GridPos{Y: 0, X: 0, W: 50, H: 16}},
+					model.Widget{Title: "B", GridPos: model.GridPos{Y: 0, X: 50, W: 50, H: 8}},
+					model.Widget{Title: "C", GridPos: model.GridPos{Y: 8, X: 50, W: 50, H: 8}},
+				}
+
+				return grid.NewFixedGrid(maxWidth, widgets)
+			},
+			expErr: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -0,0 +1,57 @@
+package view
+
+import (
+	"fmt"
+
+	"github.com/slok/grafterm/internal/service/metric/datasource"
+	"github.com/slok/grafterm/internal/service/metric/middleware"
+	"github.com/slok/grafterm/internal/view/render"
+)
+
+// DatasourceHealthReporter reports the health of the datasources that
+// a dashboard uses, the health is collected by a health gatherer middleware.
+// Satisfies render.DatasourceHealthReporter interface.
+type DatasourceHealthReporter struct {
+	tracker *middleware.HealthTracker
+	sources map[string]string
+}
+
+// NewDatasourceHealthReporter returns a new datasource health reporter
+// for the resolved datasources of a dashboard, the resolved datasources
+// are registered on the tracker so they are reported before being queried.
+func NewDatasourceHealthReporter(tracker *middleware.HealthTracker, resolutions []datasource.Resolution) *DatasourceHealthReporter {
+	sources := map[string]string{}
+	for _, r := range resolutions {
+		tracker.Register(r.ID)
+
+		source := string(r.Origin)
+		if r.Origin == datasource.OriginAlias {
+			source = fmt.Sprintf("alias of %s", r.UserID)
+		}
+		sources[r.ID] = source
+	}
+
+	return &DatasourceHealthReporter{
+		tracker: tracker,
+		sources: sources,
+	}
+}
+
+// GetDatasourcesHealth satisfies render.DatasourceHealthReporter interface.
+func (d *DatasourceHealthReporter) GetDatasourcesHealth() []render.DatasourceHealth {
+	hs := d.tracker.Health()
+	res := make([]render.DatasourceHealth, 0, len(hs))
+	for _, h := range hs {
+		res = append(res, render.DatasourceHealth{
+			ID:          h.ID,
+			Source:      d.sources[h.ID],
+			Queried:     h.Errors > 0 || !h.LastSuccess.IsZero(),
+			Failing:     h.LastErr != nil,
+			LastSuccess: h.LastSuccess,
+			Errors:      h.Errors,
+			Latency:     h.Latency,
+		})
+	}
+
+	return res
+}
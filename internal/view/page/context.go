@@ -0,0 +1,87 @@
+package page
+
+import (
+	"context"
+	gosync "sync"
+	"time"
+)
+
+// lastSyncContext stores the context of the last sync received by a page,
+// this way the page can sync again out of the app sync loop (e.g on variable
+// changes or page switches) with the same deadline rules as the app syncs.
+type lastSyncContext struct {
+	parent  context.Context
+	ctx     context.Context
+	timeout time.Duration
+	cancel  context.CancelFunc
+	mu      gosync.Mutex
+}
+
+func newLastSyncContext(parent context.Context) *lastSyncContext {
+	return &lastSyncContext{
+		parent: parent,
+		ctx:    parent,
+	}
+}
+
+// set stores the context of a received sync.
+func (l *lastSyncContext) set(ctx context.Context) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if ctx == l.ctx {
+		return
+	}
+
+	// A new sync replaces the previous resync.
+	if l.cancel != nil {
+		l.cancel()
+		l.cancel = nil
+	}
+
+	l.ctx = ctx
+	l.timeout = 0
+	if dl, ok := ctx.Deadline(); ok {
+		l.timeout = time.Until(dl)
+	}
+}
+
+// get returns the context for a resync. If the context of the last sync is
+// done (e.g the deadline has been reached) it will return a new context with
+// the same timeout.
+func (l *lastSyncContext) get() context.Context {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.ctx.Err() == nil {
+		return l.ctx
+	}
+
+	if l.cancel != nil {
+		l.cancel()
+		l.cancel = nil
+	}
+
+	l.ctx = l.parent
+	if l.timeout > 0 {
+		l.ctx, l.cancel = context.WithTimeout(l.parent, l.timeout)
+	}
+
+	return l.ctx
+}
+
+type replaceSyncKey struct{}
+
+// withReplaceSync marks the context of a sync that needs to replace the
+// in-flight syncs instead of being skipped (e.g the resyncs after a
+// variable change).
+func withReplaceSync(ctx context.Context) context.Context {
+	return context.WithValue(ctx, replaceSyncKey{}, true)
+}
+
+// replacesSync returns true if the sync of the context needs to replace the
+// in-flight syncs.
+func replacesSync(ctx context.Context) bool {
+	replace, _ := ctx.Value(replaceSyncKey{}).(bool)
+	return replace
+}
@@ -0,0 +1,44 @@
+package page
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLastSyncContext(t *testing.T) {
+	assert := assert.New(t)
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	defer cancelParent()
+	l := newLastSyncContext(parent)
+
+	// Without syncs the parent context should be used.
+	assert.Equal(parent, l.get())
+
+	// A live sync context should be reused.
+	ctx, cancel := context.WithTimeout(parent, time.Hour)
+	l.set(ctx)
+	assert.Equal(ctx, l.get())
+
+	// A finished sync context should be replaced with a new one with the same timeout.
+	cancel()
+	resyncCtx := l.get()
+	assert.NotEqual(ctx, resyncCtx)
+	assert.NoError(resyncCtx.Err())
+	dl, ok := resyncCtx.Deadline()
+	if assert.True(ok) {
+		assert.InDelta(time.Hour, time.Until(dl), float64(time.Minute))
+	}
+
+	// A new sync should cancel the resync context.
+	l.set(context.Background())
+	assert.Error(resyncCtx.Err())
+
+	// Finishing the parent should finish the resync contexts.
+	l.set(ctx)
+	cancelParent()
+	assert.Error(l.get().Err())
+}
@@ -2,11 +2,15 @@ package page
 
 import (
 	"context"
+	"fmt"
+	"sort"
+	gosync "sync"
 	"time"
 
 	"github.com/slok/grafterm/internal/controller"
 	"github.com/slok/grafterm/internal/model"
 	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/service/metric"
 	"github.com/slok/grafterm/internal/view/grid"
 	"github.com/slok/grafterm/internal/view/page/widget"
 	"github.com/slok/grafterm/internal/view/render"
@@ -18,10 +22,21 @@ import (
 // DashboardCfg is the configuration required to create a Dashboard.
 type DashboardCfg struct {
 	AppRelativeTimeRange time.Duration
+	// AppTimeRangeStart and AppTimeRangeEnd are the optional fixed time
+	// range of the app.
+	AppTimeRangeStart    time.Time
+	AppTimeRangeEnd      time.Time
 	AppOverrideVariables map[string]string
 	Controller           controller.Controller
+	Gatherer             metric.Gatherer
 	Dashboard            model.Dashboard
 	Renderer             render.Renderer
+	// DatasourceHealth is optional and reports the health of the dashboard
+	// datasources to the renderer.
+	DatasourceHealth render.DatasourceHealthReporter
+	// WidgetStaleSyncs is the number of failed syncs in a row after the
+	// data of a widget is marked as stale, 0 disables it.
+	WidgetStaleSyncs int
 }
 
 // NewDashboard returns a new syncer from a dashboard with all the required
@@ -29,10 +44,17 @@ type DashboardCfg struct {
 // The widgets the dashboard manages at the same time are syncers also.
 func NewDashboard(ctx context.Context, cfg DashboardCfg, logger log.Logger) (sync.Syncer, error) {
 	// Create variablers.
-	vs, err := variable.NewVariablers(variable.FactoryConfig{
-		TimeRange: cfg.AppRelativeTimeRange,
-		Dashboard: cfg.Dashboard,
+	vs, err := variable.NewVariablers(ctx, variable.FactoryConfig{
+		TimeRange:      cfg.AppRelativeTimeRange,
+		TimeRangeStart: cfg.AppTimeRangeStart,
+		TimeRangeEnd:   cfg.AppTimeRangeEnd,
+		Dashboard:      cfg.Dashboard,
+		Gatherer:       cfg.Gatherer,
+		Logger:         logger,
 	})
+	if err != nil {
+		return nil, err
+	}
 
 	// Create Grid.
 	var gr *grid.Grid
@@ -49,14 +71,15 @@ func NewDashboard(ctx context.Context, cfg DashboardCfg, logger log.Logger) (syn
 	}
 
 	d := &dashboard{
-		cfg:        cfg,
-		variablers: vs,
-		ctrl:       cfg.Controller,
-		logger:     logger,
+		cfg:         cfg,
+		variablers:  vs,
+		ctrl:        cfg.Controller,
+		logger:      logger,
+		lastSyncCtx: newLastSyncContext(ctx),
 	}
 
 	// Call the View to load the dashboard and return us the widgets that we will need to call.
-	renderWidgets, err := cfg.Renderer.LoadDashboard(ctx, gr)
+	renderWidgets, err := cfg.Renderer.LoadDashboard(ctx, cfg.Dashboard.Title, gr, d, cfg.DatasourceHealth)
 	if err != nil {
 		return nil, err
 	}
@@ -72,9 +95,26 @@ type dashboard struct {
 	ctrl       controller.Controller
 	variablers map[string]variable.Variabler
 	logger     log.Logger
+
+	// lastSyncCtx and lastSyncReq are the last sync request received,
+	// they are used to resync the dashboard when the variables change.
+	lastSyncCtx *lastSyncContext
+	lastSyncReq *sync.Request
+	mu          gosync.Mutex
 }
 
 func (d *dashboard) Sync(ctx context.Context, r *sync.Request) error {
+	d.lastSyncCtx.set(ctx)
+	return d.sync(ctx, r)
+}
+
+func (d *dashboard) sync(ctx context.Context, r *sync.Request) error {
+	// Store a copy of the request before adding the dashboard data.
+	d.mu.Lock()
+	lr := *r
+	d.lastSyncReq = &lr
+	d.mu.Unlock()
+
 	// Add dashboard sync data.
 	r = d.syncData(r)
 
@@ -109,6 +149,8 @@ func (d *dashboard) createWidgets(rws []render.Widget) []sync.Syncer {
 			w = widget.NewSinglestat(d.ctrl, v)
 		case render.GraphWidget:
 			w = widget.NewGraph(d.ctrl, v, d.logger)
+		case render.TableWidget:
+			w = widget.NewTable(d.ctrl, v)
 		default:
 			continue
 		}
@@ -118,7 +160,8 @@ func (d *dashboard) createWidgets(rws []render.Widget) []sync.Syncer {
 		overrideData := d.overrideVariableData()
 
 		// Widget middlewares.
-		w = withWidgetDataMiddleware(dashboardData, overrideData, w) // Assign static data to widget.
+		w = withWidgetStateMiddleware(rw, d.cfg.WidgetStaleSyncs, d.logger, w) // Show the sync errors on the widget.
+		w = withWidgetDataMiddleware(dashboardData, overrideData, w)           // Assign static data to widget.
 
 		widgets = append(widgets, w)
 	}
@@ -147,14 +190,105 @@ func (d *dashboard) staticData() template.Data {
 }
 
 func (d *dashboard) syncData(r *sync.Request) *sync.Request {
-	// Load variablers data from the sync scope.
+	// Load variablers data from the sync scope, the variables that depend
+	// on the time range use the time range of the sync (e.g after a zoom).
+	timeRange := r.TimeRangeEnd.Sub(r.TimeRangeStart)
 	data := map[string]interface{}{}
 	for vid, v := range d.variablers {
-		if v.Scope() == variable.ScopeSync {
-			data[vid] = v.GetValue()
+		if v.Scope() != variable.ScopeSync {
+			continue
+		}
+
+		if tv, ok := v.(variable.TimeRangeVariabler); ok && timeRange > 0 {
+			data[vid] = tv.GetTimeRangeValue(timeRange)
+			continue
 		}
+		data[vid] = v.GetValue()
 	}
 	r.TemplateData = r.TemplateData.WithData(data)
 
 	return r
 }
+
+// GetVariables satisfies render.VariableSelector interface.
+func (d *dashboard) GetVariables() []render.Variable {
+	vars := []render.Variable{}
+	for name, v := range d.variablers {
+		rv := render.Variable{Name: name}
+		if r, ok := v.(variable.Repeatable); ok {
+			rv.Repeatable = true
+			rv.Values = r.GetAllValues()
+			rv.Selected = r.GetValues()
+		} else {
+			rv.Values = []string{v.GetValue()}
+			rv.Selected = []string{v.GetValue()}
+		}
+		vars = append(vars, rv)
+	}
+
+	sort.Slice(vars, func(i, j int) bool {
+		return vars[i].Name < vars[j].Name
+	})
+
+	return vars
+}
+
+// SelectVariableValues satisfies render.VariableSelector interface.
+func (d *dashboard) SelectVariableValues(name string, values ...string) error {
+	r, err := d.repeatable(name)
+	if err != nil {
+		return err
+	}
+	r.Select(values...)
+	d.resync()
+
+	return nil
+}
+
+// DeselectVariableValues satisfies render.VariableSelector interface.
+func (d *dashboard) DeselectVariableValues(name string, values ...string) error {
+	r, err := d.repeatable(name)
+	if err != nil {
+		return err
+	}
+	r.Deselect(values...)
+	d.resync()
+
+	return nil
+}
+
+func (d *dashboard) repeatable(name string) (variable.Repeatable, error) {
+	v, ok := d.variablers[name]
+	if !ok {
+		return nil, fmt.Errorf("variable %s does not exist", name)
+	}
+
+	r, ok := v.(variable.Repeatable)
+	if !ok {
+		return nil, fmt.Errorf("variable %s is not repeatable", name)
+	}
+
+	return r, nil
+}
+
+// resync will sync the dashboard again in background using the last received
+// sync request, this way the changes are applied without waiting to the next
+// sync and without blocking the caller (e.g the user input handler). The
+// resync replaces the in-flight widget syncs, these would render the
+// previous data.
+func (d *dashboard) resync() {
+	d.mu.Lock()
+	if d.lastSyncReq == nil {
+		d.mu.Unlock()
+		return
+	}
+	r := *d.lastSyncReq
+	d.mu.Unlock()
+
+	go func() {
+		err := d.sync(withReplaceSync(d.lastSyncCtx.get()), &r)
+		if err != nil {
+			d.logger.Errorf("error resyncing dashboard: %s", err)
+		}
+	}()
+}
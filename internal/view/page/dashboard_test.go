@@ -0,0 +1,280 @@
+package page
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/view/render"
+	"github.com/slok/grafterm/internal/view/sync"
+	"github.com/slok/grafterm/internal/view/variable"
+)
+
+type chanWidget struct {
+	reqs chan *sync.Request
+}
+
+func (c *chanWidget) Sync(_ context.Context, r *sync.Request) error {
+	c.reqs <- r
+	return nil
+}
+
+// blockingFirstWidget blocks the first sync until its context is done.
+type blockingFirstWidget struct {
+	reqs    chan *sync.Request
+	errs    chan error
+	syncing int32
+}
+
+func (b *blockingFirstWidget) Sync(ctx context.Context, r *sync.Request) error {
+	b.reqs <- r
+	if atomic.AddInt32(&b.syncing, 1) > 1 {
+		return nil
+	}
+
+	<-ctx.Done()
+	b.errs <- ctx.Err()
+	return ctx.Err()
+}
+
+type fakeRepeatable struct {
+	values   []string
+	selected []string
+}
+
+func (f *fakeRepeatable) Scope() variable.Scope { return variable.ScopeSync }
+func (f *fakeRepeatable) IsRepeatable() bool    { return true }
+func (f *fakeRepeatable) GetValue() string      { return strings.Join(f.selected, "|") }
+func (f *fakeRepeatable) GetValues() []string   { return f.selected }
+func (f *fakeRepeatable) GetAllValues() []string {
+	return f.values
+}
+func (f *fakeRepeatable) Select(values ...string) {
+	f.selected = append(f.selected, values...)
+}
+func (f *fakeRepeatable) Deselect(values ...string) {
+	res := []string{}
+	for _, s := range f.selected {
+		deselected := false
+		for _, v := range values {
+			deselected = deselected || s == v
+		}
+		if !deselected {
+			res = append(res, s)
+		}
+	}
+	f.selected = res
+}
+
+type fakeConst struct{}
+
+func (fakeConst) Scope() variable.Scope { return variable.ScopeDashboard }
+func (fakeConst) IsRepeatable() bool    { return false }
+func (fakeConst) GetValue() string      { return "production" }
+
+func TestDashboardVariableSelector(t *testing.T) {
+	tests := []struct {
+		name        string
+		selectFunc  func(d *dashboard) error
+		expVars     []render.Variable
+		expResync   bool
+		expSyncData string
+		expErr      bool
+	}{
+		{
+			name:       "Getting the variables should return all the dashboard variables sorted.",
+			selectFunc: func(d *dashboard) error { return nil },
+			expVars: []render.Variable{
+				{Name: "env", Values: []string{"production"}, Selected: []string{"production"}},
+				{Name: "namespace", Repeatable: true, Values: []string{"dev", "prod", "staging"}, Selected: []string{"prod"}},
+			},
+		},
+		{
+			name: "Selecting values should resync the dashboard with the new variable values.",
+			selectFunc: func(d *dashboard) error {
+				return d.SelectVariableValues("namespace", "dev")
+			},
+			expVars: []render.Variable{
+				{Name: "env", Values: []string{"production"}, Selected: []string{"production"}},
+				{Name: "namespace", Repeatable: true, Values: []string{"dev", "prod", "staging"}, Selected: []string{"prod", "dev"}},
+			},
+			expResync:   true,
+			expSyncData: "prod|dev",
+		},
+		{
+			name: "Deselecting values should resync the dashboard with the new variable values.",
+			selectFunc: func(d *dashboard) error {
+				return d.DeselectVariableValues("namespace", "prod")
+			},
+			expVars: []render.Variable{
+				{Name: "env", Values: []string{"production"}, Selected: []string{"production"}},
+				{Name: "namespace", Repeatable: true, Values: []string{"dev", "prod", "staging"}, Selected: []string{}},
+			},
+			expResync:   true,
+			expSyncData: "",
+		},
+		{
+			name: "Selecting values of non repeatable variables should fail.",
+			selectFunc: func(d *dashboard) error {
+				return d.SelectVariableValues("env", "staging")
+			},
+			expErr: true,
+		},
+		{
+			name: "Selecting values of missing variables should fail.",
+			selectFunc: func(d *dashboard) error {
+				return d.SelectVariableValues("missing", "staging")
+			},
+			expErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			w := &chanWidget{reqs: make(chan *sync.Request, 10)}
+			d := &dashboard{
+				widgets: []sync.Syncer{w},
+				variablers: map[string]variable.Variabler{
+					"env": fakeConst{},
+					"namespace": &fakeRepeatable{
+						values:   []string{"dev", "prod", "staging"},
+						selected: []string{"prod"},
+					},
+				},
+				logger:      log.Dummy,
+				lastSyncCtx: newLastSyncContext(context.TODO()),
+			}
+
+			// First sync so the dashboard has a sync request.
+			require.NoError(d.Sync(context.TODO(), &sync.Request{}))
+			<-w.reqs
+
+			err := test.selectFunc(d)
+			if test.expErr {
+				assert.Error(err)
+				return
+			}
+			require.NoError(err)
+			assert.Equal(test.expVars, d.GetVariables())
+
+			// Check the resync with the new data.
+			if test.expResync {
+				select {
+				case r := <-w.reqs:
+					assert.Equal(test.expSyncData, r.TemplateData["namespace"])
+				case <-time.After(1 * time.Second):
+					assert.Fail("timeout waiting for the dashboard resync")
+				}
+			}
+		})
+	}
+}
+
+func TestDashboardVariableSelectorWhileSyncing(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	w := &blockingFirstWidget{reqs: make(chan *sync.Request, 10), errs: make(chan error, 10)}
+	d := &dashboard{
+		widgets: []sync.Syncer{withWidgetStateMiddleware(&stateRenderWidget{}, 3, log.Dummy, w)},
+		variablers: map[string]variable.Variabler{
+			"namespace": &fakeRepeatable{
+				values:   []string{"dev", "prod", "staging"},
+				selected: []string{"prod"},
+			},
+		},
+		logger:      log.Dummy,
+		lastSyncCtx: newLastSyncContext(context.TODO()),
+	}
+
+	// First sync that will be blocked until it's cancelled.
+	require.NoError(d.Sync(context.TODO(), &sync.Request{}))
+	r := <-w.reqs
+	assert.Equal("prod", r.TemplateData["namespace"])
+
+	// Selecting values while the widget is syncing should cancel the
+	// in-flight sync and sync the widget with the new variable values.
+	require.NoError(d.SelectVariableValues("namespace", "dev"))
+
+	select {
+	case err := <-w.errs:
+		assert.Equal(context.Canceled, err)
+	case <-time.After(1 * time.Second):
+		assert.Fail("timeout waiting for the in-flight sync cancellation")
+	}
+
+	select {
+	case r := <-w.reqs:
+		assert.Equal("prod|dev", r.TemplateData["namespace"])
+	case <-time.After(1 * time.Second):
+		assert.Fail("timeout waiting for the dashboard resync")
+	}
+}
+
+func TestDashboardIntervalVariableTimeRange(t *testing.T) {
+	now := time.Now()
+
+	tests := map[string]struct {
+		start       time.Time
+		end         time.Time
+		expInterval string
+	}{
+		"A sync without time range should use the interval of the initial time range.": {
+			expInterval: "1m",
+		},
+		"A sync with the initial time range should use the same interval.": {
+			start:       now.Add(-1 * time.Hour),
+			end:         now,
+			expInterval: "1m",
+		},
+		"A sync with a bigger time range (e.g zoom out) should recalculate the interval.": {
+			start:       now.Add(-10 * time.Hour),
+			end:         now,
+			expInterval: "10m",
+		},
+		"A sync with a smaller time range (e.g zoom in) should recalculate the interval.": {
+			start:       now.Add(-30 * time.Minute),
+			end:         now,
+			expInterval: "30s",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			w := &chanWidget{reqs: make(chan *sync.Request, 10)}
+			d := &dashboard{
+				widgets: []sync.Syncer{w},
+				variablers: map[string]variable.Variabler{
+					"interval": variable.NewIntervalVariabler(time.Hour, model.Variable{
+						Name: "interval",
+						VariableSource: model.VariableSource{
+							Interval: &model.IntervalVariableSource{Steps: 60},
+						},
+					}),
+				},
+				logger:      log.Dummy,
+				lastSyncCtx: newLastSyncContext(context.TODO()),
+			}
+
+			require.NoError(d.Sync(context.TODO(), &sync.Request{
+				TimeRangeStart: test.start,
+				TimeRangeEnd:   test.end,
+			}))
+			r := <-w.reqs
+			assert.Equal(test.expInterval, r.TemplateData["interval"])
+		})
+	}
+}
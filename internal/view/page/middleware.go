@@ -2,7 +2,10 @@ package page
 
 import (
 	"context"
+	gosync "sync"
 
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/view/render"
 	"github.com/slok/grafterm/internal/view/sync"
 	"github.com/slok/grafterm/internal/view/template"
 )
@@ -46,3 +49,146 @@ func (w widgetDataMiddleware) Sync(ctx context.Context, r *sync.Request) error {
 	r.TemplateData = data
 	return w.next.Sync(ctx, r)
 }
+
+// withWidgetStateMiddleware tracks the result of the widget syncs and
+// sets the state of the data on the render widget, so the user knows
+// when a widget is failing. After a number of failed syncs in a row
+// the data of the widget will be marked as stale, a successful sync
+// will clear the state.
+//
+// Only one sync is run at the same time, a sync received while the widget
+// is already syncing will be skipped and counted on the state, unless the
+// in-flight sync has been cancelled, in that case it will wait for it to
+// end. The syncs that replace the in-flight syncs (e.g resyncs after a
+// variable change) cancel the in-flight sync and wait for it to end instead
+// of being skipped. The syncs cancelled by the context don't change the
+// failed syncs.
+func withWidgetStateMiddleware(rw render.Widget, staleSyncs int, logger log.Logger, next sync.Syncer) sync.Syncer {
+	return &widgetStateMiddleware{
+		rw:         rw,
+		staleSyncs: staleSyncs,
+		logger:     logger,
+		next:       next,
+		running:    make(chan struct{}, 1),
+	}
+}
+
+type widgetStateMiddleware struct {
+	rw         render.Widget
+	staleSyncs int
+	logger     log.Logger
+	next       sync.Syncer
+	running    chan struct{}
+
+	syncCtx      context.Context
+	cancelSync   context.CancelFunc
+	failedSyncs  int
+	skippedSyncs int
+	state        render.WidgetState
+	mu           gosync.Mutex
+}
+
+func (w *widgetStateMiddleware) Sync(ctx context.Context, r *sync.Request) error {
+	ctx, cancel, ok := w.acquire(ctx)
+	if !ok {
+		return nil
+	}
+	defer func() {
+		cancel()
+		<-w.running
+	}()
+
+	err := w.next.Sync(ctx, r)
+	if ctx.Err() != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.skippedSyncs = 0
+	state := render.WidgetState{}
+	if err != nil {
+		w.failedSyncs++
+		state.Err = err
+		state.Stale = w.staleSyncs > 0 && w.failedSyncs >= w.staleSyncs
+	} else {
+		w.failedSyncs = 0
+	}
+
+	serr := w.setState(state)
+	if serr != nil && err == nil {
+		err = serr
+	}
+
+	return err
+}
+
+// acquire returns the context for the sync and its cancel function when the
+// sync can be run, if it returns false the sync has been skipped.
+func (w *widgetStateMiddleware) acquire(ctx context.Context) (context.Context, context.CancelFunc, bool) {
+	select {
+	case w.running <- struct{}{}:
+	default:
+		w.mu.Lock()
+		inFlight, cancelInFlight := w.syncCtx, w.cancelSync
+		w.mu.Unlock()
+
+		switch {
+		case replacesSync(ctx) && cancelInFlight != nil:
+			cancelInFlight()
+		case inFlight == nil || inFlight.Err() == nil:
+			w.skip()
+			return nil, nil, false
+		}
+
+		// The in-flight sync has been cancelled, it will end soon.
+		select {
+		case w.running <- struct{}{}:
+		case <-ctx.Done():
+			return nil, nil, false
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	w.mu.Lock()
+	w.syncCtx = ctx
+	w.cancelSync = cancel
+	w.mu.Unlock()
+
+	return ctx, cancel, true
+}
+
+// skip counts a skipped sync.
+func (w *widgetStateMiddleware) skip() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.skippedSyncs++
+	w.logger.Warnf("widget %s sync skipped, already syncing (%d skipped syncs)", w.rw.GetWidgetCfg().Title, w.skippedSyncs)
+
+	state := w.state
+	state.Skipped = w.skippedSyncs
+	err := w.setState(state)
+	if err != nil {
+		w.logger.Errorf("error setting widget state: %s", err)
+	}
+}
+
+// setState sets the state on the render widget only when the state
+// changes, must be called with the lock acquired.
+func (w *widgetStateMiddleware) setState(state render.WidgetState) error {
+	if sameWidgetState(state, w.state) {
+		return nil
+	}
+
+	w.state = state
+	return w.rw.SetState(state)
+}
+
+func sameWidgetState(a, b render.WidgetState) bool {
+	if a.Stale != b.Stale || a.Skipped != b.Skipped || (a.Err == nil) != (b.Err == nil) {
+		return false
+	}
+	return a.Err == nil || a.Err.Error() == b.Err.Error()
+}
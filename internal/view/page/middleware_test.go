@@ -2,10 +2,14 @@ package page
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/view/render"
 	"github.com/slok/grafterm/internal/view/sync"
 	"github.com/slok/grafterm/internal/view/template"
 )
@@ -108,3 +112,142 @@ func TestWidgetDataMiddleware(t *testing.T) {
 		})
 	}
 }
+
+type errorsWidget struct {
+	errs []error
+}
+
+func (e *errorsWidget) Sync(_ context.Context, r *sync.Request) error {
+	err := e.errs[0]
+	e.errs = e.errs[1:]
+	return err
+}
+
+type stateRenderWidget struct {
+	render.Widget
+	states []render.WidgetState
+}
+
+func (s *stateRenderWidget) SetState(state render.WidgetState) error {
+	s.states = append(s.states, state)
+	return nil
+}
+
+func (s *stateRenderWidget) GetWidgetCfg() model.Widget {
+	return model.Widget{Title: "test"}
+}
+
+func TestWidgetStateMiddleware(t *testing.T) {
+	err1 := errors.New("wanted error 1")
+	err2 := errors.New("wanted error 2")
+
+	tests := map[string]struct {
+		staleSyncs int
+		syncErrs   []error
+		expStates  []render.WidgetState
+	}{
+		"Successful syncs shouldn't change the state.": {
+			staleSyncs: 3,
+			syncErrs:   []error{nil, nil, nil},
+		},
+		"A failed sync should set the error and a successful sync should clear it.": {
+			staleSyncs: 3,
+			syncErrs:   []error{nil, err1, nil},
+			expStates: []render.WidgetState{
+				{Err: err1},
+				{},
+			},
+		},
+		"Failing multiple times in a row should mark the data as stale.": {
+			staleSyncs: 3,
+			syncErrs:   []error{err1, err1, err2, err2, nil},
+			expStates: []render.WidgetState{
+				{Err: err1},
+				{Err: err2, Stale: true},
+				{},
+			},
+		},
+		"The failed syncs count should be reset after a successful sync.": {
+			staleSyncs: 2,
+			syncErrs:   []error{err1, nil, err1, nil},
+			expStates: []render.WidgetState{
+				{Err: err1},
+				{},
+				{Err: err1},
+				{},
+			},
+		},
+		"Without stale syncs the data shouldn't be marked as stale.": {
+			staleSyncs: 0,
+			syncErrs:   []error{err1, err1, err1},
+			expStates: []render.WidgetState{
+				{Err: err1},
+			},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			rw := &stateRenderWidget{}
+			w := withWidgetStateMiddleware(rw, test.staleSyncs, log.Dummy, &errorsWidget{errs: test.syncErrs})
+			for _, expErr := range test.syncErrs {
+				err := w.Sync(context.TODO(), &sync.Request{})
+				assert.Equal(expErr, err)
+			}
+
+			assert.Equal(test.expStates, rw.states)
+		})
+	}
+}
+
+type blockingWidget struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingWidget) Sync(ctx context.Context, _ *sync.Request) error {
+	b.started <- struct{}{}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.release:
+		return nil
+	}
+}
+
+func TestWidgetStateMiddlewareSkippedSyncs(t *testing.T) {
+	assert := assert.New(t)
+
+	rw := &stateRenderWidget{}
+	bw := &blockingWidget{started: make(chan struct{}, 2), release: make(chan struct{}, 2)}
+	w := withWidgetStateMiddleware(rw, 3, log.Dummy, bw)
+
+	// Start a sync that will be in-flight.
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	defer cancel1()
+	done1 := make(chan error)
+	go func() { done1 <- w.Sync(ctx1, &sync.Request{}) }()
+	<-bw.started
+
+	// Syncs while the in-flight sync is running should be skipped.
+	assert.NoError(w.Sync(context.Background(), &sync.Request{}))
+	assert.NoError(w.Sync(context.Background(), &sync.Request{}))
+
+	// Cancelling the in-flight sync should let the new sync run.
+	done2 := make(chan error)
+	go func() { done2 <- w.Sync(context.Background(), &sync.Request{}) }()
+	cancel1()
+	assert.Equal(context.Canceled, <-done1)
+	<-bw.started
+	bw.release <- struct{}{}
+	assert.NoError(<-done2)
+
+	expStates := []render.WidgetState{
+		{Skipped: 1},
+		{Skipped: 2},
+		{},
+	}
+	assert.Equal(expStates, rw.states)
+}
@@ -0,0 +1,117 @@
+package page
+
+import (
+	"context"
+	"fmt"
+	gosync "sync"
+	"time"
+
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/view/render"
+	"github.com/slok/grafterm/internal/view/sync"
+)
+
+// PlaylistCfg is the configuration required to create a Playlist.
+type PlaylistCfg struct {
+	// Pages are the dashboard pages in the same order they have been
+	// loaded on the renderer.
+	Pages []sync.Syncer
+	// Interval is the interval the pages will be rotated automatically,
+	// if zero the pages will not be rotated.
+	Interval time.Duration
+	Renderer render.Renderer
+}
+
+// NewPlaylist returns a new syncer that manages multiple dashboard pages, only
+// the visible page will be synced. The playlist will let the renderer switch the
+// visible page and if an interval is set, it will rotate the pages until the
+// context is done.
+func NewPlaylist(ctx context.Context, cfg PlaylistCfg, logger log.Logger) (sync.Syncer, error) {
+	if len(cfg.Pages) == 0 {
+		return nil, fmt.Errorf("a playlist needs at least one page")
+	}
+
+	p := &playlist{
+		cfg:         cfg,
+		logger:      logger,
+		lastSyncCtx: newLastSyncContext(ctx),
+	}
+	cfg.Renderer.SetPageSwitcher(p)
+
+	if cfg.Interval > 0 && len(cfg.Pages) > 1 {
+		go p.rotate(ctx)
+	}
+
+	return p, nil
+}
+
+type playlist struct {
+	cfg     PlaylistCfg
+	current int
+	logger  log.Logger
+
+	// lastSyncCtx and lastSyncReq are the last sync request received,
+	// they are used to sync the pages when they are switched.
+	lastSyncCtx *lastSyncContext
+	lastSyncReq *sync.Request
+	mu          gosync.Mutex
+}
+
+func (p *playlist) Sync(ctx context.Context, r *sync.Request) error {
+	p.lastSyncCtx.set(ctx)
+
+	p.mu.Lock()
+	lr := *r
+	p.lastSyncReq = &lr
+	page := p.cfg.Pages[p.current]
+	p.mu.Unlock()
+
+	// Only sync the visible page.
+	return page.Sync(ctx, r)
+}
+
+// SwitchPage satisfies render.PageSwitcher interface.
+func (p *playlist) SwitchPage(index int) error {
+	if index < 0 || index >= len(p.cfg.Pages) {
+		return fmt.Errorf("page %d does not exist", index)
+	}
+
+	p.mu.Lock()
+	p.current = index
+	r := p.lastSyncReq
+	p.mu.Unlock()
+
+	err := p.cfg.Renderer.ShowPage(index)
+	if err != nil {
+		return err
+	}
+
+	// Sync the new visible page without waiting to the next sync.
+	if r == nil {
+		return nil
+	}
+	rc := *r
+	return p.Sync(p.lastSyncCtx.get(), &rc)
+}
+
+// rotate will switch to the next page on every interval.
+func (p *playlist) rotate(ctx context.Context) {
+	tk := time.NewTicker(p.cfg.Interval)
+	defer tk.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tk.C:
+		}
+
+		p.mu.Lock()
+		next := (p.current + 1) % len(p.cfg.Pages)
+		p.mu.Unlock()
+
+		err := p.SwitchPage(next)
+		if err != nil {
+			p.logger.Errorf("error rotating playlist page: %s", err)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package page_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+
+	mrender "github.com/slok/grafterm/internal/mocks/view/render"
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/view/page"
+	"github.com/slok/grafterm/internal/view/render"
+	"github.com/slok/grafterm/internal/view/sync"
+)
+
+type countSyncer struct {
+	reqs chan *sync.Request
+}
+
+func (c *countSyncer) Sync(_ context.Context, r *sync.Request) error {
+	c.reqs <- r
+	return nil
+}
+
+func newCountSyncer() *countSyncer {
+	return &countSyncer{reqs: make(chan *sync.Request, 10)}
+}
+
+func TestPlaylist(t *testing.T) {
+	tests := []struct {
+		name          string
+		interval      time.Duration
+		switchPage    int
+		expShowPage   bool
+		expResync     bool
+		expSyncedPage int
+		expErr        bool
+	}{
+		{
+			name:          "Without switching only the first page should be synced.",
+			switchPage:    -1,
+			expSyncedPage: 0,
+		},
+		{
+			name:          "Switching the page should show the page and sync it with the last request.",
+			switchPage:    2,
+			expShowPage:   true,
+			expResync:     true,
+			expSyncedPage: 2,
+		},
+		{
+			name:       "Switching to a missing page should fail.",
+			switchPage: 3,
+			expErr:     true,
+		},
+		{
+			name:          "With an interval the pages should be rotated.",
+			interval:      100 * time.Millisecond,
+			switchPage:    -1,
+			expShowPage:   true,
+			expResync:     true,
+			expSyncedPage: 1,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+			require := require.New(t)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			pages := []*countSyncer{newCountSyncer(), newCountSyncer(), newCountSyncer()}
+			syncers := []sync.Syncer{pages[0], pages[1], pages[2]}
+
+			mr := &mrender.Renderer{}
+			mr.On("SetPageSwitcher", mock.Anything).Once()
+			if test.expShowPage {
+				mr.On("ShowPage", test.expSyncedPage).Return(nil)
+			}
+
+			p, err := page.NewPlaylist(ctx, page.PlaylistCfg{
+				Pages:    syncers,
+				Interval: test.interval,
+				Renderer: mr,
+			}, log.Dummy)
+			require.NoError(err)
+
+			// First sync, only the first page should be synced.
+			require.NoError(p.Sync(context.TODO(), &sync.Request{TimeRangeEnd: time.Unix(1, 0)}))
+			r := <-pages[0].reqs
+			assert.Equal(time.Unix(1, 0), r.TimeRangeEnd)
+
+			if test.switchPage >= 0 {
+				err = p.(render.PageSwitcher).SwitchPage(test.switchPage)
+				if test.expErr {
+					assert.Error(err)
+					return
+				}
+				require.NoError(err)
+			}
+
+			if test.expResync {
+				select {
+				case r := <-pages[test.expSyncedPage].reqs:
+					assert.Equal(time.Unix(1, 0), r.TimeRangeEnd)
+				case <-time.After(1 * time.Second):
+					assert.Fail("timeout waiting for the page sync")
+				}
+			}
+			cancel()
+
+			// The other pages shouldn't be synced.
+			for i, pg := range pages {
+				if i != test.expSyncedPage {
+					assert.Len(pg.reqs, 0)
+				}
+			}
+
+			mr.AssertExpectations(t)
+		})
+	}
+}
@@ -2,7 +2,10 @@ package widget
 
 import (
 	"context"
+	"fmt"
 	"sort"
+	"strings"
+	gosync "sync"
 	"time"
 
 	"github.com/slok/grafterm/internal/controller"
@@ -16,6 +19,7 @@ import (
 
 const (
 	graphPointQuantityRetries = 5
+	defGraphQueryConcurrency  = 5
 )
 
 // graph is a widget that represents values in a two axis graph.
@@ -71,18 +75,73 @@ func (g *graph) Sync(ctx context.Context, r *sync.Request) error {
 	start := r.TimeRangeStart
 	end := r.TimeRangeEnd
 	step := end.Sub(start) / time.Duration(cap)
+	allSeries, ok, err := g.gatherSeries(ctx, r, start, end, step)
+	if !ok {
+		return err
+	}
+
+	// Merge sort all series.
+	metrics := g.sortSeries(allSeries)
+
+	// Transform metric to the ones the render part understands.
+	xLabels, indexedTime := g.createIndexedSlices(start, end, step, cap)
+	series := g.transformToRenderable(r, metrics, xLabels, indexedTime)
+
+	// Update the render view value.
+	g.rendererWidget.Sync(series)
+
+	// Partial failures render the series of the successful queries (even
+	// if these don't have series) but the sync is still an error.
+	return err
+}
+
+// gatherSeries gathers the series of all the graph queries concurrently. If
+// any of the queries fails it will return the series of the successful
+// queries and an error with the failed queries, the returned bool is false
+// when all the queries failed.
+func (g *graph) gatherSeries(ctx context.Context, r *sync.Request, start, end time.Time, step time.Duration) ([]metricSeries, bool, error) {
+	queries := g.widgetCfg.Graph.Queries
+	concurrency := g.widgetCfg.Graph.QueryConcurrency
+	if concurrency <= 0 {
+		concurrency = defGraphQueryConcurrency
+	}
+
+	type result struct {
+		series []model.MetricSeries
+		err    error
+	}
+	results := make([]result, len(queries))
+	sem := make(chan struct{}, concurrency)
+	var wg gosync.WaitGroup
+	for i, q := range queries {
+		i, q := i, q
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			templatedQ := q
+			templatedQ.Expr = r.TemplateData.Render(q.Expr)
+			series, err := g.controller.GetRangeMetrics(ctx, templatedQ, start, end, step)
+			results[i] = result{series: series, err: err}
+		}()
+	}
+	wg.Wait()
+
+	// Append all received series in query order.
 	allSeries := []metricSeries{}
-	for _, q := range g.widgetCfg.Graph.Queries {
-		//TODO(slok): concurrent queries.
-		templatedQ := q
-		templatedQ.Expr = r.TemplateData.Render(q.Expr)
-		series, err := g.controller.GetRangeMetrics(ctx, templatedQ, start, end, step)
-		if err != nil {
-			return err
+	errs := []string{}
+	for i, res := range results {
+		q := queries[i]
+		if res.err != nil {
+			errs = append(errs, fmt.Sprintf("query %d on %s: %s", i+1, q.DatasourceID, res.err))
+			continue
 		}
 
-		// Append all received series.
-		for _, serie := range series {
+		for _, serie := range res.series {
 			ms := metricSeries{
 				query:  q,
 				series: serie,
@@ -91,16 +150,11 @@ func (g *graph) Sync(ctx context.Context, r *sync.Request) error {
 		}
 	}
 
-	// Merge sort all series.
-	metrics := g.sortSeries(allSeries)
-
-	// Transform metric to the ones the render part understands.
-	xLabels, indexedTime := g.createIndexedSlices(start, end, step, cap)
-	series := g.transformToRenderable(r, metrics, xLabels, indexedTime)
+	if len(errs) > 0 {
+		return allSeries, len(errs) < len(queries), fmt.Errorf("%d of %d queries failed: %s", len(errs), len(queries), strings.Join(errs, "; "))
+	}
 
-	// Update the render view value.
-	g.rendererWidget.Sync(series)
-	return nil
+	return allSeries, true, nil
 }
 
 func (g *graph) sortSeries(allseries []metricSeries) []metricSeries {
@@ -2,6 +2,7 @@ package widget_test
 
 import (
 	"context"
+	"errors"
 	"regexp"
 	"testing"
 	"time"
@@ -458,6 +459,101 @@ func TestGraphWidget(t *testing.T) {
 				mg.On("Sync", series).Return(nil)
 			},
 		},
+		{
+			name: "A graph with failed queries should render the series of the successful queries and fail.",
+			syncReq: &sync.Request{
+				TimeRangeEnd:   t1,
+				TimeRangeStart: t1Minus100m,
+			},
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Graph: &model.GraphWidgetSource{
+						Queries: []model.Query{
+							model.Query{Expr: "test1"},
+							model.Query{Expr: "test2"},
+							model.Query{Expr: "test3"},
+						},
+						QueryConcurrency: 2,
+					},
+				},
+			},
+			exp: func(t *testing.T, mc *mcontroller.Controller, mg *mrender.GraphWidget) {
+				mg.On("GetGraphPointQuantity").Return(graphCapacity)
+
+				seriess := []model.MetricSeries{
+					model.MetricSeries{
+						ID: "test3",
+						Metrics: []model.Metric{
+							model.Metric{Value: 5, TS: t1Minus100m.Add(46 * time.Minute)},
+						},
+					},
+				}
+				expStep := 10 * time.Minute
+				mc.On("GetRangeMetrics", mock.Anything, model.Query{Expr: "test1"}, t1Minus100m, t1, expStep).Once().Return(nil, errors.New("wanted"))
+				mc.On("GetRangeMetrics", mock.Anything, model.Query{Expr: "test2"}, t1Minus100m, t1, expStep).Once().Return(nil, errors.New("wanted"))
+				mc.On("GetRangeMetrics", mock.Anything, model.Query{Expr: "test3"}, t1Minus100m, t1, expStep).Once().Return(seriess, nil)
+
+				series := []render.Series{
+					render.Series{
+						Label:   "test3",
+						Color:   "#7EB26D", // First color.
+						XLabels: xLabels,
+						Values:  []*render.Value{nil, nil, nil, nil, rv(5), nil, nil, nil, nil, nil},
+					},
+				}
+				mg.On("Sync", series).Once().Return(nil)
+			},
+			expErr: true,
+		},
+		{
+			name: "A graph with failed queries and successful queries without series should render the empty series and fail.",
+			syncReq: &sync.Request{
+				TimeRangeEnd:   t1,
+				TimeRangeStart: t1Minus100m,
+			},
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Graph: &model.GraphWidgetSource{
+						Queries: []model.Query{
+							model.Query{Expr: "test1"},
+							model.Query{Expr: "test2"},
+						},
+					},
+				},
+			},
+			exp: func(t *testing.T, mc *mcontroller.Controller, mg *mrender.GraphWidget) {
+				mg.On("GetGraphPointQuantity").Return(graphCapacity)
+
+				expStep := 10 * time.Minute
+				mc.On("GetRangeMetrics", mock.Anything, model.Query{Expr: "test1"}, t1Minus100m, t1, expStep).Once().Return(nil, errors.New("wanted"))
+				mc.On("GetRangeMetrics", mock.Anything, model.Query{Expr: "test2"}, t1Minus100m, t1, expStep).Once().Return([]model.MetricSeries{}, nil)
+
+				mg.On("Sync", []render.Series{}).Once().Return(nil)
+			},
+			expErr: true,
+		},
+		{
+			name: "A graph with all the queries failed should not render anything.",
+			syncReq: &sync.Request{
+				TimeRangeEnd:   t1,
+				TimeRangeStart: t1Minus100m,
+			},
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Graph: &model.GraphWidgetSource{
+						Queries: []model.Query{
+							model.Query{Expr: "test1"},
+							model.Query{Expr: "test2"},
+						},
+					},
+				},
+			},
+			exp: func(t *testing.T, mc *mcontroller.Controller, mg *mrender.GraphWidget) {
+				mg.On("GetGraphPointQuantity").Return(graphCapacity)
+				mc.On("GetRangeMetrics", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Twice().Return(nil, errors.New("wanted"))
+			},
+			expErr: true,
+		},
 	}
 
 	for _, test := range tests {
@@ -475,10 +571,11 @@ func TestGraphWidget(t *testing.T) {
 
 			if test.expErr {
 				assert.Error(err)
-			} else if assert.NoError(err) {
-				mc.AssertExpectations(t)
-				mgraph.AssertExpectations(t)
+			} else {
+				assert.NoError(err)
 			}
+			mc.AssertExpectations(t)
+			mgraph.AssertExpectations(t)
 		})
 	}
 }
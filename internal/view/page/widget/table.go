@@ -0,0 +1,203 @@
+package widget
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/slok/grafterm/internal/controller"
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/unit"
+	"github.com/slok/grafterm/internal/view/render"
+	"github.com/slok/grafterm/internal/view/sync"
+)
+
+// tableRow is a row of the table before being rendered.
+type tableRow struct {
+	series model.MetricSeries
+	value  float64
+	cells  []render.TableCell
+}
+
+// table is a widget that represents multiple series in rows.
+type table struct {
+	controller     controller.Controller
+	rendererWidget render.TableWidget
+	cfg            model.Widget
+	syncLock       syncingFlag
+}
+
+// NewTable returns a new Table widget syncer.
+func NewTable(controller controller.Controller, rendererWidget render.TableWidget) sync.Syncer {
+	cfg := rendererWidget.GetWidgetCfg()
+
+	// Sort widget thresholds. Optimization so we don't have to sort every time we calculate
+	// a color.
+	sort.Slice(cfg.Table.Thresholds, func(i, j int) bool {
+		return cfg.Table.Thresholds[i].StartValue < cfg.Table.Thresholds[j].StartValue
+	})
+
+	return &table{
+		controller:     controller,
+		rendererWidget: rendererWidget,
+		cfg:            cfg,
+	}
+}
+
+func (t *table) Sync(ctx context.Context, r *sync.Request) error {
+	// If already syncinc ignore call.
+	if t.syncLock.Get() {
+		return nil
+	}
+	// If didn't changed the value means some other sync process
+	// already entered before us.
+	if !t.syncLock.Set(true) {
+		return nil
+	}
+	defer t.syncLock.Set(false)
+
+	// Gather the series.
+	templatedQ := t.cfg.Table.Query
+	templatedQ.Expr = r.TemplateData.Render(templatedQ.Expr)
+	series, err := t.controller.GetMetrics(ctx, templatedQ, r.TimeRangeEnd)
+	if err != nil {
+		return fmt.Errorf("error getting metrics: %s", err)
+	}
+
+	tbl, err := t.seriesToTable(series)
+	if err != nil {
+		return err
+	}
+
+	err = t.rendererWidget.Sync(tbl)
+	if err != nil {
+		return fmt.Errorf("error setting table on render view widget: %s", err)
+	}
+
+	return nil
+}
+
+// seriesToTable will transform the series in a table with one row per series.
+func (t *table) seriesToTable(series []model.MetricSeries) (render.Table, error) {
+	wcfg := t.cfg.Table
+	f, err := unit.NewUnitFormatter(wcfg.Unit)
+	if err != nil {
+		return render.Table{}, err
+	}
+
+	columns := t.columns(series)
+	rows := make([]tableRow, 0, len(series))
+	for _, s := range series {
+		value := s.Metrics[0].Value
+		row := tableRow{
+			series: s,
+			value:  value,
+			cells:  make([]render.TableCell, 0, len(columns)),
+		}
+
+		for _, c := range columns {
+			if c != model.TableValueColumn {
+				row.cells = append(row.cells, render.TableCell{Text: s.Labels[c]})
+				continue
+			}
+
+			cell := render.TableCell{Text: f(value, wcfg.Decimals)}
+			if len(wcfg.Thresholds) > 0 {
+				color, err := widgetColorManager{}.GetColorFromThresholds(wcfg.Thresholds, value)
+				if err != nil {
+					return render.Table{}, fmt.Errorf("error getting threshold color: %s", err)
+				}
+				cell.Color = color
+			}
+			row.cells = append(row.cells, cell)
+		}
+
+		rows = append(rows, row)
+	}
+
+	t.sortRows(rows, columns)
+
+	tbl := render.Table{
+		Headers: columns,
+		Rows:    make([][]render.TableCell, 0, len(rows)),
+	}
+	for _, row := range rows {
+		tbl.Rows = append(tbl.Rows, row.cells)
+	}
+
+	return tbl, nil
+}
+
+// columns returns the configured columns, if not configured it will return
+// all the labels of the series sorted followed by the value column.
+func (t *table) columns(series []model.MetricSeries) []string {
+	if len(t.cfg.Table.Columns) > 0 {
+		return t.cfg.Table.Columns
+	}
+
+	labels := map[string]struct{}{}
+	for _, s := range series {
+		for k := range s.Labels {
+			labels[k] = struct{}{}
+		}
+	}
+
+	columns := make([]string, 0, len(labels)+1)
+	for k := range labels {
+		columns = append(columns, k)
+	}
+	sort.Strings(columns)
+
+	return append(columns, model.TableValueColumn)
+}
+
+// sortRows will sort the rows using the configured sort column (by default
+// the first one), the value column is sorted numerically. The ties are sorted
+// by the rest of the columns in order and by the series ID, so the row order
+// is stable between syncs.
+func (t *table) sortRows(rows []tableRow, columns []string) {
+	sortCfg := t.cfg.Table.Sort
+	sortColumns := columns
+	if sortCfg.Column != "" {
+		sortColumns = append([]string{sortCfg.Column}, columns...)
+	}
+
+	sort.Slice(rows, func(i, j int) bool {
+		a, b := rows[i], rows[j]
+		for _, c := range sortColumns {
+			cmp := compareTableColumn(a, b, c)
+			if cmp == 0 {
+				continue
+			}
+			// Descending order only applies to the sort column.
+			if sortCfg.Desc && c == sortColumns[0] {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return a.series.ID < b.series.ID
+	})
+}
+
+// compareTableColumn compares the column of two rows, returns -1 if a is less
+// than b, 1 if is greater and 0 if is equal.
+func compareTableColumn(a, b tableRow, column string) int {
+	if column == model.TableValueColumn {
+		switch {
+		case a.value < b.value:
+			return -1
+		case a.value > b.value:
+			return 1
+		}
+		return 0
+	}
+
+	av, bv := a.series.Labels[column], b.series.Labels[column]
+	switch {
+	case av < bv:
+		return -1
+	case av > bv:
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,207 @@
+package widget_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mcontroller "github.com/slok/grafterm/internal/mocks/controller"
+	mrender "github.com/slok/grafterm/internal/mocks/view/render"
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/view/page/widget"
+	"github.com/slok/grafterm/internal/view/render"
+	"github.com/slok/grafterm/internal/view/sync"
+	"github.com/slok/grafterm/internal/view/template"
+)
+
+func TestTableWidget(t *testing.T) {
+	series := []model.MetricSeries{
+		{
+			ID:      "s1",
+			Labels:  map[string]string{"pod": "pod-b", "namespace": "prod"},
+			Metrics: []model.Metric{{Value: 12.5}},
+		},
+		{
+			ID:      "s2",
+			Labels:  map[string]string{"pod": "pod-a", "namespace": "prod"},
+			Metrics: []model.Metric{{Value: 3}},
+		},
+		{
+			ID:      "s3",
+			Labels:  map[string]string{"pod": "pod-c", "namespace": "dev", "node": "n1"},
+			Metrics: []model.Metric{{Value: 25.129}},
+		},
+	}
+
+	tests := []struct {
+		name              string
+		cfg               model.Widget
+		syncReq           *sync.Request
+		controllerSeries  []model.MetricSeries
+		controllerErr     error
+		expQuery          model.Query
+		expTable          render.Table
+		expErr            bool
+		expRendererCalled bool
+	}{
+		{
+			name: "A table without columns should render all the labels sorted and the value, sorted by the first column.",
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Table: &model.TableWidgetSource{
+						ValueRepresentation: model.ValueRepresentation{Unit: "none", Decimals: 1},
+					},
+				},
+			},
+			syncReq:          &sync.Request{},
+			controllerSeries: series,
+			expTable: render.Table{
+				Headers: []string{"namespace", "node", "pod", "value"},
+				Rows: [][]render.TableCell{
+					{{Text: "dev"}, {Text: "n1"}, {Text: "pod-c"}, {Text: "25.1"}},
+					{{Text: "prod"}, {Text: ""}, {Text: "pod-a"}, {Text: "3.0"}},
+					{{Text: "prod"}, {Text: ""}, {Text: "pod-b"}, {Text: "12.5"}},
+				},
+			},
+			expRendererCalled: true,
+		},
+		{
+			name: "A table with columns should render only the selected columns in order.",
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Table: &model.TableWidgetSource{
+						ValueRepresentation: model.ValueRepresentation{Unit: "none", Decimals: 1},
+						Columns:             []string{"value", "pod"},
+					},
+				},
+			},
+			syncReq:          &sync.Request{},
+			controllerSeries: series,
+			expTable: render.Table{
+				Headers: []string{"value", "pod"},
+				Rows: [][]render.TableCell{
+					{{Text: "3.0"}, {Text: "pod-a"}},
+					{{Text: "12.5"}, {Text: "pod-b"}},
+					{{Text: "25.1"}, {Text: "pod-c"}},
+				},
+			},
+			expRendererCalled: true,
+		},
+		{
+			name: "A table sorted by a label in descending order should render the rows sorted.",
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Table: &model.TableWidgetSource{
+						ValueRepresentation: model.ValueRepresentation{Unit: "none", Decimals: 1},
+						Columns:             []string{"pod", "value"},
+						Sort:                model.TableSort{Column: "pod", Desc: true},
+					},
+				},
+			},
+			syncReq:          &sync.Request{},
+			controllerSeries: series,
+			expTable: render.Table{
+				Headers: []string{"pod", "value"},
+				Rows: [][]render.TableCell{
+					{{Text: "pod-c"}, {Text: "25.1"}},
+					{{Text: "pod-b"}, {Text: "12.5"}},
+					{{Text: "pod-a"}, {Text: "3.0"}},
+				},
+			},
+			expRendererCalled: true,
+		},
+		{
+			name: "A table sorted by value should sort numerically and set the (unordered) thresholds colors on the value cells.",
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Table: &model.TableWidgetSource{
+						ValueRepresentation: model.ValueRepresentation{Unit: "none", Decimals: 1},
+						Columns:             []string{"pod", "value"},
+						Sort:                model.TableSort{Column: "value", Desc: true},
+						Thresholds: []model.Threshold{
+							{Color: "#000020", StartValue: 20},
+							{Color: "#000000"},
+							{Color: "#000010", StartValue: 10},
+						},
+					},
+				},
+			},
+			syncReq:          &sync.Request{},
+			controllerSeries: series,
+			expTable: render.Table{
+				Headers: []string{"pod", "value"},
+				Rows: [][]render.TableCell{
+					{{Text: "pod-c"}, {Text: "25.1", Color: "#000020"}},
+					{{Text: "pod-b"}, {Text: "12.5", Color: "#000010"}},
+					{{Text: "pod-a"}, {Text: "3.0", Color: "#000000"}},
+				},
+			},
+			expRendererCalled: true,
+		},
+		{
+			name: "A table should make templated queries with variables.",
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Table: &model.TableWidgetSource{
+						Query:   model.Query{Expr: "up{namespace=\"{{ .namespace }}\"}"},
+						Columns: []string{"pod"},
+					},
+				},
+			},
+			syncReq: &sync.Request{
+				TemplateData: template.Data(map[string]interface{}{
+					"namespace": "dev",
+				}),
+			},
+			controllerSeries: series[2:],
+			expQuery:         model.Query{Expr: "up{namespace=\"dev\"}"},
+			expTable: render.Table{
+				Headers: []string{"pod"},
+				Rows: [][]render.TableCell{
+					{{Text: "pod-c"}},
+				},
+			},
+			expRendererCalled: true,
+		},
+		{
+			name: "An error getting the metrics should fail.",
+			cfg: model.Widget{
+				WidgetSource: model.WidgetSource{
+					Table: &model.TableWidgetSource{},
+				},
+			},
+			syncReq:       &sync.Request{},
+			controllerErr: errors.New("wanted error"),
+			expErr:        true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// Mocks.
+			mtable := &mrender.TableWidget{}
+			mtable.On("GetWidgetCfg").Once().Return(test.cfg)
+			if test.expRendererCalled {
+				mtable.On("Sync", test.expTable).Once().Return(nil)
+			}
+
+			mc := &mcontroller.Controller{}
+			mc.On("GetMetrics", mock.Anything, test.expQuery, mock.Anything).Return(test.controllerSeries, test.controllerErr)
+
+			table := widget.NewTable(mc, mtable)
+			err := table.Sync(context.Background(), test.syncReq)
+
+			if test.expErr {
+				assert.Error(err)
+			} else if assert.NoError(err) {
+				mc.AssertExpectations(t)
+				mtable.AssertExpectations(t)
+			}
+		})
+	}
+}
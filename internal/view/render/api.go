@@ -2,6 +2,7 @@ package render
 
 import (
 	"context"
+	"time"
 
 	"github.com/slok/grafterm/internal/model"
 	"github.com/slok/grafterm/internal/view/grid"
@@ -10,13 +11,122 @@ import (
 // Renderer is the interface that knows how to load a dashboard to be rendered
 // in some target of UI.
 type Renderer interface {
-	LoadDashboard(ctx context.Context, grid *grid.Grid) ([]Widget, error)
+	// LoadDashboard loads the dashboard grid as a new page and returns the widgets
+	// that have been created, the variable selector is optional and will let the
+	// user select the dashboard variable values, the datasource health reporter
+	// is optional and will show the health of the dashboard datasources. The first
+	// loaded page will be the visible one.
+	LoadDashboard(ctx context.Context, title string, grid *grid.Grid, selector VariableSelector, health DatasourceHealthReporter) ([]Widget, error)
+	// ShowPage shows the page of the dashboard loaded in the index position
+	// (in load order).
+	ShowPage(index int) error
+	// SetPageSwitcher sets the page switcher that will be used when the
+	// user switches between the loaded dashboard pages.
+	SetPageSwitcher(switcher PageSwitcher)
 	Close()
 }
 
+// PageSwitcher knows how to switch the visible dashboard page, switching a
+// page will show the page and sync it.
+type PageSwitcher interface {
+	// SwitchPage switches to the page on the index position (in load order).
+	SwitchPage(index int) error
+}
+
+// TimeRange is the time range of the dashboard.
+type TimeRange struct {
+	Start time.Time
+	End   time.Time
+	// Live is true when the time range is relative to now.
+	Live bool
+	// Paused is true when the refresh of the dashboard is paused.
+	Paused bool
+}
+
+// TimeRangeController knows how to control the time range of the dashboard,
+// the renderers use it to let the user navigate through time.
+type TimeRangeController interface {
+	// GetTimeRange returns the current time range.
+	GetTimeRange() TimeRange
+	// ZoomIn halves the time range.
+	ZoomIn()
+	// ZoomOut doubles the time range.
+	ZoomOut()
+	// PanBackward moves the time range half of the time range to the past.
+	PanBackward()
+	// PanForward moves the time range half of the time range to the future.
+	PanForward()
+	// Live returns the time range to now.
+	Live()
+	// TogglePause pauses or resumes the refresh.
+	TogglePause()
+}
+
+// Variable is a dashboard variable with its values.
+type Variable struct {
+	Name string
+	// Repeatable is true if the selected values of the variable can be changed.
+	Repeatable bool
+	// Values are all the available values of the variable.
+	Values []string
+	// Selected are the values of the variable that are being used.
+	Selected []string
+}
+
+// VariableSelector knows how to get the dashboard variables and change
+// the selected values of them. Changing the selected values will sync the
+// dashboard with the new values.
+type VariableSelector interface {
+	// GetVariables returns the variables of the dashboard.
+	GetVariables() []Variable
+	// SelectVariableValues selects the values of a repeatable variable.
+	SelectVariableValues(name string, values ...string) error
+	// DeselectVariableValues deselects the values of a repeatable variable.
+	DeselectVariableValues(name string, values ...string) error
+}
+
+// DatasourceHealth is the health of a datasource used by the dashboard.
+type DatasourceHealth struct {
+	ID string
+	// Source describes where the datasource used for the ID comes from.
+	Source string
+	// Queried is true when the datasource has been queried at least once.
+	Queried bool
+	// Failing is true when the last query to the datasource failed.
+	Failing bool
+	// LastSuccess is the time of the last successful query.
+	LastSuccess time.Time
+	// Errors is the number of failed queries.
+	Errors int
+	// Latency is the duration of the last query.
+	Latency time.Duration
+}
+
+// DatasourceHealthReporter knows how to get the health of the datasources
+// used by a dashboard.
+type DatasourceHealthReporter interface {
+	// GetDatasourcesHealth returns the health of the dashboard datasources.
+	GetDatasourcesHealth() []DatasourceHealth
+}
+
+// WidgetState is the state of the data of a widget.
+type WidgetState struct {
+	// Err is the error of the last sync, nil if the last sync was successful.
+	Err error
+	// Stale is true when the data of the widget is outdated because the
+	// widget has failed to sync multiple times in a row.
+	Stale bool
+	// Skipped is the number of syncs in a row that have been skipped
+	// because the widget was still syncing.
+	Skipped int
+}
+
 // Widget represnets a widget that can be rendered on the view.
 type Widget interface {
 	GetWidgetCfg() model.Widget
+	// SetState sets the state of the widget data, so the widget can show
+	// the sync errors and if the data it's showing is stale.
+	SetState(state WidgetState) error
 }
 
 // GaugeWidget knows how to render a Gauge kind widget that can be in percent
@@ -62,3 +172,25 @@ type GraphWidget interface {
 	// Sync will sync the different series on the graph.
 	Sync(series []Series) error
 }
+
+// TableCell is a cell of a table.
+type TableCell struct {
+	Text string
+	// Color is the optional color of the cell.
+	Color string
+}
+
+// Table is the data that can be rendered on a table.
+type Table struct {
+	Headers []string
+	// Rows are the rows of the table, every row has one cell per header.
+	Rows [][]TableCell
+}
+
+// TableWidget knows how to render a Table kind widget that renders rows
+// of cells with a header for each column.
+type TableWidget interface {
+	Widget
+	// Sync will sync the table data on the widget.
+	Sync(table Table) error
+}
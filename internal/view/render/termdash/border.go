@@ -0,0 +1,88 @@
+package termdash
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+
+	"github.com/slok/grafterm/internal/view/render"
+)
+
+const (
+	errorBorderColor    = cell.ColorRed
+	defFocusedColor     = cell.ColorYellow
+	staleTitleIndicator = "[stale]"
+)
+
+// containerUpdater knows how to update a container by its ID.
+type containerUpdater func(id string, opts ...container.Option) error
+
+// widgetBorder is the border of the widget containers, the border has
+// the title of the widget and shows the state of the widget data.
+// All the widgets embed it, this way they satisfy the state part of
+// render.Widget interface.
+type widgetBorder struct {
+	id     string
+	title  string
+	update containerUpdater
+
+	state render.WidgetState
+	mu    sync.Mutex
+}
+
+func newWidgetBorder(id, title string, update containerUpdater) *widgetBorder {
+	return &widgetBorder{
+		id:     id,
+		title:  title,
+		update: update,
+	}
+}
+
+// SetState satisfies render.Widget interface.
+func (w *widgetBorder) SetState(state render.WidgetState) error {
+	w.mu.Lock()
+	w.state = state
+	opts := w.stateOpts()
+	w.mu.Unlock()
+
+	return w.update(w.id, opts...)
+}
+
+// containerOpts returns the options of the container where the widget is placed.
+func (w *widgetBorder) containerOpts() []container.Option {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	opts := []container.Option{
+		container.ID(w.id),
+		container.Border(linestyle.Light),
+	}
+	return append(opts, w.stateOpts()...)
+}
+
+// stateOpts returns the options of the container that depend on the state.
+func (w *widgetBorder) stateOpts() []container.Option {
+	title := w.title
+	borderColor := cell.ColorDefault
+	focusedColor := defFocusedColor
+	if w.state.Stale {
+		title = fmt.Sprintf("%s %s", title, staleTitleIndicator)
+	}
+	if w.state.Skipped > 0 {
+		title = fmt.Sprintf("%s [skipped: %d]", title, w.state.Skipped)
+	}
+	if w.state.Err != nil {
+		title = fmt.Sprintf("%s [error: %s]", title, w.state.Err)
+		borderColor = errorBorderColor
+		focusedColor = errorBorderColor
+	}
+
+	return []container.Option{
+		container.BorderTitle(title),
+		container.BorderColor(borderColor),
+		container.FocusedColor(focusedColor),
+	}
+}
@@ -2,9 +2,7 @@ package termdash
 
 import (
 	"github.com/mum4k/termdash/cell"
-	"github.com/mum4k/termdash/container"
 	"github.com/mum4k/termdash/container/grid"
-	"github.com/mum4k/termdash/linestyle"
 	"github.com/mum4k/termdash/widgets/donut"
 
 	"github.com/slok/grafterm/internal/model"
@@ -12,13 +10,16 @@ import (
 
 // gauge satisfies render.GaugeWidget interface.
 type gauge struct {
-	cfg model.Widget
+	*widgetBorder
+
+	cfg   model.Widget
+	color cell.Color
 
 	widget  *donut.Donut
 	element grid.Element
 }
 
-func newGauge(cfg model.Widget) (*gauge, error) {
+func newGauge(cfg model.Widget, border *widgetBorder) (*gauge, error) {
 	// Create the widget.
 	donut, err := donut.New(donut.CellOpts(cell.FgColor(cell.ColorWhite)))
 	if err != nil {
@@ -26,15 +27,14 @@ func newGauge(cfg model.Widget) (*gauge, error) {
 	}
 
 	// Create the element using the new widget.
-	element := grid.Widget(donut,
-		container.Border(linestyle.Light),
-		container.BorderTitle(cfg.Title),
-	)
+	element := grid.Widget(donut, border.containerOpts()...)
 
 	return &gauge{
-		widget:  donut,
-		cfg:     cfg,
-		element: element,
+		widgetBorder: border,
+		widget:       donut,
+		color:        cell.ColorWhite,
+		cfg:          cfg,
+		element:      element,
 	}, nil
 }
 
@@ -48,14 +48,15 @@ func (g *gauge) GetWidgetCfg() model.Widget {
 
 func (g *gauge) Sync(isPercent bool, value float64) error {
 	var err error
+	colorOpt := donut.CellOpts(cell.FgColor(g.color))
 	if isPercent {
-		err = g.widget.Percent(int(value))
+		err = g.widget.Percent(int(value), colorOpt)
 	} else {
 		max := float64(g.cfg.Gauge.Max)
 		if max < value {
 			max = value
 		}
-		err = g.widget.Absolute(int(value), int(max))
+		err = g.widget.Absolute(int(value), int(max), colorOpt)
 	}
 
 	if err != nil {
@@ -70,15 +71,6 @@ func (g *gauge) SetColor(hexColor string) error {
 	if err != nil {
 		return err
 	}
-
-	// Create a new widget with the current color.
-	d, err := donut.New(donut.CellOpts(cell.FgColor(color)))
-	if err != nil {
-		return err
-	}
-
-	// Replace the instance value. We need to replace the content of the widget,
-	// is ok to copy the value in this case although the widget donut has a mutex.
-	*g.widget = *d
+	g.color = color
 	return nil
 }
@@ -7,7 +7,6 @@ import (
 	"github.com/mum4k/termdash/cell"
 	"github.com/mum4k/termdash/container"
 	"github.com/mum4k/termdash/container/grid"
-	"github.com/mum4k/termdash/linestyle"
 	"github.com/mum4k/termdash/widgets/linechart"
 	"github.com/mum4k/termdash/widgets/text"
 
@@ -32,6 +31,8 @@ const (
 
 // graph satisfies render.GraphWidget interface.
 type graph struct {
+	*widgetBorder
+
 	cfg model.Widget
 
 	widgetGraph  *linechart.LineChart
@@ -39,7 +40,7 @@ type graph struct {
 	element      grid.Element
 }
 
-func newGraph(cfg model.Widget) (*graph, error) {
+func newGraph(cfg model.Widget, border *widgetBorder) (*graph, error) {
 	vf, err := termdashValueFormatter(cfg)
 	if err != nil {
 		return nil, err
@@ -68,9 +69,10 @@ func newGraph(cfg model.Widget) (*graph, error) {
 		}
 	}
 
-	element = elementFromGraphAndLegend(cfg, lc, txt)
+	element = elementFromGraphAndLegend(cfg, border, lc, txt)
 
 	return &graph{
+		widgetBorder: border,
 		widgetGraph:  lc,
 		widgetLegend: txt,
 		cfg:          cfg,
@@ -100,7 +102,7 @@ func termdashValueFormatter(cfg model.Widget) (linechart.ValueFormatter, error)
 	}, nil
 }
 
-func elementFromGraphAndLegend(cfg model.Widget, graph *linechart.LineChart, legend *text.Text) grid.Element {
+func elementFromGraphAndLegend(cfg model.Widget, border *widgetBorder, graph *linechart.LineChart, legend *text.Text) grid.Element {
 	graphElement := grid.Widget(graph)
 
 	elements := []grid.Element{}
@@ -134,11 +136,7 @@ func elementFromGraphAndLegend(cfg model.Widget, graph *linechart.LineChart, leg
 		}
 	}
 
-	opts := []container.Option{
-		container.Border(linestyle.Light),
-		container.BorderTitle(cfg.Title),
-	}
-	element := grid.RowHeightPercWithOpts(fullPerc, opts, elements...)
+	element := grid.RowHeightPercWithOpts(fullPerc, border.containerOpts(), elements...)
 
 	return element
 }
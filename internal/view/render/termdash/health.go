@@ -0,0 +1,107 @@
+package termdash
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/widgets/text"
+
+	"github.com/slok/grafterm/internal/service/unit"
+	"github.com/slok/grafterm/internal/view/render"
+)
+
+const (
+	healthBarHeight       = 1
+	healthBarOKColor      = "#7EB26D"
+	healthBarFailingColor = "#E24D42"
+	healthBarUnknownColor = 8
+	healthBarIndicator    = "●"
+)
+
+// healthBar is a single line that shows the health of the datasources
+// used by the visible dashboard.
+type healthBar struct {
+	widget *text.Text
+	now    func() time.Time
+	mu     sync.Mutex
+}
+
+func newHealthBar() (*healthBar, error) {
+	txt, err := text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	return &healthBar{
+		widget: txt,
+		now:    time.Now,
+	}, nil
+}
+
+// containerOpts returns the container options to place the health bar on the layout.
+func (h *healthBar) containerOpts() []container.Option {
+	return []container.Option{
+		container.PlaceWidget(h.widget),
+	}
+}
+
+// render writes the health of the datasources on the widget.
+func (h *healthBar) render(health []render.DatasourceHealth) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.widget.Reset()
+
+	for _, dh := range health {
+		color := cell.ColorNumber(healthBarUnknownColor)
+		switch {
+		case dh.Failing:
+			c, err := colorHexToTermdash(healthBarFailingColor)
+			if err != nil {
+				return err
+			}
+			color = c
+		case dh.Queried:
+			c, err := colorHexToTermdash(healthBarOKColor)
+			if err != nil {
+				return err
+			}
+			color = c
+		}
+
+		err := h.widget.Write(" "+healthBarIndicator, text.WriteCellOpts(cell.FgColor(color)))
+		if err != nil {
+			return err
+		}
+
+		err = h.widget.Write(h.datasourceText(dh))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// datasourceText returns the text of the datasource health, e.g:
+// `prometheus (user) last ok 5s ago, 120ms, 2 errors`.
+func (h *healthBar) datasourceText(dh render.DatasourceHealth) string {
+	txt := " " + dh.ID
+	if dh.Source != "" {
+		txt = fmt.Sprintf("%s (%s)", txt, dh.Source)
+	}
+
+	if !dh.Queried {
+		return txt + " waiting "
+	}
+
+	lastOK := "never"
+	if !dh.LastSuccess.IsZero() {
+		lastOK = unit.DurationToSimpleString(h.now().Sub(dh.LastSuccess)) + " ago"
+	}
+
+	return fmt.Sprintf("%s last ok %s, %s, %d errors ", txt, lastOK, dh.Latency.Round(time.Millisecond), dh.Errors)
+}
@@ -0,0 +1,198 @@
+package termdash
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/linestyle"
+	"github.com/mum4k/termdash/widgets/text"
+
+	"github.com/slok/grafterm/internal/view/render"
+)
+
+const (
+	selectorWidth       = 40
+	selectorTitle       = "Variables"
+	selectorHelp        = "\n↑/↓: move  space: select  v: close"
+	selectorCursorColor = 8
+	selectorFixedColor  = 248
+)
+
+// selectorItem is a selectable value of a variable.
+type selectorItem struct {
+	variable string
+	value    string
+	selected bool
+	// multi is true when more values of the same variable are selected.
+	multi bool
+}
+
+// variableSelector is a panel that lets the user change the selected values
+// of the dashboard repeatable variables.
+type variableSelector struct {
+	selector render.VariableSelector
+	widget   *text.Text
+	open     bool
+	cursor   int
+	mu       sync.Mutex
+}
+
+func newVariableSelector(selector render.VariableSelector) (*variableSelector, error) {
+	txt, err := text.New(text.WrapAtRunes())
+	if err != nil {
+		return nil, err
+	}
+
+	return &variableSelector{
+		selector: selector,
+		widget:   txt,
+	}, nil
+}
+
+// containerOpts returns the container options to place the selector on the layout.
+func (v *variableSelector) containerOpts() []container.Option {
+	return []container.Option{
+		container.Border(linestyle.Light),
+		container.BorderTitle(selectorTitle),
+		container.PlaceWidget(v.widget),
+	}
+}
+
+func (v *variableSelector) isOpen() bool {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.open
+}
+
+// toggle opens or closes the selector.
+func (v *variableSelector) toggle() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.open = !v.open
+	v.cursor = 0
+	return v.render()
+}
+
+// move moves the cursor over the selectable values.
+func (v *variableSelector) move(positions int) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	items := v.items()
+	v.cursor += positions
+	if v.cursor >= len(items) {
+		v.cursor = len(items) - 1
+	}
+	if v.cursor < 0 {
+		v.cursor = 0
+	}
+
+	return v.render()
+}
+
+// selectCurrent selects or deselects the value under the cursor, a variable
+// will always have at least one selected value.
+func (v *variableSelector) selectCurrent() error {
+	v.mu.Lock()
+	items := v.items()
+	if v.cursor >= len(items) {
+		v.mu.Unlock()
+		return nil
+	}
+	item := items[v.cursor]
+	v.mu.Unlock()
+
+	// Don't hold the lock while the dashboard applies the selection.
+	var err error
+	switch {
+	case !item.selected:
+		err = v.selector.SelectVariableValues(item.variable, item.value)
+	case item.multi:
+		err = v.selector.DeselectVariableValues(item.variable, item.value)
+	}
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.render()
+}
+
+// items returns the selectable items of the repeatable variables.
+func (v *variableSelector) items() []selectorItem {
+	items := []selectorItem{}
+	for _, rv := range v.selector.GetVariables() {
+		if !rv.Repeatable {
+			continue
+		}
+
+		selected := map[string]bool{}
+		for _, s := range rv.Selected {
+			selected[s] = true
+		}
+		for _, value := range rv.Values {
+			items = append(items, selectorItem{
+				variable: rv.Name,
+				value:    value,
+				selected: selected[value],
+				multi:    len(rv.Selected) > 1,
+			})
+		}
+	}
+
+	return items
+}
+
+// render writes the variables on the widget.
+func (v *variableSelector) render() error {
+	v.widget.Reset()
+	if !v.open {
+		return nil
+	}
+
+	idx := 0
+	for _, rv := range v.selector.GetVariables() {
+		// Non repeatable variables can't be changed, only show them.
+		if !rv.Repeatable {
+			line := fmt.Sprintf("%s: %s\n", rv.Name, strings.Join(rv.Selected, ","))
+			err := v.widget.Write(line, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(selectorFixedColor))))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		err := v.widget.Write(fmt.Sprintf("%s:\n", rv.Name))
+		if err != nil {
+			return err
+		}
+
+		selected := map[string]bool{}
+		for _, s := range rv.Selected {
+			selected[s] = true
+		}
+		for _, value := range rv.Values {
+			mark := "[ ]"
+			if selected[value] {
+				mark = "[x]"
+			}
+
+			opts := []text.WriteOption{}
+			if idx == v.cursor {
+				opts = append(opts, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(selectorCursorColor))))
+			}
+			err := v.widget.Write(fmt.Sprintf(" %s %s\n", mark, value), opts...)
+			if err != nil {
+				return err
+			}
+			idx++
+		}
+	}
+
+	return v.widget.Write(selectorHelp)
+}
@@ -2,9 +2,7 @@ package termdash
 
 import (
 	"github.com/mum4k/termdash/cell"
-	"github.com/mum4k/termdash/container"
 	"github.com/mum4k/termdash/container/grid"
-	"github.com/mum4k/termdash/linestyle"
 	"github.com/mum4k/termdash/widgets/segmentdisplay"
 
 	"github.com/slok/grafterm/internal/model"
@@ -12,6 +10,8 @@ import (
 
 // singlestat satisfies render.SinglestatWidget interface.
 type singlestat struct {
+	*widgetBorder
+
 	cfg   model.Widget
 	color cell.Color
 
@@ -19,7 +19,7 @@ type singlestat struct {
 	element grid.Element
 }
 
-func newSinglestat(cfg model.Widget) (*singlestat, error) {
+func newSinglestat(cfg model.Widget, border *widgetBorder) (*singlestat, error) {
 	// Create the widget.
 	sd, err := segmentdisplay.New()
 	if err != nil {
@@ -27,16 +27,14 @@ func newSinglestat(cfg model.Widget) (*singlestat, error) {
 	}
 
 	// Create the element using the new widget.
-	element := grid.Widget(sd,
-		container.Border(linestyle.Light),
-		container.BorderTitle(cfg.Title),
-	)
+	element := grid.Widget(sd, border.containerOpts()...)
 
 	return &singlestat{
-		widget:  sd,
-		color:   cell.ColorWhite,
-		cfg:     cfg,
-		element: element,
+		widgetBorder: border,
+		widget:       sd,
+		color:        cell.ColorWhite,
+		cfg:          cfg,
+		element:      element,
 	}, nil
 }
 
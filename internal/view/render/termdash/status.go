@@ -0,0 +1,89 @@
+package termdash
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/widgets/text"
+
+	"github.com/slok/grafterm/internal/service/unit"
+	"github.com/slok/grafterm/internal/view/render"
+)
+
+const (
+	statusHeight     = 1
+	statusTimeFormat = "2006-01-02 15:04:05"
+	statusHelp       = "  +/-: zoom  ←/→: pan  n: now  p: pause  v: variables  q: quit"
+	statusLiveColor  = "#7EB26D"
+	statusPauseColor = "#EAB839"
+	statusHelpColor  = 8
+)
+
+// statusLine is a single line that shows the status of the dashboard, like
+// the current time range.
+type statusLine struct {
+	timeRange render.TimeRangeController
+	widget    *text.Text
+	mu        sync.Mutex
+}
+
+func newStatusLine(timeRange render.TimeRangeController) (*statusLine, error) {
+	txt, err := text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	return &statusLine{
+		timeRange: timeRange,
+		widget:    txt,
+	}, nil
+}
+
+// containerOpts returns the container options to place the status line on the layout.
+func (s *statusLine) containerOpts() []container.Option {
+	return []container.Option{
+		container.PlaceWidget(s.widget),
+	}
+}
+
+// render writes the status on the widget.
+func (s *statusLine) render() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tr := s.timeRange.GetTimeRange()
+	s.widget.Reset()
+
+	// Time range.
+	timeRange := fmt.Sprintf(" %s → %s (%s)",
+		tr.Start.Local().Format(statusTimeFormat),
+		tr.End.Local().Format(statusTimeFormat),
+		unit.DurationToSimpleString(tr.End.Sub(tr.Start)))
+	err := s.widget.Write(timeRange)
+	if err != nil {
+		return err
+	}
+
+	// Refresh mode.
+	mode, modeColor := "", ""
+	switch {
+	case tr.Paused:
+		mode, modeColor = " paused", statusPauseColor
+	case tr.Live:
+		mode, modeColor = " live", statusLiveColor
+	}
+	if mode != "" {
+		color, err := colorHexToTermdash(modeColor)
+		if err != nil {
+			return err
+		}
+		err = s.widget.Write(mode, text.WriteCellOpts(cell.FgColor(color)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.widget.Write(statusHelp, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(statusHelpColor))))
+}
@@ -0,0 +1,118 @@
+package termdash
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container/grid"
+	"github.com/mum4k/termdash/widgets/text"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/view/render"
+)
+
+const (
+	tableColumnSeparator = "  "
+	tableHeaderColor     = 248
+)
+
+// table satisfies render.TableWidget interface.
+type table struct {
+	*widgetBorder
+
+	cfg model.Widget
+
+	widget  *text.Text
+	element grid.Element
+}
+
+func newTable(cfg model.Widget, border *widgetBorder) (*table, error) {
+	// Create the widget.
+	txt, err := text.New()
+	if err != nil {
+		return nil, err
+	}
+
+	// Create the element using the new widget.
+	element := grid.Widget(txt, border.containerOpts()...)
+
+	return &table{
+		widgetBorder: border,
+		widget:       txt,
+		cfg:          cfg,
+		element:      element,
+	}, nil
+}
+
+func (t *table) getElement() grid.Element {
+	return t.element
+}
+
+func (t *table) GetWidgetCfg() model.Widget {
+	return t.cfg
+}
+
+func (t *table) Sync(tbl render.Table) error {
+	// Get the width of each column so we can align the cells.
+	widths := make([]int, len(tbl.Headers))
+	for i, h := range tbl.Headers {
+		widths[i] = utf8.RuneCountInString(h)
+	}
+	for _, row := range tbl.Rows {
+		for i, c := range row {
+			if i < len(widths) && utf8.RuneCountInString(c.Text) > widths[i] {
+				widths[i] = utf8.RuneCountInString(c.Text)
+			}
+		}
+	}
+
+	t.widget.Reset()
+
+	// Write the headers.
+	for i, h := range tbl.Headers {
+		err := t.widget.Write(padTableCell(h, widths[i]), text.WriteCellOpts(cell.FgColor(cell.ColorNumber(tableHeaderColor))))
+		if err != nil {
+			return err
+		}
+	}
+	err := t.widget.Write("\n")
+	if err != nil {
+		return err
+	}
+
+	// Write the rows.
+	for _, row := range tbl.Rows {
+		for i, c := range row {
+			if i >= len(widths) {
+				break
+			}
+
+			opts := []text.WriteOption{}
+			if c.Color != "" {
+				color, err := colorHexToTermdash(c.Color)
+				if err != nil {
+					return err
+				}
+				opts = append(opts, text.WriteCellOpts(cell.FgColor(color)))
+			}
+
+			err := t.widget.Write(padTableCell(c.Text, widths[i]), opts...)
+			if err != nil {
+				return err
+			}
+		}
+
+		err := t.widget.Write("\n")
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// padTableCell pads the text of a cell to the column width.
+func padTableCell(txt string, width int) string {
+	return txt + strings.Repeat(" ", width-utf8.RuneCountInString(txt)) + tableColumnSeparator
+}
@@ -0,0 +1,62 @@
+package termdash
+
+import (
+	"fmt"
+
+	"github.com/mum4k/termdash/cell"
+	"github.com/mum4k/termdash/container"
+	"github.com/mum4k/termdash/widgets/text"
+)
+
+const (
+	tabBarHeight       = 1
+	tabBarCurrentColor = 8
+	tabBarHelp         = "  tab/[/]/1-9: switch dashboard"
+	tabBarHelpColor    = 8
+)
+
+// tabBar is a single line that shows the loaded dashboard pages and
+// which one is the visible one.
+type tabBar struct {
+	widget *text.Text
+}
+
+func newTabBar() (*tabBar, error) {
+	txt, err := text.New(text.DisableScrolling())
+	if err != nil {
+		return nil, err
+	}
+
+	return &tabBar{
+		widget: txt,
+	}, nil
+}
+
+// containerOpts returns the container options to place the tab bar on the layout.
+func (t *tabBar) containerOpts() []container.Option {
+	return []container.Option{
+		container.PlaceWidget(t.widget),
+	}
+}
+
+// render writes the page titles on the widget.
+func (t *tabBar) render(titles []string, current int) error {
+	t.widget.Reset()
+
+	for i, title := range titles {
+		if title == "" {
+			title = "dashboard"
+		}
+
+		opts := []text.WriteOption{}
+		if i == current {
+			opts = append(opts, text.WriteCellOpts(cell.BgColor(cell.ColorNumber(tabBarCurrentColor))))
+		}
+		err := t.widget.Write(fmt.Sprintf(" %d:%s ", i+1, title), opts...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return t.widget.Write(tabBarHelp, text.WriteCellOpts(cell.FgColor(cell.ColorNumber(tabBarHelpColor))))
+}
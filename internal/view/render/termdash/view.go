@@ -2,6 +2,10 @@ package termdash
 
 import (
 	"context"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
 	"time"
 
 	"github.com/mum4k/termdash"
@@ -18,8 +22,21 @@ import (
 )
 
 const (
-	rootID         = "root"
-	redrawInterval = 250 * time.Millisecond
+	rootID                = "root"
+	redrawInterval        = 250 * time.Millisecond
+	statusRefreshInterval = 1 * time.Second
+)
+
+// Keys.
+const (
+	keyVariableSelector = 'v'
+	keyZoomIn           = '+'
+	keyZoomInAlt        = '='
+	keyZoomOut          = '-'
+	keyLive             = 'n'
+	keyPause            = 'p'
+	keyNextPage         = ']'
+	keyPreviousPage     = '['
 )
 
 // elementer is an internal interface that all widgets from the termdash
@@ -30,29 +47,54 @@ type elementer interface {
 	getElement() grid.Element
 }
 
+// page is a loaded dashboard.
+type page struct {
+	title         string
+	dashboardOpts []container.Option
+	selector      *variableSelector
+	health        render.DatasourceHealthReporter
+	// borders are the borders of the page widgets.
+	borders []*widgetBorder
+}
+
 // View is what renders the metrics.
 type termDashboard struct {
-	widgets []render.Widget
-	logger  log.Logger
-	cancel  func()
+	logger    log.Logger
+	cancel    func()
+	timeRange render.TimeRangeController
+	switcher  render.PageSwitcher
 
 	// Term fields.
-	terminal *termbox.Terminal
+	terminal  *termbox.Terminal
+	container *container.Container
+	status    *statusLine
+	health    *healthBar
+	tabs      *tabBar
+
+	// Pages fields.
+	pages   []*page
+	current int
+	// widgetSeq is used to create unique widget container IDs.
+	widgetSeq int
+	mu        sync.Mutex
 }
 
 // NewTermDashboard returns a new terminal view, it accepts a cancel function that will
 // be called when the terminal rendered quit function is called. This is required because
 // the events now are captured by the rendered terminal.
-func NewTermDashboard(cancel func(), logger log.Logger) (render.Renderer, error) {
+// The time range controller is optional and will let the user navigate through
+// time using the keyboard.
+func NewTermDashboard(cancel func(), timeRange render.TimeRangeController, logger log.Logger) (render.Renderer, error) {
 	t, err := termbox.New()
 	if err != nil {
 		return nil, err
 	}
 
 	return &termDashboard{
-		cancel:   cancel,
-		terminal: t,
-		logger:   logger,
+		cancel:    cancel,
+		timeRange: timeRange,
+		terminal:  t,
+		logger:    logger,
 	}, nil
 }
 
@@ -60,42 +102,351 @@ func (t *termDashboard) Close() {
 	t.terminal.Close()
 }
 
-// Run will run the view, its' a blocker.
-func (t *termDashboard) LoadDashboard(ctx context.Context, gr *graftermgrid.Grid) ([]render.Widget, error) {
-	// Create main view (root).
-	c, err := container.New(t.terminal, container.ID(rootID))
-	if err != nil {
-		return nil, err
+// LoadDashboard will load the dashboard as a new page, the first time it
+// will start running the view.
+func (t *termDashboard) LoadDashboard(ctx context.Context, title string, gr *graftermgrid.Grid, selector render.VariableSelector, health render.DatasourceHealthReporter) ([]render.Widget, error) {
+	p := &page{
+		title:  title,
+		health: health,
 	}
 
 	// Get the layout from the grid.
-	gridOpts, err := t.gridLayout(gr)
+	widgets, gridOpts, err := t.gridLayout(p, gr)
 	if err != nil {
 		return []render.Widget{}, err
 	}
+	p.dashboardOpts = gridOpts
+
+	if selector != nil {
+		p.selector, err = newVariableSelector(selector)
+		if err != nil {
+			return []render.Widget{}, err
+		}
+	}
+
+	t.mu.Lock()
+	t.pages = append(t.pages, p)
+	firstPage := len(t.pages) == 1
+	t.mu.Unlock()
 
-	err = c.Update(rootID, gridOpts...)
+	// If is the first page, start running the view.
+	if firstPage {
+		err := t.run(ctx)
+		if err != nil {
+			return []render.Widget{}, err
+		}
+		return widgets, nil
+	}
+
+	// Show the new page on the tabs.
+	err = t.updateLayout()
 	if err != nil {
 		return []render.Widget{}, err
 	}
 
-	go func() {
-		quitter := func(k *terminalapi.Keyboard) {
-			if k.Key == 'q' || k.Key == 'Q' || k.Key == keyboard.KeyEsc {
-				t.cancel()
-			}
+	return widgets, nil
+}
+
+// run creates the main view and runs the terminal.
+func (t *termDashboard) run(ctx context.Context) error {
+	// Create main view (root).
+	c, err := container.New(t.terminal, container.ID(rootID))
+	if err != nil {
+		return err
+	}
+	t.container = c
+
+	t.tabs, err = newTabBar()
+	if err != nil {
+		return err
+	}
+
+	t.health, err = newHealthBar()
+	if err != nil {
+		return err
+	}
+
+	if t.timeRange != nil {
+		t.status, err = newStatusLine(t.timeRange)
+		if err != nil {
+			return err
 		}
-		if err := termdash.Run(ctx, t.terminal, c, termdash.KeyboardSubscriber(quitter), termdash.RedrawInterval(redrawInterval)); err != nil {
+	}
+	go t.refreshStatus(ctx)
+
+	err = t.updateLayout()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		if err := termdash.Run(ctx, t.terminal, c, termdash.KeyboardSubscriber(t.onKeyboard), termdash.RedrawInterval(redrawInterval)); err != nil {
 			t.logger.Errorf("error running termdash terminal: %s", err)
 			// TODO(slok): exit on error.
 		}
 	}()
 
-	return t.widgets, nil
+	return nil
+}
+
+// ShowPage satisfies render.Renderer interface.
+func (t *termDashboard) ShowPage(index int) error {
+	t.mu.Lock()
+	if index < 0 || index >= len(t.pages) {
+		t.mu.Unlock()
+		return fmt.Errorf("page %d does not exist", index)
+	}
+
+	// Close the variable selector of the hidden page.
+	if sel := t.pages[t.current].selector; sel != nil && sel.isOpen() {
+		err := sel.toggle()
+		if err != nil {
+			t.mu.Unlock()
+			return err
+		}
+	}
+	t.current = index
+	t.mu.Unlock()
+
+	return t.updateLayout()
+}
+
+// SetPageSwitcher satisfies render.Renderer interface.
+func (t *termDashboard) SetPageSwitcher(switcher render.PageSwitcher) {
+	t.switcher = switcher
+}
+
+// currentPage returns the visible page.
+func (t *termDashboard) currentPage() *page {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.pages[t.current]
 }
 
-func (t *termDashboard) gridLayout(gr *graftermgrid.Grid) ([]container.Option, error) {
+// updateLayout will place the visible dashboard page on the root container,
+// the status line and the datasources health bar at the top, the tabs of the pages if there are multiple
+// pages and the variable selector on the left side if it's open.
+func (t *termDashboard) updateLayout() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	page := t.pages[t.current]
+
+	statusOpts := []container.Option{}
+	statusSize := 0
+	if t.status != nil {
+		statusOpts = t.status.containerOpts()
+		statusSize = statusHeight
+	}
+
+	healthOpts := []container.Option{}
+	healthSize := 0
+	if page.health != nil {
+		err := t.health.render(page.health.GetDatasourcesHealth())
+		if err != nil {
+			return err
+		}
+		healthOpts = t.health.containerOpts()
+		healthSize = healthBarHeight
+	}
+
+	tabsOpts := []container.Option{}
+	tabsSize := 0
+	if len(t.pages) > 1 {
+		titles := make([]string, 0, len(t.pages))
+		for _, p := range t.pages {
+			titles = append(titles, p.title)
+		}
+		err := t.tabs.render(titles, t.current)
+		if err != nil {
+			return err
+		}
+		tabsOpts = t.tabs.containerOpts()
+		tabsSize = tabBarHeight
+	}
+
+	selectorOpts := []container.Option{}
+	selectorSize := 0
+	if page.selector != nil && page.selector.isOpen() {
+		selectorOpts = page.selector.containerOpts()
+		selectorSize = selectorWidth
+	}
+
+	err := t.container.Update(rootID,
+		container.SplitHorizontal(
+			container.Top(
+				container.SplitHorizontal(
+					container.Top(statusOpts...),
+					container.Bottom(healthOpts...),
+					container.SplitFixed(statusSize),
+				),
+			),
+			container.Bottom(
+				container.SplitHorizontal(
+					container.Top(tabsOpts...),
+					container.Bottom(
+						container.SplitVertical(
+							container.Left(selectorOpts...),
+							container.Right(page.dashboardOpts...),
+							container.SplitFixed(selectorSize),
+						),
+					),
+					container.SplitFixed(tabsSize),
+				),
+			),
+			container.SplitFixed(statusSize+healthSize),
+		),
+	)
+	if err != nil {
+		return err
+	}
+
+	// The layout has been created with the initial state of the widgets,
+	// set the current state of the page widgets.
+	for _, b := range page.borders {
+		b.mu.Lock()
+		opts := b.stateOpts()
+		b.mu.Unlock()
+
+		err := t.container.Update(b.id, opts...)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refreshStatus will refresh the status line and the datasources health bar
+// until the context is done.
+func (t *termDashboard) refreshStatus(ctx context.Context) {
+	tk := time.NewTicker(statusRefreshInterval)
+	defer tk.Stop()
+	for {
+		if t.status != nil {
+			err := t.status.render()
+			if err != nil {
+				t.logger.Errorf("error rendering status line: %s", err)
+			}
+		}
+
+		if health := t.currentPage().health; health != nil {
+			err := t.health.render(health.GetDatasourcesHealth())
+			if err != nil {
+				t.logger.Errorf("error rendering datasources health bar: %s", err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-tk.C:
+		}
+	}
+}
+
+// onKeyboard handles the keyboard events of the terminal.
+func (t *termDashboard) onKeyboard(k *terminalapi.Keyboard) {
+	selector := t.currentPage().selector
+
+	var err error
+	switch {
+	case k.Key == 'q' || k.Key == 'Q':
+		t.cancel()
+	// When the selector is open, it captures the keys.
+	case selector != nil && selector.isOpen():
+		err = t.onSelectorKeyboard(selector, k)
+	case k.Key == keyboard.KeyEsc:
+		t.cancel()
+	case k.Key == keyVariableSelector && selector != nil:
+		err = t.toggleSelector(selector)
+	case t.switcher != nil && t.isPageKey(k):
+		err = t.onPageKeyboard(k)
+	case t.timeRange != nil:
+		err = t.onTimeRangeKeyboard(k)
+	}
+
+	if err != nil {
+		t.logger.Errorf("error handling keyboard event: %s", err)
+	}
+}
+
+func (t *termDashboard) isPageKey(k *terminalapi.Keyboard) bool {
+	return k.Key == keyboard.KeyTab || k.Key == keyNextPage || k.Key == keyPreviousPage || (k.Key >= '1' && k.Key <= '9')
+}
+
+func (t *termDashboard) onPageKeyboard(k *terminalapi.Keyboard) error {
+	t.mu.Lock()
+	index := t.current
+	total := len(t.pages)
+	t.mu.Unlock()
+
+	switch {
+	case k.Key == keyboard.KeyTab, k.Key == keyNextPage:
+		index = (index + 1) % total
+	case k.Key == keyPreviousPage:
+		index = (index - 1 + total) % total
+	default:
+		index = int(k.Key - '1')
+		if index >= total {
+			return nil
+		}
+	}
+
+	return t.switcher.SwitchPage(index)
+}
+
+func (t *termDashboard) onTimeRangeKeyboard(k *terminalapi.Keyboard) error {
+	switch k.Key {
+	case keyZoomIn, keyZoomInAlt:
+		t.timeRange.ZoomIn()
+	case keyZoomOut:
+		t.timeRange.ZoomOut()
+	case keyboard.KeyArrowLeft:
+		t.timeRange.PanBackward()
+	case keyboard.KeyArrowRight:
+		t.timeRange.PanForward()
+	case keyLive:
+		t.timeRange.Live()
+	case keyPause:
+		t.timeRange.TogglePause()
+	default:
+		return nil
+	}
+
+	// Show the new time range.
+	return t.status.render()
+}
+
+func (t *termDashboard) onSelectorKeyboard(selector *variableSelector, k *terminalapi.Keyboard) error {
+	switch k.Key {
+	case keyboard.KeyEsc, keyVariableSelector:
+		return t.toggleSelector(selector)
+	case keyboard.KeyArrowUp, 'k':
+		return selector.move(-1)
+	case keyboard.KeyArrowDown, 'j':
+		return selector.move(1)
+	case keyboard.KeySpace, keyboard.KeyEnter:
+		return selector.selectCurrent()
+	}
+
+	return nil
+}
+
+func (t *termDashboard) toggleSelector(selector *variableSelector) error {
+	err := selector.toggle()
+	if err != nil {
+		return err
+	}
+
+	return t.updateLayout()
+}
+
+// gridLayout creates the widgets of the grid and returns them with the layout
+// of the grid.
+func (t *termDashboard) gridLayout(p *page, gr *graftermgrid.Grid) ([]render.Widget, []container.Option, error) {
 	builder := grid.New()
+	widgets := []render.Widget{}
 
 	// Create the rendering widgets.
 	rowsElements := [][]grid.Element{}
@@ -108,13 +459,13 @@ func (t *termDashboard) gridLayout(gr *graftermgrid.Grid) ([]container.Option, e
 			// New widget.
 			var element grid.Element
 			if !rowElement.Empty {
-				widget, err := t.newWidget(cfg)
+				widget, err := t.newWidget(p, cfg)
 				if err != nil {
 					t.logger.Errorf("error creating widget: %s", err)
 					continue
 				}
 				// Add widget to the tracked widgets so the app can control them.
-				t.widgets = append(t.widgets, widget)
+				widgets = append(widgets, widget)
 
 				// Get the grid.Element from our widget and place on the grid.
 				element = widget.(elementer).getElement()
@@ -140,22 +491,10 @@ func (t *termDashboard) gridLayout(gr *graftermgrid.Grid) ([]container.Option, e
 
 	// Add rows to grid.
 	var gridElements []grid.Element
-	totalFilled := 0
-	for i, row := range gr.Rows {
-		rowElements := rowsElements[i]
-		rowPerc := row.PercentSize
-		// Fix the size on the last element.
-		// Termdash does not allow a rows greater than 99, we have
-		// used percents (0-100), so we remove a 1% from the last element.
-		// Ugly but makes easy to work with % and is difficult for the
-		// eye to notice of the 1%.
-		if totalFilled+rowPerc >= 100 {
-			rowPerc--
-		}
-		totalFilled += rowPerc
-
+	rowPercs := rowHeightPercents(gr.Rows)
+	for i := range gr.Rows {
 		// Place the row.
-		rowElement := grid.RowHeightPerc(rowPerc, rowElements...)
+		rowElement := grid.RowHeightPerc(rowPercs[i], rowsElements[i]...)
 		gridElements = append(gridElements, rowElement)
 	}
 
@@ -163,21 +502,100 @@ func (t *termDashboard) gridLayout(gr *graftermgrid.Grid) ([]container.Option, e
 	builder.Add(gridElements...)
 
 	// Get the layout from the grid.
-	return builder.Build()
+	opts, err := builder.Build()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return widgets, opts, nil
+}
+
+// rowHeightPercents returns the height percent of the rows. Termdash does not
+// allow rows greater than 99 in total and every row needs at least 1%, so
+// every row gets 1% and the rest (until 99%) is distributed based on the
+// percent size of the rows, the rounding remainder goes to the rows with
+// the biggest decimals. This way the total is always 99% (the 1% is
+// difficult for the eye to notice).
+func rowHeightPercents(rows []*graftermgrid.Row) []int {
+	res := make([]int, len(rows))
+	total := 0
+	for i, row := range rows {
+		res[i] = 1
+		total += row.PercentSize
+	}
+
+	available := 99 - len(rows)
+	if len(rows) == 0 || available <= 0 {
+		return res
+	}
+
+	type remainder struct {
+		idx  int
+		frac float64
+	}
+	remainders := make([]remainder, len(rows))
+	filled := 0
+	for i, row := range rows {
+		share := float64(available) / float64(len(rows))
+		if total > 0 {
+			share = float64(available) * float64(row.PercentSize) / float64(total)
+		}
+		whole := math.Floor(share)
+		res[i] += int(whole)
+		filled += int(whole)
+		remainders[i] = remainder{idx: i, frac: share - whole}
+	}
+
+	sort.SliceStable(remainders, func(i, j int) bool { return remainders[i].frac > remainders[j].frac })
+	for i := 0; filled < available; i++ {
+		res[remainders[i%len(remainders)].idx]++
+		filled++
+	}
+
+	return res
 }
 
-func (t *termDashboard) newWidget(widgetcfg model.Widget) (render.Widget, error) {
+func (t *termDashboard) newWidget(p *page, widgetcfg model.Widget) (render.Widget, error) {
+	t.mu.Lock()
+	t.widgetSeq++
+	id := fmt.Sprintf("widget-%d", t.widgetSeq)
+	t.mu.Unlock()
+
+	border := newWidgetBorder(id, widgetcfg.Title, func(id string, opts ...container.Option) error {
+		return t.updateWidgetContainer(p, id, opts...)
+	})
+
 	var widget render.Widget
 	var err error
 
 	switch {
 	case widgetcfg.Gauge != nil:
-		widget, err = newGauge(widgetcfg)
+		widget, err = newGauge(widgetcfg, border)
 	case widgetcfg.Singlestat != nil:
-		widget, err = newSinglestat(widgetcfg)
+		widget, err = newSinglestat(widgetcfg, border)
 	case widgetcfg.Graph != nil:
-		widget, err = newGraph(widgetcfg)
+		widget, err = newGraph(widgetcfg, border)
+	case widgetcfg.Table != nil:
+		widget, err = newTable(widgetcfg, border)
+	}
+	if err != nil {
+		return nil, err
+	}
+	p.borders = append(p.borders, border)
+
+	return widget, nil
+}
+
+// updateWidgetContainer updates the container of a page widget, the widgets of the
+// hidden pages are not placed on the layout, they will be updated when the
+// page is shown.
+func (t *termDashboard) updateWidgetContainer(p *page, id string, opts ...container.Option) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.container == nil || t.pages[t.current] != p {
+		return nil
 	}
 
-	return widget, err
+	return t.container.Update(id, opts...)
 }
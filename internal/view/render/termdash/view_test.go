@@ -0,0 +1,98 @@
+package termdash
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	graftermgrid "github.com/slok/grafterm/internal/view/grid"
+)
+
+func TestRowHeightPercents(t *testing.T) {
+	rows := func(percents ...int) []*graftermgrid.Row {
+		rs := make([]*graftermgrid.Row, len(percents))
+		for i, p := range percents {
+			rs[i] = &graftermgrid.Row{PercentSize: p}
+		}
+		return rs
+	}
+
+	tests := []struct {
+		name string
+		rows []*graftermgrid.Row
+		exp  []int
+	}{
+		{
+			name: "Without rows there shouldn't be percents.",
+			rows: rows(),
+			exp:  []int{},
+		},
+		{
+			name: "A single row should use the 99%.",
+			rows: rows(100),
+			exp:  []int{99},
+		},
+		{
+			name: "Rows with the same height should have the same percent and the remainder should go to the first row.",
+			rows: rows(50, 50),
+			exp:  []int{50, 49},
+		},
+		{
+			name: "Rows with mixed heights should be distributed based on their percent.",
+			rows: rows(25, 75),
+			exp:  []int{25, 74},
+		},
+		{
+			name: "Rows with mixed heights should give the rounding remainder to the rows with the biggest decimals.",
+			rows: rows(20, 20, 60),
+			exp:  []int{20, 20, 59},
+		},
+		{
+			name: "Rows that don't need rounding should keep their percent.",
+			rows: rows(33, 33, 33),
+			exp:  []int{33, 33, 33},
+		},
+		{
+			name: "Rows with a total greater than 100% should be kept within 99%.",
+			rows: rows(60, 60),
+			exp:  []int{50, 49},
+		},
+		{
+			name: "Rows without percent should be distributed equally.",
+			rows: rows(0, 0),
+			exp:  []int{50, 49},
+		},
+		{
+			name: "Rows with a tiny percent should have at least 1%.",
+			rows: rows(0, 100),
+			exp:  []int{1, 98},
+		},
+		{
+			name: "Having more rows than percents available should give 1% to every row.",
+			rows: rows(make([]int, 99)...),
+			exp: func() []int {
+				exp := make([]int, 99)
+				for i := range exp {
+					exp[i] = 1
+				}
+				return exp
+			}(),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			got := rowHeightPercents(test.rows)
+			assert.Equal(test.exp, got)
+
+			// Termdash doesn't allow heights greater than 99%.
+			total := 0
+			for _, p := range got {
+				total += p
+			}
+			assert.LessOrEqual(total, 99)
+		})
+	}
+}
@@ -0,0 +1,205 @@
+package view
+
+import (
+	"sync"
+	"time"
+
+	"github.com/slok/grafterm/internal/view/render"
+)
+
+const (
+	minTimeRange = 1 * time.Minute
+)
+
+// TimeRangeController controls the time range the app uses on every sync,
+// it knows how to navigate through time (zoom, pan...) and pause the refresh.
+// Satisfies render.TimeRangeController interface.
+//
+// The time range can be relative to now (live) or fixed. When the start
+// and the end are not set the time range will be relative to now using
+// the relative duration.
+type TimeRangeController struct {
+	start    time.Time
+	end      time.Time
+	relative time.Duration
+	paused   bool
+	pausedAt time.Time
+	changes  chan struct{}
+	now      func() time.Time
+	mu       sync.Mutex
+}
+
+// NewTimeRangeController returns a new time range controller, start and end
+// are optional fixed times, if not set the relative duration will be used.
+func NewTimeRangeController(start, end time.Time, relative time.Duration) *TimeRangeController {
+	if relative == 0 {
+		relative = defRelativeTimeRange
+	}
+
+	return &TimeRangeController{
+		start:    start,
+		end:      end,
+		relative: relative,
+		changes:  make(chan struct{}, 1),
+		now:      time.Now,
+	}
+}
+
+// TimeRange returns the current time range.
+func (t *TimeRangeController) TimeRange() (start, end time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.timeRange()
+}
+
+// Paused returns true if the refresh of the time range is paused.
+func (t *TimeRangeController) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
+
+// Changes returns a channel that will receive an event every time
+// the time range is changed by the user.
+func (t *TimeRangeController) Changes() <-chan struct{} {
+	return t.changes
+}
+
+// GetTimeRange satisfies render.TimeRangeController interface.
+func (t *TimeRangeController) GetTimeRange() render.TimeRange {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, end := t.timeRange()
+	return render.TimeRange{
+		Start:  start,
+		End:    end,
+		Live:   t.isLive(),
+		Paused: t.paused,
+	}
+}
+
+// ZoomIn satisfies render.TimeRangeController interface.
+func (t *TimeRangeController) ZoomIn() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.zoom(0.5)
+}
+
+// ZoomOut satisfies render.TimeRangeController interface.
+func (t *TimeRangeController) ZoomOut() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.zoom(2)
+}
+
+// PanBackward satisfies render.TimeRangeController interface.
+func (t *TimeRangeController) PanBackward() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, end := t.timeRange()
+	shift := end.Sub(start) / 2
+	t.setFixed(start.Add(-1*shift), end.Add(-1*shift))
+	t.changed()
+}
+
+// PanForward satisfies render.TimeRangeController interface.
+func (t *TimeRangeController) PanForward() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, end := t.timeRange()
+	shift := end.Sub(start) / 2
+	t.setFixed(start.Add(shift), end.Add(shift))
+	t.changed()
+}
+
+// Live satisfies render.TimeRangeController interface.
+func (t *TimeRangeController) Live() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	start, end := t.timeRange()
+	t.relative = end.Sub(start)
+	t.start = time.Time{}
+	t.end = time.Time{}
+	t.paused = false
+	t.changed()
+}
+
+// TogglePause satisfies render.TimeRangeController interface.
+func (t *TimeRangeController) TogglePause() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.paused = !t.paused
+	t.pausedAt = t.now().UTC()
+	t.changed()
+}
+
+// timeRange returns the time range, if paused the now time will
+// be the time when it was paused.
+func (t *TimeRangeController) timeRange() (start, end time.Time) {
+	now := t.now().UTC()
+	if t.paused {
+		now = t.pausedAt
+	}
+
+	end = t.end
+	if end.IsZero() {
+		end = now
+	}
+	start = t.start
+	if start.IsZero() {
+		start = end.Add(-1 * t.relative)
+	}
+
+	return start, end
+}
+
+func (t *TimeRangeController) isLive() bool {
+	return t.start.IsZero() && t.end.IsZero()
+}
+
+// zoom will change the size of the time range maintaining the center
+// of the time range, on live mode the end will be maintained.
+func (t *TimeRangeController) zoom(factor float64) {
+	start, end := t.timeRange()
+	dur := time.Duration(float64(end.Sub(start)) * factor)
+	if dur < minTimeRange {
+		dur = minTimeRange
+	}
+
+	if t.isLive() {
+		t.relative = dur
+	} else {
+		center := start.Add(end.Sub(start) / 2)
+		t.setFixed(center.Add(-1*dur/2), center.Add(dur/2))
+	}
+	t.changed()
+}
+
+// setFixed sets a fixed time range, if the time range ends in the
+// future it will return to live mode.
+func (t *TimeRangeController) setFixed(start, end time.Time) {
+	if !end.Before(t.now().UTC()) {
+		t.start = time.Time{}
+		t.end = time.Time{}
+		t.relative = end.Sub(start)
+		return
+	}
+
+	t.start = start
+	t.end = end
+}
+
+// changed notifies the time range change without blocking.
+func (t *TimeRangeController) changed() {
+	select {
+	case t.changes <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,139 @@
+package view
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/slok/grafterm/internal/view/render"
+)
+
+func TestTimeRangeController(t *testing.T) {
+	now := time.Date(2019, 5, 20, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		start      time.Time
+		end        time.Time
+		relative   time.Duration
+		actions    func(t *TimeRangeController)
+		expRange   render.TimeRange
+		expChanged bool
+	}{
+		{
+			name:     "Without fixed time the time range should be relative to now.",
+			relative: time.Hour,
+			actions:  func(t *TimeRangeController) {},
+			expRange: render.TimeRange{Start: now.Add(-1 * time.Hour), End: now, Live: true},
+		},
+		{
+			name:     "Fixed time range should not be live.",
+			start:    now.Add(-3 * time.Hour),
+			end:      now.Add(-2 * time.Hour),
+			actions:  func(t *TimeRangeController) {},
+			expRange: render.TimeRange{Start: now.Add(-3 * time.Hour), End: now.Add(-2 * time.Hour)},
+		},
+		{
+			name:       "Zooming in on live mode should reduce the relative time range.",
+			relative:   time.Hour,
+			actions:    func(t *TimeRangeController) { t.ZoomIn() },
+			expRange:   render.TimeRange{Start: now.Add(-30 * time.Minute), End: now, Live: true},
+			expChanged: true,
+		},
+		{
+			name:       "Zooming out on live mode should increase the relative time range.",
+			relative:   time.Hour,
+			actions:    func(t *TimeRangeController) { t.ZoomOut() },
+			expRange:   render.TimeRange{Start: now.Add(-2 * time.Hour), End: now, Live: true},
+			expChanged: true,
+		},
+		{
+			name:       "Zooming on fixed time range should maintain the center of the range.",
+			start:      now.Add(-4 * time.Hour),
+			end:        now.Add(-2 * time.Hour),
+			actions:    func(t *TimeRangeController) { t.ZoomIn() },
+			expRange:   render.TimeRange{Start: now.Add(-210 * time.Minute), End: now.Add(-150 * time.Minute)},
+			expChanged: true,
+		},
+		{
+			name:     "Zooming in should have a minimum time range.",
+			relative: 90 * time.Second,
+			actions: func(t *TimeRangeController) {
+				t.ZoomIn()
+				t.ZoomIn()
+			},
+			expRange:   render.TimeRange{Start: now.Add(-1 * time.Minute), End: now, Live: true},
+			expChanged: true,
+		},
+		{
+			name:       "Panning backward should move the time range half of its size and leave live mode.",
+			relative:   time.Hour,
+			actions:    func(t *TimeRangeController) { t.PanBackward() },
+			expRange:   render.TimeRange{Start: now.Add(-90 * time.Minute), End: now.Add(-30 * time.Minute)},
+			expChanged: true,
+		},
+		{
+			name:     "Panning forward to now should return to live mode.",
+			relative: time.Hour,
+			actions: func(t *TimeRangeController) {
+				t.PanBackward()
+				t.PanForward()
+			},
+			expRange:   render.TimeRange{Start: now.Add(-1 * time.Hour), End: now, Live: true},
+			expChanged: true,
+		},
+		{
+			name:  "Returning to live should maintain the time range size relative to now.",
+			start: now.Add(-4 * time.Hour),
+			end:   now.Add(-2 * time.Hour),
+			actions: func(t *TimeRangeController) {
+				t.Live()
+			},
+			expRange:   render.TimeRange{Start: now.Add(-2 * time.Hour), End: now, Live: true},
+			expChanged: true,
+		},
+		{
+			name:     "Pausing should freeze the time range.",
+			relative: time.Hour,
+			actions: func(tr *TimeRangeController) {
+				tr.TogglePause()
+				tr.now = func() time.Time { return now.Add(time.Hour) }
+			},
+			expRange:   render.TimeRange{Start: now.Add(-1 * time.Hour), End: now, Live: true, Paused: true},
+			expChanged: true,
+		},
+		{
+			name:     "Unpausing should continue with the time range relative to now.",
+			relative: time.Hour,
+			actions: func(tr *TimeRangeController) {
+				tr.TogglePause()
+				tr.now = func() time.Time { return now.Add(time.Hour) }
+				tr.TogglePause()
+			},
+			expRange:   render.TimeRange{Start: now, End: now.Add(time.Hour), Live: true},
+			expChanged: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			tr := NewTimeRangeController(test.start, test.end, test.relative)
+			tr.now = func() time.Time { return now }
+			test.actions(tr)
+
+			assert.Equal(test.expRange, tr.GetTimeRange())
+			assert.Equal(test.expRange.Paused, tr.Paused())
+
+			changed := false
+			select {
+			case <-tr.Changes():
+				changed = true
+			default:
+			}
+			assert.Equal(test.expChanged, changed)
+		})
+	}
+}
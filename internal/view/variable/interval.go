@@ -9,30 +9,38 @@ import (
 
 type intervalVariabler struct {
 	intervalStr string
+	steps       int
 	cfg         model.Variable
 }
 
 // NewIntervalVariabler returns a new variabler that knows how to set
 // variables based on the interval, at this moment it only returns
-// autoinverval so is not repeatable.
+// autoinverval so is not repeatable. The interval is calculated with
+// the time range of every sync, the received time range is the one
+// used when the sync doesn't have a time range.
 // TODO(slok): make repeatable and allow selecting multiple intervals.
-func NewIntervalVariabler(timeRange time.Duration, cfg model.Variable) Variabler {
+func NewIntervalVariabler(timeRange time.Duration, cfg model.Variable) TimeRangeVariabler {
 	// Set default auto interval if not 0.
 	steps := 50
 	if cfg.Interval.Steps != 0 {
 		steps = cfg.Interval.Steps
 	}
-	dur := unit.NearestDurationFromSteps(timeRange, steps)
-	durStr := unit.DurationToSimpleString(dur)
 
 	return &intervalVariabler{
 		cfg:         cfg,
-		intervalStr: durStr,
+		steps:       steps,
+		intervalStr: interval(timeRange, steps),
 	}
 }
 
+func interval(timeRange time.Duration, steps int) string {
+	dur := unit.NearestDurationFromSteps(timeRange, steps)
+	return unit.DurationToSimpleString(dur)
+}
+
 func (i intervalVariabler) Scope() Scope {
-	return ScopeDashboard
+	// The time range can change between syncs (e.g zoom).
+	return ScopeSync
 }
 
 func (i intervalVariabler) IsRepeatable() bool {
@@ -42,3 +50,7 @@ func (i intervalVariabler) IsRepeatable() bool {
 func (i intervalVariabler) GetValue() string {
 	return i.intervalStr
 }
+
+func (i intervalVariabler) GetTimeRangeValue(timeRange time.Duration) string {
+	return interval(timeRange, i.steps)
+}
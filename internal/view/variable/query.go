@@ -0,0 +1,204 @@
+package variable
+
+import (
+	"context"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/service/metric"
+)
+
+const (
+	queryRangeSteps = 10
+)
+
+// labelValuesRegex matches Prometheus `label_values(metric, label)` style expressions.
+var labelValuesRegex = regexp.MustCompile(`^\s*label_values\(\s*(.+?)\s*,\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\)\s*$`)
+
+type queryVariabler struct {
+	cfg      model.Variable
+	values   []string
+	selected map[string]struct{}
+	mu       sync.Mutex
+}
+
+// NewQueryVariabler returns a new variabler that gets the available values
+// of the variable from a datasource using the gatherer in the start and end
+// time range. The values are resolved once on creation, if they can't be
+// resolved the variable will only have the default value (if any).
+// The variabler is repeatable, when multiple values are selected the value
+// of the variable will be a regex alternation (e.g `staging|prod|dev`).
+func NewQueryVariabler(ctx context.Context, gatherer metric.Gatherer, start, end time.Time, cfg model.Variable, logger log.Logger) Repeatable {
+	values, err := queryValues(ctx, gatherer, start, end, *cfg.Query)
+	if err != nil {
+		// Don't fail, the dashboard can be used without the datasource
+		// of the variable.
+		logger.Warnf("error getting %s variable values, using the default value: %s", cfg.Name, err)
+		values = []string{}
+		if cfg.Query.Default != "" {
+			values = []string{cfg.Query.Default}
+		}
+	}
+
+	q := &queryVariabler{
+		cfg:      cfg,
+		values:   values,
+		selected: map[string]struct{}{},
+	}
+
+	// By default select all the values on multi value variables,
+	// and the first one on the single value ones.
+	if cfg.Query.Multi {
+		q.Select(values...)
+	} else if len(values) > 0 {
+		q.Select(values[0])
+	}
+
+	return q
+}
+
+// queryValues will get the values from the datasource, sorted and
+// without duplicates.
+func queryValues(ctx context.Context, gatherer metric.Gatherer, start, end time.Time, cfg model.QueryVariableSource) ([]string, error) {
+	query := cfg.Query
+	label := cfg.Label
+
+	// Prometheus style label values, gather the metric and get the values
+	// from the label.
+	if m := labelValuesRegex.FindStringSubmatch(query.Expr); m != nil {
+		query.Expr = m[1]
+		label = m[2]
+	}
+
+	step := end.Sub(start) / queryRangeSteps
+	series, err := gatherer.GatherRange(ctx, query, start, end, step)
+	if err != nil {
+		return nil, err
+	}
+
+	valuesIdx := map[string]struct{}{}
+	for _, s := range series {
+		v := s.ID
+		if label != "" {
+			v = s.Labels[label]
+		}
+
+		// Filter and extract the value using the regex if required.
+		if cfg.CompiledRegex != nil {
+			m := cfg.CompiledRegex.FindStringSubmatch(v)
+			if m == nil {
+				continue
+			}
+			if len(m) > 1 {
+				v = m[1]
+			}
+		}
+
+		if v == "" {
+			continue
+		}
+		valuesIdx[v] = struct{}{}
+	}
+
+	values := make([]string, 0, len(valuesIdx))
+	for v := range valuesIdx {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+
+	return values, nil
+}
+
+func (q *queryVariabler) Scope() Scope {
+	// The selected values can change between syncs.
+	return ScopeSync
+}
+
+func (q *queryVariabler) IsRepeatable() bool {
+	return true
+}
+
+func (q *queryVariabler) GetValue() string {
+	values := q.GetValues()
+	if !q.cfg.Query.Multi {
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+
+	// Multi value variables are always a regex alternation of the quoted
+	// values (also with one value), this way the queries have the same
+	// meaning regardless of the number of selected values.
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = regexp.QuoteMeta(v)
+	}
+
+	return strings.Join(quoted, "|")
+}
+
+// Select satisfies Repeatable interface. Single value variables
+// will only select the last value.
+func (q *queryVariabler) Select(values ...string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, v := range values {
+		if !q.isValue(v) {
+			continue
+		}
+
+		if !q.cfg.Query.Multi {
+			q.selected = map[string]struct{}{}
+		}
+		q.selected[v] = struct{}{}
+	}
+}
+
+// Deselect satisfies Repeatable interface.
+func (q *queryVariabler) Deselect(values ...string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, v := range values {
+		delete(q.selected, v)
+	}
+}
+
+// GetValues satisfies Repeatable interface.
+func (q *queryVariabler) GetValues() []string {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	// Use the values to return them in order.
+	res := []string{}
+	for _, v := range q.values {
+		if _, ok := q.selected[v]; ok {
+			res = append(res, v)
+		}
+	}
+
+	return res
+}
+
+// GetAllValues satisfies Repeatable interface.
+func (q *queryVariabler) GetAllValues() []string {
+	res := make([]string, len(q.values))
+	copy(res, q.values)
+	return res
+}
+
+func (q *queryVariabler) isValue(value string) bool {
+	for _, v := range q.values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,168 @@
+package variable_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+
+	mmetric "github.com/slok/grafterm/internal/mocks/service/metric"
+	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/view/variable"
+)
+
+func TestQueryVariabler(t *testing.T) {
+	series := []model.MetricSeries{
+		{ID: "servers.web02.cpu", Labels: map[string]string{"job": "web", "namespace": "prod"}},
+		{ID: "servers.web01.cpu", Labels: map[string]string{"job": "web", "namespace": "staging"}},
+		{ID: "servers.db01.cpu", Labels: map[string]string{"job": "db", "namespace": "prod"}},
+		{ID: "servers.db01.mem", Labels: map[string]string{"job": "db", "namespace": "dev.local"}},
+	}
+
+	tests := []struct {
+		name         string
+		cfg          model.QueryVariableSource
+		gatherSeries []model.MetricSeries
+		gatherErr    error
+		selectValues []string
+		deselect     []string
+		expQuery     model.Query
+		expAllValues []string
+		expValues    []string
+		expValue     string
+	}{
+		{
+			name: "Prometheus label values expression should get the label values of the metric and select the first one.",
+			cfg: model.QueryVariableSource{
+				Query: model.Query{DatasourceID: "ds", Expr: `label_values(up{job="web"}, namespace)`},
+			},
+			gatherSeries: series,
+			expQuery:     model.Query{DatasourceID: "ds", Expr: `up{job="web"}`},
+			expAllValues: []string{"dev.local", "prod", "staging"},
+			expValues:    []string{"dev.local"},
+			expValue:     "dev.local",
+		},
+		{
+			name: "Multi value variables should select all the values by default and return a regex alternation.",
+			cfg: model.QueryVariableSource{
+				Query: model.Query{DatasourceID: "ds", Expr: "up"},
+				Label: "namespace",
+				Multi: true,
+			},
+			gatherSeries: series,
+			expQuery:     model.Query{DatasourceID: "ds", Expr: "up"},
+			expAllValues: []string{"dev.local", "prod", "staging"},
+			expValues:    []string{"dev.local", "prod", "staging"},
+			expValue:     `dev\.local|prod|staging`,
+		},
+		{
+			name: "Without label the series ID should be used and the regex capture group should extract the value.",
+			cfg: model.QueryVariableSource{
+				Query:         model.Query{DatasourceID: "ds", Expr: "servers.*.cpu"},
+				CompiledRegex: regexp.MustCompile(`servers\.([^.]+)\.cpu`),
+			},
+			gatherSeries: series,
+			expQuery:     model.Query{DatasourceID: "ds", Expr: "servers.*.cpu"},
+			expAllValues: []string{"db01", "web01", "web02"},
+			expValues:    []string{"db01"},
+			expValue:     "db01",
+		},
+		{
+			name: "Selecting on single value variables should replace the selection and ignore unknown values.",
+			cfg: model.QueryVariableSource{
+				Query: model.Query{DatasourceID: "ds", Expr: "up"},
+				Label: "job",
+			},
+			gatherSeries: series,
+			selectValues: []string{"web", "unknown"},
+			expQuery:     model.Query{DatasourceID: "ds", Expr: "up"},
+			expAllValues: []string{"db", "web"},
+			expValues:    []string{"web"},
+			expValue:     "web",
+		},
+		{
+			name: "Deselecting on multi value variables should remove the values from the selection.",
+			cfg: model.QueryVariableSource{
+				Query: model.Query{DatasourceID: "ds", Expr: "up"},
+				Label: "namespace",
+				Multi: true,
+			},
+			gatherSeries: series,
+			deselect:     []string{"dev.local"},
+			expQuery:     model.Query{DatasourceID: "ds", Expr: "up"},
+			expAllValues: []string{"dev.local", "prod", "staging"},
+			expValues:    []string{"prod", "staging"},
+			expValue:     "prod|staging",
+		},
+		{
+			name: "Multi value variables with a single selected value should return the quoted value.",
+			cfg: model.QueryVariableSource{
+				Query: model.Query{DatasourceID: "ds", Expr: "up"},
+				Label: "namespace",
+				Multi: true,
+			},
+			gatherSeries: series,
+			deselect:     []string{"prod", "staging"},
+			expQuery:     model.Query{DatasourceID: "ds", Expr: "up"},
+			expAllValues: []string{"dev.local", "prod", "staging"},
+			expValues:    []string{"dev.local"},
+			expValue:     `dev\.local`,
+		},
+		{
+			name: "Errors gathering the values should use the default value.",
+			cfg: model.QueryVariableSource{
+				Query:   model.Query{DatasourceID: "ds", Expr: "up"},
+				Default: "prod",
+			},
+			gatherErr:    errors.New("wanted error"),
+			expQuery:     model.Query{DatasourceID: "ds", Expr: "up"},
+			expAllValues: []string{"prod"},
+			expValues:    []string{"prod"},
+			expValue:     "prod",
+		},
+		{
+			name: "Errors gathering the values without default value should have an empty value.",
+			cfg: model.QueryVariableSource{
+				Query: model.Query{DatasourceID: "ds", Expr: "up"},
+				Multi: true,
+			},
+			gatherErr:    errors.New("wanted error"),
+			expQuery:     model.Query{DatasourceID: "ds", Expr: "up"},
+			expAllValues: []string{},
+			expValues:    []string{},
+			expValue:     "",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert := assert.New(t)
+
+			// The values should be gathered on the time range of the variable.
+			start := time.Date(2019, 5, 12, 9, 0, 0, 0, time.UTC)
+			end := start.Add(2 * time.Hour)
+			mg := &mmetric.Gatherer{}
+			mg.On("GatherRange", mock.Anything, test.expQuery, start, end, 12*time.Minute).Once().Return(test.gatherSeries, test.gatherErr)
+
+			cfg := test.cfg
+			v := variable.NewQueryVariabler(context.TODO(), mg, start, end, model.Variable{
+				Name:           "test",
+				VariableSource: model.VariableSource{Query: &cfg},
+			}, log.Dummy)
+
+			v.Select(test.selectValues...)
+			v.Deselect(test.deselect...)
+
+			assert.True(v.IsRepeatable())
+			assert.Equal(test.expAllValues, v.GetAllValues())
+			assert.Equal(test.expValues, v.GetValues())
+			assert.Equal(test.expValue, v.GetValue())
+			mg.AssertExpectations(t)
+		})
+	}
+}
@@ -1,11 +1,17 @@
 package variable
 
 import (
+	"context"
+	"fmt"
 	"time"
 
 	"github.com/slok/grafterm/internal/model"
+	"github.com/slok/grafterm/internal/service/log"
+	"github.com/slok/grafterm/internal/service/metric"
 )
 
+const defTimeRange = 1 * time.Hour
+
 // Scope is the scope of the variable
 type Scope int
 
@@ -27,6 +33,14 @@ type Variabler interface {
 	GetValue() string
 }
 
+// TimeRangeVariabler is a variabler which value depends on the time range
+// of the sync (e.g the interval).
+type TimeRangeVariabler interface {
+	Variabler
+	// GetTimeRangeValue returns the value of the variable for a time range.
+	GetTimeRangeValue(timeRange time.Duration) string
+}
+
 // Repeatable is a variabler that can be repeated.
 type Repeatable interface {
 	Variabler
@@ -38,19 +52,61 @@ type Repeatable interface {
 
 // FactoryConfig is the configuration required by the variabler factory.
 type FactoryConfig struct {
+	// TimeRange is the relative time range from now, used when the
+	// start is not set.
 	TimeRange time.Duration
-	Dashboard model.Dashboard
+	// TimeRangeStart and TimeRangeEnd are the optional fixed time range,
+	// if end is not set now will be used.
+	TimeRangeStart time.Time
+	TimeRangeEnd   time.Time
+	Dashboard      model.Dashboard
+	// Gatherer is used by the variables that get their values from the datasources.
+	Gatherer metric.Gatherer
+	Logger   log.Logger
+}
+
+func (c *FactoryConfig) defaults() {
+	if c.TimeRange <= 0 {
+		c.TimeRange = defTimeRange
+	}
+
+	if c.Logger == nil {
+		c.Logger = log.Dummy
+	}
+}
+
+// timeRange returns the time range the variables are created with.
+func (c FactoryConfig) timeRange() (start, end time.Time) {
+	end = c.TimeRangeEnd
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+
+	start = c.TimeRangeStart
+	if start.IsZero() {
+		start = end.Add(-1 * c.TimeRange)
+	}
+
+	return start, end
 }
 
 // NewVariablers is a factory that knows how to create variablers.
-func NewVariablers(cfg FactoryConfig) (map[string]Variabler, error) {
+func NewVariablers(ctx context.Context, cfg FactoryConfig) (map[string]Variabler, error) {
+	cfg.defaults()
+	start, end := cfg.timeRange()
+
 	variablers := map[string]Variabler{}
 	for _, v := range cfg.Dashboard.Variables {
 		switch {
 		case v.Constant != nil:
 			variablers[v.Name] = &ConstVariabler{cfg: v}
 		case v.Interval != nil:
-			variablers[v.Name] = NewIntervalVariabler(cfg.TimeRange, v)
+			variablers[v.Name] = NewIntervalVariabler(end.Sub(start), v)
+		case v.Query != nil:
+			if cfg.Gatherer == nil {
+				return nil, fmt.Errorf("%s query variable requires a gatherer", v.Name)
+			}
+			variablers[v.Name] = NewQueryVariabler(ctx, cfg.Gatherer, start, end, v, cfg.Logger)
 		}
 	}
 